@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// Live check outcomes of `tg accounts --check`.
+const (
+	statusAuthorized   = "authorized"
+	statusUnauthorized = "unauthorized"
+	statusBanned       = "banned"
+	statusUnreachable  = "unreachable"
+)
+
+// defaultCheckTimeout bounds each account's live check.
+const defaultCheckTimeout = 20 * time.Second
+
+// accountCheck is the live check of one account.
+type accountCheck struct {
+	// Status is authorized, unauthorized (no session, or logged out or
+	// revoked remotely), banned, or unreachable.
+	Status   string `json:"status"`
+	UserID   int64  `json:"user_id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Bot      bool   `json:"bot,omitempty"`
+	// LastUsed is when a run last saved the session; only file sessions have
+	// it. The check itself does not change it.
+	LastUsed *time.Time `json:"last_used,omitempty"`
+	// Proxy is the proxy connected through, without credentials, or "direct".
+	Proxy string `json:"proxy"`
+	DC    int    `json:"dc,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// String renders the check for the text output.
+func (c accountCheck) String() string {
+	parts := []string{c.Status}
+	if c.UserID != 0 {
+		parts = append(parts, fmt.Sprintf("id=%d", c.UserID))
+	}
+	if c.Username != "" {
+		parts = append(parts, "username=@"+c.Username)
+	}
+	if c.DC != 0 {
+		parts = append(parts, fmt.Sprintf("dc=%d", c.DC))
+	}
+	parts = append(parts, "via="+c.Proxy)
+	if c.LastUsed != nil {
+		parts = append(parts, "last_used="+c.LastUsed.Local().Format(time.DateTime))
+	}
+	if c.Error != "" {
+		parts = append(parts, fmt.Sprintf("error=%q", c.Error))
+	}
+	return strings.Join(parts, " ")
+}
+
+// checkStatus maps the outcome of a live check to its status.
+func checkStatus(err error) string {
+	switch {
+	case err == nil:
+		return statusAuthorized
+	case tgerr.Is(err, "USER_DEACTIVATED", "USER_DEACTIVATED_BAN", "PHONE_NUMBER_BANNED"):
+		return statusBanned
+	case errors.Is(err, errNotAuthorized), auth.IsUnauthorized(err),
+		tgerr.Is(err, "ACCESS_TOKEN_INVALID", "ACCESS_TOKEN_EXPIRED"):
+		return statusUnauthorized
+	default:
+		return statusUnreachable
+	}
+}
+
+// checkAccounts checks every account in res live, at most maxFanOut at a
+// time.
+func (a *app) checkAccounts(ctx context.Context, res accountsResult, timeout time.Duration) {
+	sem := make(chan struct{}, maxFanOut)
+	var wg sync.WaitGroup
+	for i := range res.Accounts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			res.Accounts[i].Check = a.checkLive(ctx, res.Accounts[i], timeout)
+		}()
+	}
+	wg.Wait()
+}
+
+// checkLive connects as the account and asks Telegram who it is. It uses the
+// user session, else the bot one; without either the account is
+// unauthorized and nothing connects.
+func (a *app) checkLive(ctx context.Context, status accountStatus, timeout time.Duration) *accountCheck {
+	label := status.Label
+	c := &accountCheck{Proxy: "direct"}
+	acc, err := a.cfg.account(label)
+	if err != nil {
+		c.Status, c.Error = statusUnreachable, err.Error()
+		return c
+	}
+	proxyURL := a.proxyURL
+	if proxyURL == "" {
+		proxyURL = acc.Proxy
+	}
+	if proxyURL != "" {
+		c.Proxy = proxyLabel(proxyURL)
+	}
+
+	kind := authUser
+	if !status.HasSession {
+		if !status.HasBot {
+			c.Status, c.Error = statusUnauthorized, fmt.Sprintf("no session: run tg login --account %s", label)
+			return c
+		}
+		kind = authBot
+	}
+	if fi, err := os.Stat(acc.sessionPath(filepath.Dir(a.configPath), label, kind.String())); err == nil {
+		t := fi.ModTime().UTC()
+		c.LastUsed = &t
+	}
+
+	st, err := a.accountState(label)
+	if err != nil {
+		c.Status, c.Error = statusUnreachable, err.Error()
+		return c
+	}
+	// Read-only, so a check leaves the session, and its last-used time, as
+	// they were.
+	b := a.sessionBackend()
+	b.readOnly = true
+	rp := runParams{
+		auth:    kind,
+		storage: newSessionStore(filepath.Dir(a.configPath), label, acc, kind.String(), b),
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err = a.connectWith(ctx, st, rp, func(ctx context.Context, client *telegram.Client, _ tg.UpdateDispatcher) error {
+		c.DC = client.Config().ThisDC
+		user, err := fetchSelf(ctx, client.API())
+		if kind == authBot && auth.IsUnauthorized(err) && checkStatus(err) != statusBanned {
+			// Bot sessions are re-created from the token.
+			if _, err = client.Auth().Bot(ctx, st.acc.BotToken); err != nil {
+				return errors.Wrap(err, "bot auth")
+			}
+			user, err = fetchSelf(ctx, client.API())
+		}
+		if err != nil {
+			return err
+		}
+		c.UserID, c.Username, c.Bot = user.ID, user.Username, user.Bot
+		return nil
+	})
+	if err == nil && c.UserID == 0 {
+		// connectWith swallows cancellation.
+		err = ctx.Err()
+	}
+	c.Status = checkStatus(err)
+	if err != nil {
+		c.Error = err.Error()
+	}
+	return c
+}
@@ -4,9 +4,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/go-faster/errors"
 	"github.com/spf13/cobra"
+
+	"github.com/gotd/cli/internal/lockedfile"
+	"github.com/gotd/cli/internal/output"
 )
 
 // accountStatus describes one configured account.
@@ -16,6 +21,10 @@ type accountStatus struct {
 	HasBot     bool   `json:"has_bot"`
 	HasSession bool   `json:"has_session"`
 	Default    bool   `json:"default"`
+	// FloodWait counts the account's flood waits across runs.
+	FloodWait floodStats `json:"flood_wait"`
+	// Check is the live check, with --check.
+	Check *accountCheck `json:"check,omitempty"`
 }
 
 // accountsResult is the result of `tg accounts`.
@@ -23,6 +32,9 @@ type accountsResult struct {
 	Accounts []accountStatus `json:"accounts"`
 }
 
+// Items implements output.ItemLister.
+func (r accountsResult) Items() []any { return output.Items(r.Accounts) }
+
 // MarshalText renders one account per line.
 func (r accountsResult) MarshalText(w io.Writer) error {
 	for _, ac := range r.Accounts {
@@ -34,7 +46,16 @@ func (r accountsResult) MarshalText(w io.Writer) error {
 		if ac.Default {
 			marker = "*"
 		}
-		if _, err := fmt.Fprintf(w, "%s %-16s app_id=%d %s\n", marker, ac.Label, ac.AppID, session); err != nil {
+		flood := ""
+		if fw := ac.FloodWait; fw.Last != nil {
+			flood = fmt.Sprintf(" flood_waits=%d waited=%s gave_up=%d last=%s",
+				fw.Waits, time.Duration(fw.WaitedSeconds)*time.Second, fw.GaveUp, fw.Last.Local().Format(time.DateTime))
+		}
+		check := ""
+		if ac.Check != nil {
+			check = " check=" + ac.Check.String()
+		}
+		if _, err := fmt.Fprintf(w, "%s %-16s app_id=%d %s%s%s\n", marker, ac.Label, ac.AppID, session, flood, check); err != nil {
 			return err
 		}
 	}
@@ -42,12 +63,28 @@ func (r accountsResult) MarshalText(w io.Writer) error {
 }
 
 func (a *app) newAccountsCmd() *cobra.Command {
+	var (
+		check   bool
+		timeout time.Duration
+	)
+
 	cmd := &cobra.Command{
 		Use:     "accounts",
 		Short:   "List and manage configured accounts",
 		GroupID: groupAuth,
-		Long:    "List configured accounts (with auth status), or add/remove named accounts.",
-		Args:    cobra.NoArgs,
+		Long: `List configured accounts (with auth status), or add/remove named accounts.
+
+has_session only says a session is stored. --check connects to every account
+at once and asks Telegram: each account is authorized (with its user id and
+username), unauthorized (no session, or logged out or terminated from another
+device), banned, or unreachable, along with the DC, the proxy used and when
+the session was last used. The check never writes the session.
+
+The command succeeds whatever the accounts' status; alert on it with e.g.
+  tg accounts --check -o json | jq -e 'all(.data.accounts[]; .check.status == "authorized")'`,
+		Example: `  tg accounts
+  tg accounts --check -o json`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			def := a.cfg.resolvedDefault()
 			var res accountsResult
@@ -60,18 +97,29 @@ func (a *app) newAccountsCmd() *cobra.Command {
 				if err != nil {
 					return errors.Wrapf(err, "check session for %s", label)
 				}
+				flood, err := readFloodStats(floodStatsPath(filepath.Dir(a.configPath), label))
+				if err != nil {
+					return err
+				}
 				res.Accounts = append(res.Accounts, accountStatus{
 					Label:      label,
 					AppID:      acc.AppID,
 					HasBot:     acc.BotToken != "",
 					HasSession: hasSession,
 					Default:    label == def,
+					FloodWait:  flood,
 				})
 			}
+			if check {
+				a.checkAccounts(cmd.Context(), res, timeout)
+			}
 			return a.printer.Emit(res)
 		},
 	}
 
+	cmd.Flags().BoolVar(&check, "check", false, "connect to each account and report whether it is still authorized")
+	cmd.Flags().DurationVar(&timeout, "timeout", defaultCheckTimeout, "time limit for each account's --check")
+
 	cmd.AddCommand(a.newAccountsAddCmd(), a.newAccountsRemoveCmd(), a.newAccountsDefaultCmd())
 	return cmd
 }
@@ -125,13 +173,21 @@ func (a *app) newAccountsAddCmd() *cobra.Command {
 			if a.cfg.Accounts == nil {
 				a.cfg.Accounts = map[string]Account{}
 			}
+			prev := a.cfg.Accounts[label]
 			a.cfg.Accounts[label] = Account{
 				AppID:    appID,
 				AppHash:  appHash,
 				BotToken: token,
 				Proxy:    proxy,
 				Test:     test,
+				// Keep the settings and the session of an account being
+				// updated.
+				MaxFloodWait: prev.MaxFloodWait,
+				Aliases:      prev.Aliases,
+				Defaults:     prev.Defaults,
+				SessionID:    prev.SessionID,
 			}
+			a.cfg.pinSessionID(label)
 			if err := saveConfig(a.configPath, a.cfg); err != nil {
 				return err
 			}
@@ -157,6 +213,9 @@ func (a *app) newAccountsRemoveCmd() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			label := args[0]
+			if label == defaultAccount {
+				return errors.New("the default account cannot be removed")
+			}
 			if _, ok := a.cfg.Accounts[label]; !ok {
 				return errors.Errorf("unknown account %q", label)
 			}
@@ -164,6 +223,9 @@ func (a *app) newAccountsRemoveCmd() *cobra.Command {
 			if err := saveConfig(a.configPath, a.cfg); err != nil {
 				return err
 			}
+			if err := lockedfile.Remove(floodStatsPath(filepath.Dir(a.configPath), label)); err != nil {
+				return err
+			}
 			_, err := fmt.Fprintf(cmd.OutOrStdout(), "Removed account %q\n", label)
 			return err
 		},
@@ -62,7 +62,7 @@ func editBanned(ctx context.Context, api *tg.Client, ch tg.InputChannelClass, pa
 
 // channelAndUser resolves a channel peer and a user peer.
 func (a *app) channelAndUser(ctx context.Context, api *tg.Client, chArg, userArg string) (tg.InputChannelClass, tg.InputUserClass, error) {
-	m, err := a.manager(api)
+	m, err := a.manager(ctx, api)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -94,7 +94,7 @@ func (a *app) newPromoteCmd() *cobra.Command {
 				if err := editAdmin(ctx, api, ch, user, defaultAdminRights(), rank); err != nil {
 					return err
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -118,7 +118,7 @@ func (a *app) newDemoteCmd() *cobra.Command {
 				if err := editAdmin(ctx, api, ch, user, tg.ChatAdminRights{}, ""); err != nil {
 					return err
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -134,7 +134,7 @@ func (a *app) banUnbanCmd(use, short string, ban bool) *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -153,7 +153,7 @@ func (a *app) banUnbanCmd(use, short string, ban bool) *cobra.Command {
 				if err := editBanned(ctx, api, ch, participant, ban); err != nil {
 					return err
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -181,7 +181,7 @@ func (a *app) newSlowModeCmd() *cobra.Command {
 				return errors.Wrap(err, "seconds must be an integer")
 			}
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -195,7 +195,7 @@ func (a *app) newSlowModeCmd() *cobra.Command {
 				}); err != nil {
 					return errors.Wrap(err, "channels.toggleSlowMode")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
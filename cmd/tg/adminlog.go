@@ -2,14 +2,15 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"io"
+	"strconv"
 	"strings"
 
 	"github.com/go-faster/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/output"
 )
 
 // adminEvent is one admin-log entry.
@@ -25,14 +26,17 @@ type recentActionsResult struct {
 	Events []adminEvent `json:"events"`
 }
 
-// MarshalText renders one event per line.
-func (r recentActionsResult) MarshalText(w io.Writer) error {
-	for _, e := range r.Events {
-		if _, err := fmt.Fprintf(w, "#%d user=%d %s\n", e.ID, e.UserID, e.Action); err != nil {
-			return err
-		}
-	}
-	return nil
+// Items implements output.ItemLister.
+func (r recentActionsResult) Items() []any { return output.Items(r.Events) }
+
+// Table implements output.Tabler.
+func (r recentActionsResult) Table() output.Table {
+	return output.NewTable([]output.Column[adminEvent]{
+		{Name: "id", Value: func(e adminEvent) string { return strconv.FormatInt(e.ID, 10) }},
+		{Name: "date", Value: func(e adminEvent) string { return dateCell(e.Date) }},
+		{Name: "user_id", Value: func(e adminEvent) string { return strconv.FormatInt(e.UserID, 10) }},
+		{Name: "action", Value: func(e adminEvent) string { return e.Action }},
+	}, r.Events)
 }
 
 // actionName returns a short name for an admin-log action type.
@@ -51,7 +55,7 @@ func (a *app) newRecentActionsCmd() *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -76,7 +80,7 @@ func (a *app) newRecentActionsCmd() *cobra.Command {
 						Action: actionName(e.Action),
 					})
 				}
-				return a.printer.Emit(out)
+				return a.out(ctx).Emit(out)
 			})
 		},
 	}
@@ -80,7 +80,7 @@ attached to the first item.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
 				upld := uploader.NewUploader(api).WithPartSize(uploader.MaximumPartSize)
-				sender, m, err := a.sender(api)
+				sender, m, err := a.sender(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -107,7 +107,7 @@ attached to the first item.`,
 				if err != nil {
 					return errors.Wrap(err, "send album")
 				}
-				return a.printer.Emit(sentResult{Peer: peer, MessageID: id})
+				return a.out(ctx).Emit(sentResult{Peer: peer, MessageID: id})
 			})
 		},
 	}
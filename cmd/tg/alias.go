@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/go-faster/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/gotd/cli/internal/output"
+)
+
+// Alias scopes reported by `tg alias list`.
+const aliasScopeGlobal = "global"
+
+// mergeAliases returns the aliases of an account: the global ones overridden by
+// the account's own. The inputs are not modified.
+func mergeAliases(global, account map[string]string) map[string]string {
+	if len(account) == 0 {
+		return global
+	}
+	out := make(map[string]string, len(global)+len(account))
+	for k, v := range global {
+		out[k] = v
+	}
+	for k, v := range account {
+		out[k] = v
+	}
+	return out
+}
+
+// validateAlias rejects alias names that would be read as another peer form:
+// "me"/"self", "@username", phone numbers, numeric ids, links and the "id:" /
+// "name:" / "~" selectors.
+func validateAlias(name string) error {
+	if name == "" {
+		return errors.New("empty alias name")
+	}
+	if isSelf(name) {
+		return errors.Errorf("alias %q would shadow Saved Messages", name)
+	}
+	digits := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != '.' {
+			return errors.Errorf("alias %q: only letters, digits, '_', '-' and '.' are allowed", name)
+		}
+		if !unicode.IsDigit(r) {
+			digits = false
+		}
+	}
+	if digits {
+		return errors.Errorf("alias %q would be read as a numeric id", name)
+	}
+	return nil
+}
+
+// expandAlias returns the peer an alias stands for, following aliases of
+// aliases; any other argument is returned unchanged. Aliases take precedence
+// over bare usernames of the same name ("@name" always means the username).
+func (m *peerManager) expandAlias(arg string) (string, error) {
+	return expandAliasIn(m.aliases, arg)
+}
+
+// expandAliasIn is expandAlias over the given aliases. An alias that leads
+// back to itself is an error naming the cycle.
+func expandAliasIn(aliases map[string]string, arg string) (string, error) {
+	var chain []string
+	for {
+		key := strings.TrimSpace(arg)
+		v, ok := aliases[key]
+		if !ok {
+			return arg, nil
+		}
+		if slices.Contains(chain, key) {
+			return "", withClass(classUsage, errors.Errorf("alias cycle: %s -> %s", strings.Join(chain, " -> "), key))
+		}
+		chain = append(chain, key)
+		arg = v
+	}
+}
+
+// aliasEntry is one alias in effect for an account.
+type aliasEntry struct {
+	Name  string `json:"name"`
+	Peer  string `json:"peer"`
+	Scope string `json:"scope"` // "global" or the account label
+}
+
+// aliasesResult is the result of `tg alias list`.
+type aliasesResult struct {
+	Aliases []aliasEntry `json:"aliases"`
+}
+
+// Items implements output.ItemLister.
+func (r aliasesResult) Items() []any { return output.Items(r.Aliases) }
+
+// MarshalText renders one alias per line.
+func (r aliasesResult) MarshalText(w io.Writer) error {
+	for _, al := range r.Aliases {
+		if _, err := fmt.Fprintf(w, "%-16s %-24s %s\n", al.Name, al.Peer, al.Scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aliasEntries lists the aliases in effect for an account label, by name.
+func (c Config) aliasEntries(label string) []aliasEntry {
+	own := c.Accounts[label].Aliases
+	out := []aliasEntry{}
+	for name, peer := range mergeAliases(c.Aliases, own) {
+		scope := aliasScopeGlobal
+		if _, ok := own[name]; ok {
+			scope = label
+		}
+		out = append(out, aliasEntry{Name: name, Peer: peer, Scope: scope})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// aliasScope returns the alias map `tg alias add|rm` edits and its scope name:
+// the top-level aliases with --global or for the default account, otherwise
+// the selected account's own. The map is created if missing.
+func (a *app) aliasScope(global bool) (map[string]string, string, error) {
+	if err := a.ensureActive(); err != nil {
+		return nil, "", err
+	}
+	label := a.active.label
+	if global || label == defaultAccount {
+		if a.cfg.Aliases == nil {
+			a.cfg.Aliases = map[string]string{}
+		}
+		return a.cfg.Aliases, aliasScopeGlobal, nil
+	}
+	acc, ok := a.cfg.Accounts[label]
+	if !ok {
+		return nil, "", errors.Errorf("unknown account %q (see tg accounts)", label)
+	}
+	if acc.Aliases == nil {
+		acc.Aliases = map[string]string{}
+		a.cfg.Accounts[label] = acc
+	}
+	return acc.Aliases, label, nil
+}
+
+// checkAliasCycles reports an alias cycle through name in the aliases of any
+// account, global ones included.
+func (a *app) checkAliasCycles(name string) error {
+	for _, label := range a.cfg.labels() {
+		acc, err := a.cfg.account(label)
+		if err != nil {
+			return err
+		}
+		if _, err := expandAliasIn(acc.Aliases, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aliasCompletion completes alias names of the selected account.
+func aliasCompletion(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	_, acc, _, _, err := completionAccount(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return filterCandidates(aliasCandidates(acc.Aliases), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// aliasCandidates renders aliases as completion candidates, by name.
+func aliasCandidates(aliases map[string]string) []string {
+	out := make([]string, 0, len(aliases))
+	for name, peer := range aliases {
+		out = append(out, name+"\talias for "+peer)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (a *app) newAliasCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "alias",
+		Short:   "Manage peer aliases",
+		GroupID: groupChats,
+		Long: `Manage peer aliases: short names that stand for a peer anywhere a <peer> is
+accepted, e.g. "tg send --peer boss hi". Top-level aliases apply to every account;
+an account's own aliases (set with --account) override them, so one script can
+target "ops" on each account.
+
+Aliases live in the config:
+
+  aliases:
+    boss: "@jane_doe"
+  accounts:
+    work:
+      aliases:
+        ops: "id:2201861038"`,
+	}
+	cmd.AddCommand(a.newAliasAddCmd(), a.newAliasRmCmd(), a.newAliasListCmd())
+	return cmd
+}
+
+func (a *app) newAliasAddCmd() *cobra.Command {
+	var global bool
+	cmd := &cobra.Command{
+		Use:   "add <name> <peer>",
+		Short: "Add or replace an alias",
+		Long: `Add or replace an alias for the selected account, or for every account with
+--global. The default account's aliases are the global ones. The peer is stored
+as given and resolved on use.`,
+		Example: `  tg alias add boss @jane_doe
+  tg alias add ops id:2201861038 --account work
+  tg alias add family "name:Family Chat" --global`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			name, peer := args[0], strings.TrimSpace(args[1])
+			if err := validateAlias(name); err != nil {
+				return withClass(classUsage, err)
+			}
+			if peer == "" {
+				return withClass(classUsage, errors.New("empty alias peer"))
+			}
+			aliases, _, err := a.aliasScope(global)
+			if err != nil {
+				return err
+			}
+			prev, had := aliases[name]
+			aliases[name] = peer
+			if err := a.checkAliasCycles(name); err != nil {
+				if had {
+					aliases[name] = prev
+				} else {
+					delete(aliases, name)
+				}
+				return err
+			}
+			if err := saveConfig(a.configPath, a.cfg); err != nil {
+				return err
+			}
+			return a.printer.Emit(okResult{OK: true})
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 1 {
+				return peerCandidates(cmd, toComplete), peerCompDirective
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().BoolVar(&global, "global", false, "add the alias for every account")
+	return cmd
+}
+
+func (a *app) newAliasRmCmd() *cobra.Command {
+	var global bool
+	cmd := &cobra.Command{
+		Use:     "rm <name>...",
+		Aliases: []string{"remove"},
+		Short:   "Remove aliases",
+		Long: `Remove aliases of the selected account, or global ones with --global. Removing
+an account's alias uncovers a global alias of the same name, if any.`,
+		Example: `  tg alias rm boss
+  tg alias rm ops --account work`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			aliases, scope, err := a.aliasScope(global)
+			if err != nil {
+				return err
+			}
+			for _, name := range args {
+				if _, ok := aliases[name]; !ok {
+					return errors.Errorf("no %s alias %q", scope, name)
+				}
+				delete(aliases, name)
+			}
+			if err := saveConfig(a.configPath, a.cfg); err != nil {
+				return err
+			}
+			return a.printer.Emit(okResult{OK: true})
+		},
+		ValidArgsFunction: aliasCompletion,
+	}
+	cmd.Flags().BoolVar(&global, "global", false, "remove global aliases")
+	return cmd
+}
+
+func (a *app) newAliasListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   cmdList,
+		Short: "List the aliases in effect for the selected account",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if err := a.ensureActive(); err != nil {
+				return err
+			}
+			return a.printer.Emit(aliasesResult{Aliases: a.cfg.aliasEntries(a.active.label)})
+		},
+	}
+}
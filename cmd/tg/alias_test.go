@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gotd/td/telegram/peers"
+	"github.com/gotd/td/tg"
+)
+
+func TestValidateAlias(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		ok   bool
+	}{
+		{"boss", true},
+		{"ops-team.eu", true},
+		{"me", false},
+		{"", false},
+		{"@boss", false},
+		{"id:1", false},
+		{"~boss", false},
+		{"12345", false},
+		{"two words", false},
+	} {
+		if err := validateAlias(c.name); (err == nil) != c.ok {
+			t.Errorf("validateAlias(%q) = %v, want ok %v", c.name, err, c.ok)
+		}
+	}
+}
+
+func TestExpandAlias(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(t)
+	m.aliases = map[string]string{"boss": "jane", "jane": "id:42", "loop": "loop", "self-ish": "me"}
+	if err := m.store.Save(ctx, peers.Key{Prefix: "users_", ID: 42}, peers.Value{AccessHash: 999}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := m.expandAlias("boss"); err != nil || got != "id:42" {
+		t.Errorf("expandAlias(boss) = %q, %v, want id:42", got, err)
+	}
+	if got, err := m.expandAlias("@boss"); err != nil || got != "@boss" {
+		t.Errorf("expandAlias(@boss) = %q, %v, usernames must not expand", got, err)
+	}
+	m.aliases["a"], m.aliases["b"] = "b", "a"
+	for _, arg := range []string{"loop", "a"} {
+		if _, err := resolvePeer(ctx, m, arg); classify(err) != classUsage || !strings.Contains(err.Error(), "alias cycle") {
+			t.Errorf("resolvePeer(%s): %v, want alias cycle error", arg, err)
+		}
+	}
+	if p, err := resolvePeer(ctx, m, "self-ish"); err != nil || p.TypeID() != tg.InputPeerSelfTypeID {
+		t.Errorf("resolvePeer(self-ish) = %v, %v", p, err)
+	}
+}
+
+func TestAliasCommands(t *testing.T) {
+	configPath, _ := newTestConfig(t)
+	if err := os.WriteFile(configPath, []byte("app_id: 1\napp_hash: x\naccounts:\n  work:\n    app_id: 2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) string {
+		t.Helper()
+		out, err := runRoot(t, append([]string{"--config", configPath, "-o", "json"}, args...)...)
+		if err != nil {
+			t.Fatalf("tg %v: %v", args, err)
+		}
+		return out
+	}
+	list := func(account string) []aliasEntry {
+		t.Helper()
+		var env struct{ Data aliasesResult }
+		if err := json.Unmarshal([]byte(run("alias", "list", "--account", account)), &env); err != nil {
+			t.Fatal(err)
+		}
+		return env.Data.Aliases
+	}
+
+	run("alias", "add", "ops", "@ops_global")
+	run("alias", "add", "boss", "@jane_doe")
+	run("alias", "add", "ops", "id:2201861038", "--account", "work")
+
+	got := list("work")
+	want := []aliasEntry{
+		{Name: "boss", Peer: "@jane_doe", Scope: aliasScopeGlobal},
+		{Name: "ops", Peer: "id:2201861038", Scope: "work"},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("alias list --account work = %+v, want %+v", got, want)
+	}
+	if got := list(defaultAccount); len(got) != 2 || got[1].Peer != "@ops_global" {
+		t.Fatalf("alias list = %+v", got)
+	}
+
+	// Aliases are offered by peer completion.
+	out, err := runRoot(t, "__complete", "--config", configPath, "--account", "work", "read", "o")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "ops\talias for id:2201861038") {
+		t.Errorf("completion = %q, want the work ops alias", out)
+	}
+
+	// Removing the account alias uncovers the global one.
+	run("alias", "rm", "ops", "--account", "work")
+	if got := list("work"); len(got) != 2 || got[1] != (aliasEntry{Name: "ops", Peer: "@ops_global", Scope: aliasScopeGlobal}) {
+		t.Fatalf("alias list after rm = %+v", got)
+	}
+	if _, err := runRoot(t, "--config", configPath, "alias", "rm", "nope"); err == nil {
+		t.Fatal("removing an unknown alias succeeded")
+	}
+	if _, err := runRoot(t, "--config", configPath, "alias", "add", "me", "@x"); classify(err) != classUsage {
+		t.Fatalf("alias add me: %v", err)
+	}
+
+	// A global alias closing a cycle through an account's alias is rejected
+	// and not saved.
+	run("alias", "add", "chief", "boss", "--account", "work")
+	_, err = runRoot(t, "--config", configPath, "alias", "add", "boss", "chief")
+	if classify(err) != classUsage || !strings.Contains(err.Error(), "alias cycle: boss -> chief -> boss") {
+		t.Fatalf("alias add cycle: %v", err)
+	}
+	if got := list(defaultAccount); got[0] != (aliasEntry{Name: "boss", Peer: "@jane_doe", Scope: aliasScopeGlobal}) {
+		t.Fatalf("alias list after rejected cycle = %+v", got)
+	}
+}
@@ -2,6 +2,12 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-faster/errors"
 	"github.com/spf13/cobra"
@@ -9,11 +15,13 @@ import (
 
 	"github.com/gotd/contrib/middleware/floodwait"
 	"github.com/gotd/log/logzap"
+	"github.com/gotd/td/session"
 	"github.com/gotd/td/telegram"
 	"github.com/gotd/td/telegram/dcs"
 	"github.com/gotd/td/tg"
 
 	"github.com/gotd/cli/internal/output"
+	"github.com/gotd/cli/internal/peercache"
 	"github.com/gotd/cli/internal/pretty"
 	"github.com/gotd/cli/internal/proxy"
 )
@@ -48,6 +56,24 @@ type accountState struct {
 	label    string
 	acc      Account
 	resolver dcs.Resolver
+
+	// waiter handles FLOOD_WAIT for this account only, within floodBudget,
+	// and flood counts its waits. middlewares is the account's own chain,
+	// waiter included, and log its logger, named after the label.
+	waiter      *floodwait.Waiter
+	floodBudget time.Duration
+	flood       *floodMeter
+	middlewares []telegram.Middleware
+	log         *zap.Logger
+
+	// printer receives the account's results in a fan-out (see fanOut); nil
+	// means the app's printer.
+	printer *output.Printer
+
+	// peers is the account's peer cache, opened on first use and shared by
+	// every manager of the run so saves batch into one write; see peerCache.
+	peersMu sync.Mutex
+	peers   *peercache.Storage
 }
 
 // app holds shared state and the values of the global (persistent) flags.
@@ -56,13 +82,20 @@ type app struct {
 	configPath   string
 	debugInvoker bool
 	outputFormat string
+	templateText string
+	templateFile string
 	proxyURL     string
 	accountFlag  string
+	maxFloodWait time.Duration
+	// maxFloodWaitSet records an explicit --max-flood-wait, which overrides
+	// the config.
+	maxFloodWaitSet bool
 
-	cfg     Config
-	log     *zap.Logger
-	waiter  *floodwait.Waiter
-	printer *output.Printer
+	cfg Config
+	log *zap.Logger
+	// sessionKey encrypts sessions at rest; nil when encryption is off.
+	sessionKey *sessionKey
+	printer    *output.Printer
 
 	// active is the account currently being operated on (set per run iteration).
 	active *accountState
@@ -80,7 +113,6 @@ func newApp() *app {
 	}
 
 	return &app{
-		waiter:  floodwait.NewWaiter(),
 		log:     defaultLog,
 		printer: output.New(output.Text, nil),
 	}
@@ -90,24 +122,65 @@ func newApp() *app {
 // subcommand. It sets up the output printer and (for commands that need it)
 // loads the config.
 func (a *app) before(cmd *cobra.Command) error {
+	// The config goes first: its defaults section may set --output.
+	load := !skipConfig(cmd)
+	if load {
+		cfg, err := loadConfig(a.configPath)
+		if err != nil {
+			return err
+		}
+		a.cfg = cfg
+		label := a.accountFlag
+		if label == "" {
+			label = cfg.resolvedDefault()
+		}
+		set, err := applyDefaults(cmd, cfg.defaultLayers(label))
+		if err != nil {
+			return err
+		}
+		a.maxFloodWaitSet = cmd.Flags().Changed("max-flood-wait") || set["max-flood-wait"]
+	}
+
 	format, err := output.ParseFormat(a.outputFormat)
 	if err != nil {
 		return err
 	}
 	a.printer = output.New(format, cmd.OutOrStdout())
+	if err := a.setupTemplate(cmd); err != nil {
+		return err
+	}
 
-	if skipConfig(cmd) {
+	if !load {
 		return nil
 	}
+	a.sessionKey = newSessionKey(a.cfg.Encryption, filepath.Dir(a.configPath), true)
+	if a.debugInvoker {
+		a.debug = true
+	}
+	return nil
+}
 
-	cfg, err := loadConfig(a.configPath)
+// setupTemplate installs the --template / --template-file renderer, if any.
+func (a *app) setupTemplate(cmd *cobra.Command) error {
+	text := a.templateText
+	if a.templateFile != "" {
+		raw, err := os.ReadFile(a.templateFile) // #nosec G304 // path provided via flag
+		if err != nil {
+			return errors.Wrap(err, "read template file")
+		}
+		text = string(raw)
+	}
+	if text == "" {
+		return nil
+	}
+	if cmd.Flags().Changed("output") {
+		return errors.New("--template cannot be combined with --output")
+	}
+	t, err := output.ParseTemplate(text, templateFuncs())
 	if err != nil {
 		return err
 	}
-	a.cfg = cfg
-	if a.debugInvoker {
-		a.debug = true
-	}
+	a.printer.SetTemplate(t)
 	return nil
 }
 
@@ -128,17 +201,13 @@ func (a *app) selectedLabels() ([]string, error) {
 	return []string{label}, nil
 }
 
-// activate resolves and installs the active account state for label. When multi
-// is set, the account label is included in output.
-func (a *app) activate(label string, multi bool) error {
+// activate resolves and installs the active account state for label.
+func (a *app) activate(label string) error {
 	st, err := a.accountState(label)
 	if err != nil {
 		return err
 	}
 	a.active = st
-	if multi {
-		a.printer.SetAccount(label)
-	}
 	return nil
 }
 
@@ -154,14 +223,40 @@ func (a *app) ensureActive() error {
 	if len(labels) != 1 {
 		return errors.New("this command needs a single --account (not 'all')")
 	}
-	return a.activate(labels[0], false)
+	return a.activate(labels[0])
+}
+
+// accountStateKey is the context key of the account a run callback works on.
+type accountStateKey struct{}
+
+// withAccountState returns ctx carrying st, for stateOf and out.
+func withAccountState(ctx context.Context, st *accountState) context.Context {
+	return context.WithValue(ctx, accountStateKey{}, st)
+}
+
+// stateOf returns the account ctx works on: the one run called back for,
+// else the active one. Run callbacks use it rather than a.active, which
+// concurrent accounts would share.
+func (a *app) stateOf(ctx context.Context) *accountState {
+	if st, ok := ctx.Value(accountStateKey{}).(*accountState); ok {
+		return st
+	}
+	return a.active
+}
+
+// out returns the printer for the account ctx works on.
+func (a *app) out(ctx context.Context) *output.Printer {
+	if st := a.stateOf(ctx); st != nil && st.printer != nil {
+		return st.printer
+	}
+	return a.printer
 }
 
 // skipConfig reports whether the command runs without a loaded config/session.
 func skipConfig(cmd *cobra.Command) bool {
 	for c := cmd; c != nil; c = c.Parent() {
 		switch c.Name() {
-		case "init", "docs", "completion", "help",
+		case "init", "docs", "schema", "completion", "help", "doctor", "config",
 			cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
 			return true
 		}
@@ -177,21 +272,22 @@ type runParams struct {
 	// authorized. If nil, user sessions error with errNotAuthorized and bot
 	// sessions authenticate with the configured token.
 	authorize func(ctx context.Context, client *telegram.Client, d tg.UpdateDispatcher) error
+	// storage replaces the account's session storage, e.g. to connect
+	// without creating a session.
+	storage session.Storage
 }
 
 // optionsFor builds telegram.Options for a specific account state.
 func (a *app) optionsFor(st *accountState, rp runParams, d tg.UpdateDispatcher) telegram.Options {
-	mw := []telegram.Middleware{a.waiter}
-	if a.debug {
-		mw = append(mw, pretty.Middleware())
-	}
-
 	opts := telegram.Options{
-		Logger:         logzap.New(a.log.Named("tg")),
+		Logger:         logzap.New(st.log),
 		Device:         deviceConfig(),
-		Middlewares:    mw,
+		Middlewares:    st.middlewares,
 		SessionStorage: a.sessionStore(st.label, st.acc, rp.auth.String()),
 	}
+	if rp.storage != nil {
+		opts.SessionStorage = rp.storage
+	}
 	if rp.updates {
 		opts.UpdateHandler = d
 	} else {
@@ -207,13 +303,14 @@ func (a *app) optionsFor(st *accountState, rp runParams, d tg.UpdateDispatcher)
 
 // connectWith builds a client for the given account state and runs f inside the
 // flood-wait + client run loop. The dispatcher is non-nil only when rp.updates
-// is set.
+// is set. Peers learned during the run are written to the peer cache when f
+// returns, and periodically while a long-running update stream is open.
 func (a *app) connectWith(
 	ctx context.Context,
 	st *accountState,
 	rp runParams,
 	f func(ctx context.Context, client *telegram.Client, d tg.UpdateDispatcher) error,
-) error {
+) (rErr error) {
 	var d tg.UpdateDispatcher
 	if rp.updates {
 		d = tg.NewUpdateDispatcher()
@@ -224,9 +321,19 @@ func (a *app) connectWith(
 		return err
 	}
 	client := telegram.NewClient(appID, appHash, a.optionsFor(st, rp, d))
+	defer func() {
+		if err := st.flushPeers(); err != nil && rErr == nil {
+			rErr = err
+		}
+		// Best effort: the counters must not fail the command.
+		_ = st.flood.save(floodStatsPath(filepath.Dir(a.configPath), st.label))
+	}()
 
-	if err := a.waiter.Run(ctx, func(ctx context.Context) error {
+	if err := st.waiter.Run(ctx, func(ctx context.Context) error {
 		return client.Run(ctx, func(ctx context.Context) error {
+			if rp.updates {
+				go st.flushPeersEvery(ctx, peerFlushInterval)
+			}
 			return f(ctx, client, d)
 		})
 	}); err != nil && !errors.Is(err, context.Canceled) {
@@ -268,14 +375,34 @@ func (a *app) accountState(label string) (*accountState, error) {
 		// transport) instead of gotd's plain default.
 		resolver = telegram.TDesktopResolver()
 	}
-	return &accountState{label: label, acc: acc, resolver: resolver}, nil
+	budget, err := a.floodBudget(acc)
+	if err != nil {
+		return nil, err
+	}
+	st := &accountState{
+		label:       label,
+		acc:         acc,
+		resolver:    resolver,
+		floodBudget: budget,
+		flood:       &floodMeter{},
+		log:         a.log.Named("tg").Named(label),
+	}
+	st.waiter = newFloodWaiter(label, budget)
+	st.middlewares = []telegram.Middleware{floodWaitMiddleware(label, budget, st.flood), st.waiter}
+	if a.debug {
+		st.middlewares = append(st.middlewares, pretty.Middleware())
+	}
+	return st, nil
 }
 
+// maxFanOut bounds how many accounts --account all runs at once.
+const maxFanOut = 8
+
 // run connects, ensures the session is authorized, and calls f with the API
 // client, once per selected account. With --account all it fans out across all
-// configured accounts (sequentially), labeling each result. User sessions must
-// already be logged in (unless rp.authorize is set); bot sessions authenticate
-// with the configured token on demand.
+// configured accounts; see fanOut. User sessions must already be logged in
+// (unless rp.authorize is set); bot sessions authenticate with the configured
+// token on demand.
 func (a *app) run(
 	ctx context.Context,
 	rp runParams,
@@ -285,30 +412,113 @@ func (a *app) run(
 	if err != nil {
 		return err
 	}
-	multi := len(labels) > 1
+	if len(labels) > 1 {
+		return a.fanOut(ctx, labels, rp, f)
+	}
+	if err := a.ensureActive(); err != nil {
+		return err
+	}
+	return a.runOne(ctx, a.active, rp, f)
+}
 
-	for _, label := range labels {
-		a.active = nil
-		if err := a.activate(label, multi); err != nil {
+// fanOut runs f for every account in labels, at most maxFanOut at a time, and
+// goes on past accounts that fail. Each account writes to a fork of the
+// printer (see output.Printer.Fork): JSON output is one document keyed by
+// label, with each account's data or error. When any account failed, the
+// error lists them; its class is theirs when they agree.
+func (a *app) fanOut(
+	ctx context.Context,
+	labels []string,
+	rp runParams,
+	f func(ctx context.Context, api *tg.Client) error,
+) error {
+	forks := make([]*output.Printer, len(labels))
+	errs := make([]error, len(labels))
+	sem := make(chan struct{}, maxFanOut)
+	var wg sync.WaitGroup
+	for i, label := range labels {
+		forks[i] = a.printer.Fork(label)
+		st, err := a.accountState(label)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		st.printer = forks[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			errs[i] = a.runOne(ctx, st, rp, f)
+		}()
+	}
+	wg.Wait()
+
+	results := make(map[string]output.AccountResult, len(labels))
+	failed := &fanOutError{total: len(labels)}
+	for i, label := range labels {
+		if errs[i] == nil {
+			results[label] = output.AccountResult{Data: forks[i].Captured()}
+			continue
+		}
+		info := errorInfo(errs[i])
+		results[label] = output.AccountResult{Error: &info}
+		if err := forks[i].EmitError(info); err != nil {
 			return err
 		}
-		if err := a.runOne(ctx, rp, f); err != nil {
-			if multi {
-				return errors.Wrapf(err, "account %q", label)
-			}
+		failed.labels = append(failed.labels, label)
+		failed.errs = append(failed.errs, errs[i])
+	}
+	if a.printer.Format() == output.JSON && !a.printer.Templated() {
+		if err := a.printer.EmitAccounts(results); err != nil {
 			return err
 		}
+	} else if err := a.printer.Flush(forks...); err != nil {
+		return err
 	}
-	return nil
+	if len(failed.errs) == 0 {
+		return nil
+	}
+	return failed
 }
 
-// runOne authorizes and runs f against the active account.
+// fanOutError reports the accounts that failed in a fan-out, whose errors were
+// already written with the results.
+type fanOutError struct {
+	total  int
+	labels []string
+	errs   []error
+}
+
+func (e *fanOutError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d accounts failed:", len(e.errs), e.total)
+	for i, err := range e.errs {
+		fmt.Fprintf(&b, "\n  %s: %v", e.labels[i], err)
+	}
+	return b.String()
+}
+
+// class returns the class the failed accounts share, else classError.
+func (e *fanOutError) class() errorClass {
+	c := classify(e.errs[0])
+	for _, err := range e.errs[1:] {
+		if classify(err) != c {
+			return classError
+		}
+	}
+	return c
+}
+
+// runOne authorizes and runs f against the account st, which f finds through
+// its context (see stateOf).
 func (a *app) runOne(
 	ctx context.Context,
+	st *accountState,
 	rp runParams,
 	f func(ctx context.Context, api *tg.Client) error,
 ) error {
-	return a.connect(ctx, rp, func(ctx context.Context, client *telegram.Client, d tg.UpdateDispatcher) error {
+	return a.connectWith(withAccountState(ctx, st), st, rp, func(ctx context.Context, client *telegram.Client, d tg.UpdateDispatcher) error {
 		status, err := client.Auth().Status(ctx)
 		if err != nil {
 			return errors.Wrap(err, "auth status")
@@ -320,10 +530,10 @@ func (a *app) runOne(
 					return err
 				}
 			case rp.auth == authBot:
-				if a.active.acc.BotToken == "" {
+				if st.acc.BotToken == "" {
 					return errors.New("no bot_token in config")
 				}
-				if _, err := client.Auth().Bot(ctx, a.active.acc.BotToken); err != nil {
+				if _, err := client.Auth().Bot(ctx, st.acc.BotToken); err != nil {
 					return errors.Wrap(err, "bot auth")
 				}
 			default:
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFanOutAggregates(t *testing.T) {
+	configPath, _ := newTestConfig(t)
+	// Accounts whose proxy is rejected fail before connecting.
+	raw := fmt.Sprintf("version: %d\naccounts:\n  default:\n    app_id: 1\n    app_hash: x\n    proxy: http://bad\n"+
+		"  work:\n    proxy: http://bad\n", configVersion)
+	if err := os.WriteFile(configPath, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runRoot(t, "-c", configPath, "-o", "json", "--account", "all", "chats", "list")
+	var fo *fanOutError
+	if !errors.As(err, &fo) || len(fo.labels) != 2 {
+		t.Fatalf("err = %v", err)
+	}
+	var env struct {
+		Schema int `json:"schema"`
+		Data   map[string]struct {
+			Data  json.RawMessage `json:"data"`
+			Error *struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	for _, label := range []string{defaultAccount, "work"} {
+		if r, ok := env.Data[label]; !ok || r.Error == nil || r.Error.Code == "" {
+			t.Errorf("%s: result = %+v", label, r)
+		}
+	}
+
+	// The error envelope is not written again.
+	var stdout, stderr bytes.Buffer
+	reportError(&stdout, &stderr, "json", false, err)
+	if stdout.Len() != 0 || !strings.Contains(stderr.String(), "2 of 2 accounts failed") {
+		t.Errorf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestFanOutErrorClass(t *testing.T) {
+	same := &fanOutError{total: 3, labels: []string{"a", "b"}, errs: []error{errNotAuthorized, errNotAuthorized}}
+	if got := classify(same); got != classAuth {
+		t.Errorf("same class = %v", got)
+	}
+	mixed := &fanOutError{total: 3, labels: []string{"a", "b"}, errs: []error{errNotAuthorized, withClass(classUsage, errors.New("x"))}}
+	if got := classify(mixed); got != classError {
+		t.Errorf("mixed class = %v", got)
+	}
+}
@@ -76,7 +76,7 @@ func (a *app) newChatGetCmd() *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -84,7 +84,7 @@ func (a *app) newChatGetCmd() *cobra.Command {
 				if err != nil {
 					return errors.Wrapf(err, "resolve %q", args[0])
 				}
-				return a.printer.Emit(chatInfo{
+				return a.out(ctx).Emit(chatInfo{
 					Peer:     describeManagedPeer(p),
 					Verified: p.Verified(),
 					Scam:     p.Scam(),
@@ -104,7 +104,7 @@ func (a *app) newChatFullCmd() *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -117,7 +117,7 @@ func (a *app) newChatFullCmd() *cobra.Command {
 				if err := fillChatFull(ctx, p, &res); err != nil {
 					return err
 				}
-				return a.printer.Emit(res)
+				return a.out(ctx).Emit(res)
 			})
 		},
 	}
@@ -22,7 +22,7 @@ func (a *app) newSetTitleCmd() *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -41,7 +41,7 @@ func (a *app) newSetTitleCmd() *cobra.Command {
 				if err != nil {
 					return errors.Wrap(err, "edit title")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -57,7 +57,7 @@ func (a *app) newSetAboutCmd() *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -71,7 +71,7 @@ func (a *app) newSetAboutCmd() *cobra.Command {
 				}); err != nil {
 					return errors.Wrap(err, "messages.editChatAbout")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -93,7 +93,7 @@ func (a *app) newSetPhotoCmd() *cobra.Command {
 				}
 				photo := &tg.InputChatUploadedPhoto{File: file}
 
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -112,7 +112,7 @@ func (a *app) newSetPhotoCmd() *cobra.Command {
 				if err != nil {
 					return errors.Wrap(err, "edit photo")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -143,7 +143,7 @@ func (a *app) newJoinLinkCmd() *cobra.Command {
 				if _, err := api.MessagesImportChatInvite(ctx, hash); err != nil {
 					return errors.Wrap(err, "messages.importChatInvite")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
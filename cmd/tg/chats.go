@@ -2,8 +2,7 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"io"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,6 +11,9 @@ import (
 
 	"github.com/gotd/td/telegram/query"
 	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/output"
+	"github.com/gotd/cli/internal/peercache"
 )
 
 // chatItem describes one dialog.
@@ -30,31 +32,38 @@ type chatList struct {
 	Chats []chatItem `json:"chats"`
 }
 
-// MarshalText renders one chat per line.
-func (l chatList) MarshalText(w io.Writer) error {
-	for _, c := range l.Chats {
-		var flags []string
-		if c.Pinned {
-			flags = append(flags, "pinned")
-		}
-		if c.Muted {
-			flags = append(flags, "muted")
-		}
-		if c.Archived {
-			flags = append(flags, "archived")
-		}
-		line := fmt.Sprintf("%-24s unread=%d", c.Peer.label(), c.Unread)
-		if len(flags) > 0 {
-			line += " [" + strings.Join(flags, ",") + "]"
-		}
-		if c.LastMessage != "" {
-			line += "  " + truncate(c.LastMessage, 60)
-		}
-		if _, err := fmt.Fprintln(w, line); err != nil {
-			return err
-		}
+// Items implements output.ItemLister.
+func (l chatList) Items() []any { return output.Items(l.Chats) }
+
+// Table implements output.Tabler.
+func (l chatList) Table() output.Table { return output.NewTable(chatColumns(), l.Chats) }
+
+// chatColumns are the tabular columns of the chat list.
+func chatColumns() []output.Column[chatItem] {
+	return []output.Column[chatItem]{
+		{Name: "peer", Width: 24, Value: func(c chatItem) string { return c.Peer.label() }},
+		{Name: "id", Value: func(c chatItem) string { return strconv.FormatInt(c.Peer.ID, 10) }},
+		{Name: "type", Value: func(c chatItem) string { return c.Peer.Type }},
+		{Name: "unread", Value: func(c chatItem) string { return strconv.Itoa(c.Unread) }},
+		{Name: "flags", Value: chatFlags},
+		{Name: "last_date", Value: func(c chatItem) string { return dateCell(c.LastDate) }},
+		{Name: "last_message", Width: 60, Value: func(c chatItem) string { return c.LastMessage }},
 	}
-	return nil
+}
+
+// chatFlags joins the set pinned/muted/archived flags, e.g. "pinned,muted".
+func chatFlags(c chatItem) string {
+	var flags []string
+	if c.Pinned {
+		flags = append(flags, "pinned")
+	}
+	if c.Muted {
+		flags = append(flags, "muted")
+	}
+	if c.Archived {
+		flags = append(flags, "archived")
+	}
+	return strings.Join(flags, ",")
 }
 
 func truncate(s string, n int) string {
@@ -67,8 +76,29 @@ func truncate(s string, n int) string {
 
 // listChats fetches up to limit dialogs (archived folder when archived is set).
 // When m is non-nil, the dialogs' peer entities are persisted to the access-hash
-// cache, so peers without a username/phone can later be addressed by "id:<n>".
+// cache, so peers without a username/phone can later be addressed by "id:<n>",
+// together with their names and last activity for offline completion.
 func listChats(ctx context.Context, api *tg.Client, m *peerManager, limit int, archived bool) (chatList, error) {
+	var out chatList
+	if err := streamChats(ctx, api, m, limit, archived, func(c chatItem) error {
+		out.Chats = append(out.Chats, c)
+		return nil
+	}); err != nil {
+		return chatList{}, err
+	}
+	return out, nil
+}
+
+// streamChats is listChats, handing each dialog to each as soon as its page is
+// fetched instead of collecting them.
+func streamChats(
+	ctx context.Context,
+	api *tg.Client,
+	m *peerManager,
+	limit int,
+	archived bool,
+	each func(chatItem) error,
+) error {
 	folder := 0
 	if archived {
 		folder = 1
@@ -85,12 +115,13 @@ func listChats(ctx context.Context, api *tg.Client, m *peerManager, limit int, a
 	now := time.Now().Unix()
 
 	var (
-		out   chatList
+		n     int
 		users = map[int64]*tg.User{}
 		chats = map[int64]tg.ChatClass{}
+		seen  []peercache.Info
 	)
 	for iter.Next(ctx) {
-		if len(out.Chats) >= limit {
+		if n >= limit {
 			break
 		}
 		elem := iter.Value()
@@ -112,7 +143,11 @@ func listChats(ctx context.Context, api *tg.Client, m *peerManager, limit int, a
 			item.LastMessage = messagePreview(msg)
 			item.LastDate = msg.Date
 		}
-		out.Chats = append(out.Chats, item)
+		if err := each(item); err != nil {
+			return err
+		}
+		n++
+		seen = append(seen, peerInfo(item.Peer, int64(item.LastDate)))
 
 		for id, u := range elem.Entities.Users() {
 			users[id] = u
@@ -125,7 +160,7 @@ func listChats(ctx context.Context, api *tg.Client, m *peerManager, limit int, a
 		}
 	}
 	if err := iter.Err(); err != nil {
-		return chatList{}, errors.Wrap(err, "iterate dialogs")
+		return errors.Wrap(err, "iterate dialogs")
 	}
 
 	if m != nil {
@@ -138,10 +173,13 @@ func listChats(ctx context.Context, api *tg.Client, m *peerManager, limit int, a
 			cs = append(cs, c)
 		}
 		if err := m.Apply(ctx, us, cs); err != nil {
-			return chatList{}, errors.Wrap(err, "cache peers")
+			return errors.Wrap(err, "cache peers")
+		}
+		if err := m.remember(seen...); err != nil {
+			return err
 		}
 	}
-	return out, nil
+	return nil
 }
 
 func (a *app) newChatsCmd() *cobra.Command {
@@ -172,15 +210,16 @@ flags and a last-message preview.`,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
-				list, err := listChats(ctx, api, m, limit, archived)
-				if err != nil {
+				var list chatList
+				a.out(ctx).Begin()
+				if err := streamChats(ctx, api, m, limit, archived, output.Collect(a.out(ctx), &list.Chats)); err != nil {
 					return err
 				}
-				return a.printer.Emit(list)
+				return a.out(ctx).End(list)
 			})
 		},
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"testing"
 
@@ -8,6 +9,8 @@ import (
 
 	"github.com/gotd/td/bin"
 	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/output"
 )
 
 func dialogsResult(dialogs []tg.DialogClass, messages []tg.MessageClass, users []tg.UserClass) func(bin.Encoder) (bin.Encoder, error) {
@@ -112,3 +115,22 @@ func TestListChatsBatchSize(t *testing.T) {
 		}
 	}
 }
+
+func TestChatListCSV(t *testing.T) {
+	list := chatList{Chats: []chatItem{{
+		Peer:        peerRef{ID: 42, Type: peerUser, Name: "Pavel, D", Username: "durov"},
+		Unread:      2,
+		Pinned:      true,
+		Muted:       true,
+		LastMessage: "multi\nline",
+	}}}
+	var buf bytes.Buffer
+	if err := output.New(output.CSV, &buf).Emit(list); err != nil {
+		t.Fatal(err)
+	}
+	want := "peer,id,type,unread,flags,last_date,last_message\n" +
+		"@durov,42,user,2,\"pinned,muted\",,\"multi\nline\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("csv = %q, want %q", got, want)
+	}
+}
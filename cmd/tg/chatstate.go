@@ -62,7 +62,7 @@ func (a *app) peerActionCmd(use, short string, action peerAction) *cobra.Command
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -73,7 +73,7 @@ func (a *app) peerActionCmd(use, short string, action peerAction) *cobra.Command
 				if err := action(ctx, api, peer); err != nil {
 					return err
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
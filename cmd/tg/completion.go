@@ -1,36 +1,117 @@
 package main
 
 import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
 	"github.com/spf13/cobra"
+
+	"github.com/gotd/cli/internal/peercache"
 )
 
+// maxPeerCandidates caps the peers offered by completion, most recent first.
+const maxPeerCandidates = 200
+
 // registerPeerCompletion wires dynamic shell completion for a peer-taking flag.
-//
-// TODO(Phase 0): read resolved dialogs/usernames from the local peer cache so
-// completion reflects the user's actual chats without a network round-trip. For
-// now it offers the always-valid self aliases.
+// Candidates come from the local peer cache, so completion reflects the user's
+// actual chats without a network round-trip.
 func registerPeerCompletion(cmd *cobra.Command, flag string) {
 	_ = cmd.RegisterFlagCompletionFunc(flag,
-		func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
-			return []string{
-					"me\tSaved Messages (yourself)",
-					"self\tSaved Messages (yourself)",
-				},
-				cobra.ShellCompDirectiveNoFileComp
+		func(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return peerCandidates(cmd, toComplete), peerCompDirective
 		},
 	)
 }
 
 // peerArgCompletion completes a positional peer argument (the first arg).
-func peerArgCompletion(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+func peerArgCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	if len(args) > 0 {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
-	return []string{
-			"me\tSaved Messages (yourself)",
-			"self\tSaved Messages (yourself)",
-		},
-		cobra.ShellCompDirectiveNoFileComp
+	return peerCandidates(cmd, toComplete), peerCompDirective
+}
+
+// peerCompDirective keeps the recency ranking instead of letting the shell
+// sort candidates alphabetically.
+const peerCompDirective = cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+
+// peerCandidates returns "me"/"self", the selected account's aliases, and its
+// cached peers as "@username" (or "id:<n>" when there is none), each described
+// by name and kind, ranked by last dialog activity. Errors (no config, no cache
+// yet) just leave the self aliases.
+func peerCandidates(cmd *cobra.Command, toComplete string) []string {
+	out := filterCandidates([]string{
+		"me\tSaved Messages (yourself)",
+		"self\tSaved Messages (yourself)",
+	}, toComplete)
+	cfg, acc, label, dir, err := completionAccount(cmd)
+	if err != nil {
+		return out
+	}
+	out = append(out, filterCandidates(aliasCandidates(acc.Aliases), toComplete)...)
+
+	key := newSessionKey(cfg.Encryption, dir, false) // never prompt while completing
+	store, err := peercache.OpenCodec(acc.peerCachePath(dir, label, authUser.String()), cacheCodec(cfg, key))
+	if err != nil {
+		return out
+	}
+	for _, info := range store.Infos() {
+		if len(out) >= maxPeerCandidates {
+			break
+		}
+		out = append(out, filterCandidates([]string{peerCandidate(info)}, toComplete)...)
+	}
+	return out
+}
+
+// peerCandidate renders one cached peer as a completion candidate.
+func peerCandidate(info peercache.Info) string {
+	value := peerIDPrefix + strconv.FormatInt(info.ID, 10)
+	if info.Username != "" {
+		value = "@" + info.Username
+	}
+	desc := info.Kind
+	if info.Name != "" {
+		desc = fmt.Sprintf("%s (%s)", info.Name, info.Kind)
+	}
+	// Descriptions are single-line; tabs would start a new field.
+	return value + "\t" + strings.Join(strings.Fields(desc), " ")
+}
+
+// filterCandidates keeps the candidates whose value starts with prefix,
+// ignoring case.
+func filterCandidates(cands []string, prefix string) []string {
+	prefix = strings.ToLower(prefix)
+	var out []string
+	for _, c := range cands {
+		value, _, _ := strings.Cut(c, "\t")
+		if strings.HasPrefix(strings.ToLower(value), prefix) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// completionAccount returns the config and the account selected by the
+// --config / --account flags, with its label and config directory. Completion
+// runs without the usual config loading, so the flags are read directly.
+func completionAccount(cmd *cobra.Command) (cfg Config, acc Account, label, dir string, err error) {
+	configPath := cmd.Flag("config").Value.String()
+	cfg, err = peekConfig(configPath)
+	if err != nil {
+		return Config{}, Account{}, "", "", err
+	}
+	label = cmd.Flag("account").Value.String()
+	if label == "" || label == "all" {
+		label = cfg.resolvedDefault()
+	}
+	acc, err = cfg.account(label)
+	if err != nil {
+		return Config{}, Account{}, "", "", err
+	}
+	return cfg, acc, label, filepath.Dir(configPath), nil
 }
 
 // noFileComp disables file completion for positional args.
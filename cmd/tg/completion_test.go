@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gotd/cli/internal/peercache"
+)
+
+func TestPeerCompletionFromCache(t *testing.T) {
+	configPath, store := newTestConfig(t)
+	if err := store.SaveInfo(
+		peercache.Info{ID: 1, Kind: peerUser, Name: "Pavel Durov", Username: "durov", LastSeen: 100},
+		peercache.Info{ID: 2, Kind: peerChat, Name: "Family", LastSeen: 200},
+		peercache.Info{ID: 3, Kind: peerChannel, Name: "Durov's Channel", Username: "durovschannel"},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	complete := func(toComplete string) []string {
+		out, err := runRoot(t, "__complete", "--config", configPath, "read", toComplete)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cands []string
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			if !strings.HasPrefix(line, ":") {
+				cands = append(cands, line)
+			}
+		}
+		return cands
+	}
+
+	got := complete("")
+	want := []string{
+		"me\tSaved Messages (yourself)",
+		"self\tSaved Messages (yourself)",
+		"id:2\tFamily (chat)",
+		"@durov\tPavel Durov (user)",
+		"@durovschannel\tDurov's Channel (channel)",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("completions:\n%q\nwant\n%q", got, want)
+	}
+
+	got = complete("@DUROV")
+	if len(got) != 2 || !strings.HasPrefix(got[0], "@durov\t") {
+		t.Errorf("completions for @DUROV = %q", got)
+	}
+}
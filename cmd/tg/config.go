@@ -6,10 +6,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-faster/errors"
 	"gopkg.in/yaml.v3"
+
+	"github.com/gotd/cli/internal/lockedfile"
 )
 
 // defaultAccount is the label of the top-level (legacy) account.
@@ -24,20 +29,51 @@ type Account struct {
 	Proxy string `yaml:"proxy,omitempty"`
 	// Test connects to the Telegram test server.
 	Test bool `yaml:"test,omitempty"`
+	// MaxFloodWait is the longest single FLOOD_WAIT this account waits out
+	// before failing; zero inherits the top-level max_flood_wait.
+	MaxFloodWait time.Duration `yaml:"max_flood_wait,omitempty"`
+	// Aliases maps short names to peers for this account, overriding the
+	// top-level aliases of the same name.
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+	// Defaults pre-sets flags when this account is selected, overriding the
+	// top-level defaults; see applyDefaults.
+	Defaults map[string]any `yaml:"defaults,omitempty"`
+	// SessionID pins the name of the user session file (or keychain item), so
+	// editing the credentials above does not orphan the login. Without it the
+	// name derives from the label and credentials; see seed. Bot sessions are
+	// not pinned: they are re-created from the token.
+	SessionID string `yaml:"session_id,omitempty"`
 }
 
 // Config is the persisted CLI configuration.
 //
-// The top-level fields form the "default" account; additional named accounts
-// live under `accounts`. The legacy single-account schema keeps working
-// unchanged (see [[roadmap-build-decisions]]).
+// Since version 2 every account, "default" included, lives under `accounts`.
+// In older configs the top-level credential fields form the "default" account;
+// loadConfig migrates them (see migrateConfig).
 type Config struct {
+	// Version is the schema version; unset means 1. See configMigrations.
+	Version int `yaml:"version,omitempty"`
+
+	// Legacy (version 1) fields of the default account.
 	AppID    int    `yaml:"app_id,omitempty"`
 	AppHash  string `yaml:"app_hash,omitempty"`
 	BotToken string `yaml:"bot_token,omitempty"`
 	Proxy    string `yaml:"proxy,omitempty"`
 	Test     bool   `yaml:"test,omitempty"`
 
+	// MaxFloodWait is the flood-wait budget of the default account, and of
+	// named accounts that do not set their own (see --max-flood-wait).
+	MaxFloodWait time.Duration `yaml:"max_flood_wait,omitempty"`
+
+	// Aliases maps short names (e.g. "boss") to peers (e.g. "@jane_doe") for
+	// every account; see tg alias.
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+
+	// Defaults pre-sets command flags: "output: json" for every command with
+	// the flag, "history.limit: 100" for one command. Flags given on the
+	// command line win. See applyDefaults.
+	Defaults map[string]any `yaml:"defaults,omitempty"`
+
 	// DefaultAccount is the account used when --account / TG_ACCOUNT is unset.
 	// Empty means the top-level "default" account.
 	DefaultAccount string `yaml:"default_account,omitempty"`
@@ -47,8 +83,31 @@ type Config struct {
 	// headless macOS). Ignored off macOS, where sessions are always files.
 	Keychain *bool `yaml:"keychain,omitempty"`
 
+	// SessionHelper is a command that stores sessions instead of tg (e.g. in
+	// Vault or a password manager); see helperSessionStore for the protocol.
+	SessionHelper string `yaml:"session_helper,omitempty"`
+
+	// Encryption encrypts file sessions, and optionally peer caches, at rest.
+	// Setting TG_SESSION_KEY enables it for sessions too.
+	Encryption *Encryption `yaml:"encryption,omitempty"`
+
 	// Accounts holds additional named accounts, usable via --account <label>.
 	Accounts map[string]Account `yaml:"accounts,omitempty"`
+
+	// dir is the directory of the config file, against which relative file:
+	// references are resolved.
+	dir string
+}
+
+// Encryption configures at-rest encryption of session files. The secret is
+// taken from TG_SESSION_KEY, else from KeyFile, else asked for on the
+// terminal.
+type Encryption struct {
+	// KeyFile holds the secret; a relative path is relative to the config
+	// directory.
+	KeyFile string `yaml:"key_file,omitempty"`
+	// PeerCache also encrypts the peer caches.
+	PeerCache bool `yaml:"peer_cache,omitempty"`
 }
 
 // resolvedDefault returns the configured default account label.
@@ -59,19 +118,121 @@ func (c Config) resolvedDefault() string {
 	return defaultAccount
 }
 
-// defaultAcc returns the top-level account.
+// defaultAcc returns the default account as configured: accounts.default, or
+// the legacy top-level fields.
 func (c Config) defaultAcc() Account {
+	if a, ok := c.Accounts[defaultAccount]; ok {
+		return a
+	}
 	return Account{AppID: c.AppID, AppHash: c.AppHash, BotToken: c.BotToken, Proxy: c.Proxy, Test: c.Test}
 }
 
-// account returns the account config for a label ("" or "default" = top-level).
+// account returns the account config for a label ("" = "default"), with the
+// top-level flood-wait budget and aliases inherited and its secret references
+// resolved.
 func (c Config) account(label string) (Account, error) {
+	var a Account
 	if label == "" || label == defaultAccount {
-		return c.defaultAcc(), nil
+		a = c.defaultAcc()
+	} else {
+		var ok bool
+		if a, ok = c.Accounts[label]; !ok {
+			return Account{}, errors.Errorf("unknown account %q (see tg accounts)", label)
+		}
 	}
-	a, ok := c.Accounts[label]
-	if !ok {
-		return Account{}, errors.Errorf("unknown account %q (see tg accounts)", label)
+	if a.MaxFloodWait == 0 {
+		a.MaxFloodWait = c.MaxFloodWait
+	}
+	a.Aliases = mergeAliases(c.Aliases, a.Aliases)
+	a, err := a.resolveSecrets(c.dir)
+	if err != nil {
+		return Account{}, errors.Wrapf(err, "account %q", label)
+	}
+	return a, nil
+}
+
+// secretFilePrefix marks a value read from a file: "file:<path>", relative to
+// the config directory.
+const secretFilePrefix = "file:"
+
+// envRef matches an environment reference: "${env:VAR}".
+//
+//nolint:gochecknoglobals // compiled once
+var envRef = regexp.MustCompile(`\$\{env:([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// hasSecretRef reports whether a config value holds a reference.
+func hasSecretRef(v string) bool {
+	return strings.HasPrefix(v, secretFilePrefix) || strings.Contains(v, "${env:")
+}
+
+// checkSecretRef checks the syntax of the references in a config value.
+func checkSecretRef(v string) error {
+	if strings.Contains(envRef.ReplaceAllString(v, ""), "${env:") {
+		return errors.New("malformed reference: want ${env:VAR}")
+	}
+	if path, ok := strings.CutPrefix(v, secretFilePrefix); ok && strings.TrimSpace(path) == "" {
+		return errors.New("file: reference without a path")
+	}
+	return nil
+}
+
+// resolveSecret expands the references in a config value: a whole-value
+// "file:<path>" is replaced by the file's trimmed contents, and every
+// "${env:VAR}" by the variable, which must be set.
+func resolveSecret(v, dir string) (string, error) {
+	if err := checkSecretRef(v); err != nil {
+		return "", err
+	}
+	if path, ok := strings.CutPrefix(v, secretFilePrefix); ok {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		raw, err := os.ReadFile(path) // #nosec G304 // path from config
+		if err != nil {
+			return "", errors.Wrap(err, "read secret file")
+		}
+		return strings.TrimSpace(string(raw)), nil
+	}
+	var err error
+	out := envRef.ReplaceAllStringFunc(v, func(ref string) string {
+		name := envRef.FindStringSubmatch(ref)[1]
+		val, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = errors.Errorf("environment variable %s is not set", name)
+		}
+		return val
+	})
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// secretFields returns pointers to the fields of a that accept references,
+// by key.
+func (a *Account) secretFields() []struct {
+	key string
+	v   *string
+} {
+	return []struct {
+		key string
+		v   *string
+	}{
+		{"app_hash", &a.AppHash},
+		{"bot_token", &a.BotToken},
+		{"proxy", &a.Proxy},
+	}
+}
+
+// resolveSecrets returns a with the references in app_hash, bot_token and
+// proxy resolved.
+func (a Account) resolveSecrets(dir string) (Account, error) {
+	for _, f := range a.secretFields() {
+		v, err := resolveSecret(*f.v, dir)
+		if err != nil {
+			return Account{}, errors.Wrap(err, f.key)
+		}
+		*f.v = v
 	}
 	return a, nil
 }
@@ -82,14 +243,28 @@ func (c Config) labels() []string {
 	labels := []string{defaultAccount}
 	keys := make([]string, 0, len(c.Accounts))
 	for k := range c.Accounts {
-		keys = append(keys, k)
+		if k != defaultAccount {
+			keys = append(keys, k)
+		}
 	}
 	sort.Strings(keys)
 	return append(labels, keys...)
 }
 
-// loadConfig reads and parses the config file at path.
+// loadConfig reads and parses the config file at path, migrating an older
+// config to the current version first (see migrateConfig) and saving it so.
 func loadConfig(path string) (Config, error) {
+	return readConfig(path, true)
+}
+
+// peekConfig is loadConfig without side effects: an older config is migrated
+// in memory only, and nothing is written or printed. Shell completion and
+// doctor use it.
+func peekConfig(path string) (Config, error) {
+	return readConfig(path, false)
+}
+
+func readConfig(path string, save bool) (Config, error) {
 	var cfg Config
 	if path == "" {
 		return cfg, errors.New("no config path provided")
@@ -102,9 +277,30 @@ func loadConfig(path string) (Config, error) {
 		}
 		return cfg, err
 	}
+	res, migrated, err := migrateConfig(path, data)
+	if err != nil {
+		return cfg, err
+	}
+	if res.From != res.To && save {
+		// A read-only config still works, migrated in memory only.
+		switch saved, out, err := saveMigrated(path); {
+		case err != nil:
+			_, _ = fmt.Fprintf(os.Stderr, "tg: config %s is version %d; cannot save it migrated: %v\n", path, res.From, err)
+		case saved.From != saved.To:
+			_, _ = fmt.Fprintf(os.Stderr, "tg: migrated config %s to version %d (backup: %s)\n", path, saved.To, saved.Backup)
+			migrated = out
+		default:
+			// Another tg process migrated it first.
+			migrated = out
+		}
+	}
+	if res.From != res.To {
+		data = migrated
+	}
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return cfg, errors.Wrap(err, "parse config")
 	}
+	cfg.dir = filepath.Dir(path)
 	return cfg, nil
 }
 
@@ -137,15 +333,43 @@ func saveConfig(path string, cfg Config) error {
 	if err := e.Encode(cfg); err != nil {
 		return errors.Wrap(err, "encode")
 	}
+	return saveConfigRaw(path, buf.Bytes())
+}
+
+// saveConfigRaw writes an encoded config to path, replacing any existing file
+// atomically under the config lock, so a concurrent tg process never reads a
+// partial config.
+func saveConfigRaw(path string, raw []byte) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
 		return err
 	}
-	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+	unlock, err := lockedfile.Lock(path)
+	if err != nil {
+		return errors.Wrap(err, "lock config")
+	}
+	defer func() { _ = unlock() }()
+	if err := lockedfile.WriteFile(path, raw, 0o600); err != nil {
 		return errors.Wrap(err, "write")
 	}
 	return nil
 }
 
+// pinSessionID records the derived user session id of a named account (or the
+// normalized default) that has none. It is a no-op when a secret reference
+// the id derives from cannot be resolved here.
+func (c Config) pinSessionID(label string) {
+	own, ok := c.Accounts[label]
+	if !ok || own.SessionID != "" {
+		return
+	}
+	acc, err := c.account(label)
+	if err != nil {
+		return
+	}
+	own.SessionID = acc.seed(label, kindUser)
+	c.Accounts[label] = own
+}
+
 // sessionPath returns the session file path for an account label + auth kind.
 func (a Account) sessionPath(dir, label, kind string) string {
 	return filepath.Join(dir, fmt.Sprintf("gotd.session.%s.%s.%s.json", label, kind, a.seed(label, kind)))
@@ -156,8 +380,12 @@ func (a Account) peerCachePath(dir, label, kind string) string {
 	return filepath.Join(dir, fmt.Sprintf("gotd.peers.%s.%s.%s.json", label, kind, a.seed(label, kind)))
 }
 
-// seed returns a stable per-account filename fragment.
+// seed returns a stable per-account filename fragment: the pinned session id,
+// else one derived from the label and credentials.
 func (a Account) seed(label, kind string) string {
+	if kind == kindUser && a.SessionID != "" {
+		return a.SessionID
+	}
 	s := fmt.Sprintf("%s:%d:%s:%s", label, a.AppID, kind, a.BotToken)
 	return fmt.Sprintf("%x", md5.Sum([]byte(s))) // #nosec G401 // filename only
 }
@@ -0,0 +1,739 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+
+	"github.com/gotd/cli/internal/lockedfile"
+	"github.com/gotd/cli/internal/proxy"
+)
+
+// configValueResult is the result of `tg config get`.
+type configValueResult struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+
+	node *yaml.Node
+}
+
+// MarshalText prints a scalar as is and a section as YAML.
+func (r configValueResult) MarshalText(w io.Writer) error {
+	if r.node.Kind == yaml.ScalarNode {
+		_, err := fmt.Fprintln(w, r.node.Value)
+		return err
+	}
+	e := yaml.NewEncoder(w)
+	e.SetIndent(2)
+	if err := e.Encode(r.node); err != nil {
+		return errors.Wrap(err, "encode")
+	}
+	return e.Close()
+}
+
+// configPathResult is the result of `tg config path`.
+type configPathResult struct {
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+}
+
+// MarshalText prints the path.
+func (r configPathResult) MarshalText(w io.Writer) error {
+	_, err := fmt.Fprintln(w, r.Path)
+	return err
+}
+
+// configProblem is one finding of config validation.
+type configProblem struct {
+	Key     string
+	Message string
+}
+
+func (p configProblem) String() string {
+	if p.Key == "" {
+		return p.Message
+	}
+	return p.Key + ": " + p.Message
+}
+
+// problemsError reports validation problems as a usage error, one per line.
+func problemsError(path string, problems []configProblem) error {
+	lines := make([]string, len(problems))
+	for i, p := range problems {
+		lines[i] = "  " + p.String()
+	}
+	return withClass(classUsage, errors.Errorf("invalid config %s:\n%s", path, strings.Join(lines, "\n")))
+}
+
+// yamlFieldError matches the strict decoder's unknown-field message.
+//
+//nolint:gochecknoglobals // compiled once
+var yamlFieldError = regexp.MustCompile(`field (\S+) not found in type main\.\w+`)
+
+// sessionIDPattern matches a pinned session id, which is part of a filename.
+//
+//nolint:gochecknoglobals // compiled once
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// validateConfig checks an encoded config: its schema (unknown keys, types)
+// and its values (accounts, secret references, proxies, durations). dir is
+// the config directory. resolve also resolves the secret references, which
+// fails when a variable is unset here but may be set where tg runs.
+func validateConfig(raw []byte, dir string, resolve bool) []configProblem {
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		var te *yaml.TypeError
+		if !errors.As(err, &te) {
+			return []configProblem{{Message: err.Error()}}
+		}
+		problems := make([]configProblem, len(te.Errors))
+		for i, msg := range te.Errors {
+			problems[i] = configProblem{Message: yamlFieldError.ReplaceAllString(msg, "unknown key $1")}
+		}
+		return problems
+	}
+	var problems []configProblem
+	add := func(key, format string, args ...any) {
+		problems = append(problems, configProblem{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+	if def := cfg.DefaultAccount; def != "" && def != defaultAccount {
+		if _, ok := cfg.Accounts[def]; !ok {
+			add("default_account", "no account %q under accounts", def)
+		}
+	}
+	if cfg.MaxFloodWait < 0 {
+		add("max_flood_wait", "must not be negative")
+	}
+	if cfg.Version > configVersion {
+		add("version", "version %d is newer than this tg supports (%d): upgrade tg", cfg.Version, configVersion)
+	}
+	_, normalized := cfg.Accounts[defaultAccount]
+	if normalized || cfg.Version >= 2 {
+		// In legacyAccountKeys order.
+		for i, set := range []bool{cfg.AppID != 0, cfg.AppHash != "", cfg.BotToken != "", cfg.Proxy != "", cfg.Test} {
+			if set {
+				add(legacyAccountKeys[i], "belongs in accounts.%s since config version 2", defaultAccount)
+			}
+		}
+	}
+	for _, label := range cfg.labels() {
+		prefix := ""
+		raw := cfg.defaultAcc()
+		if label != defaultAccount || normalized {
+			prefix = "accounts." + label + "."
+			raw = cfg.Accounts[label]
+			if label == "all" {
+				add("accounts."+label, "%q is reserved for --account all", label)
+			}
+		}
+		if raw.SessionID != "" && !sessionIDPattern.MatchString(raw.SessionID) {
+			add(prefix+"session_id", "want letters, digits, - and _ only")
+		}
+		if (raw.AppID == 0) != (raw.AppHash == "") {
+			add(prefix+"app_id", "app_id and app_hash must be set together")
+		}
+		if raw.MaxFloodWait < 0 {
+			add(prefix+"max_flood_wait", "must not be negative")
+		}
+		for _, f := range raw.secretFields() {
+			var err error
+			if resolve {
+				*f.v, err = resolveSecret(*f.v, dir)
+			} else {
+				err = checkSecretRef(*f.v)
+			}
+			if err != nil {
+				add(prefix+f.key, "%s", err)
+			}
+		}
+		if resolve || !hasSecretRef(raw.Proxy) {
+			if _, err := proxy.Resolver(raw.Proxy); err != nil {
+				add(prefix+"proxy", "%s", err)
+			}
+		}
+	}
+	if enc := cfg.Encryption; enc != nil && enc.KeyFile != "" {
+		path := enc.KeyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			add("encryption.key_file", "%s", err)
+		}
+	}
+	return problems
+}
+
+// splitConfigKey splits a dotted key ("accounts.work.proxy").
+func splitConfigKey(key string) ([]string, error) {
+	parts := strings.Split(key, ".")
+	for _, p := range parts {
+		if p == "" {
+			return nil, withClass(classUsage, errors.Errorf("invalid key %q", key))
+		}
+	}
+	return parts, nil
+}
+
+// yamlKeys returns the YAML keys of a struct type and their field types.
+func yamlKeys(t reflect.Type) map[string]reflect.Type {
+	keys := map[string]reflect.Type{}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if !f.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		keys[name] = f.Type
+	}
+	return keys
+}
+
+// configKeyType returns the Go type a key path addresses in the config.
+func configKeyType(path []string) (reflect.Type, error) {
+	t := reflect.TypeFor[Config]()
+	for i, p := range path {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		at := strings.Join(path[:i], ".")
+		switch t.Kind() {
+		case reflect.Struct:
+			keys := yamlKeys(t)
+			ft, ok := keys[p]
+			if !ok {
+				names := make([]string, 0, len(keys))
+				for k := range keys {
+					names = append(names, k)
+				}
+				sort.Strings(names)
+				where := "the config"
+				if at != "" {
+					where = at
+				}
+				return nil, withClass(classUsage, errors.Errorf("unknown key %q in %s (valid: %s)",
+					p, where, strings.Join(names, ", ")))
+			}
+			t = ft
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Interface:
+			// Free-form section (defaults): any key below it.
+			return t, nil
+		default:
+			return nil, withClass(classUsage, errors.Errorf("%s is a value, it has no key %q", at, p))
+		}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t, nil
+}
+
+// scalarNode encodes a string value as a YAML node of Go type t.
+func scalarNode(key string, t reflect.Type, value string) (*yaml.Node, error) {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	switch {
+	case t == reflect.TypeFor[time.Duration]():
+		if _, err := time.ParseDuration(value); err != nil {
+			return nil, withClass(classUsage, errors.Errorf("%s: want a duration like 90s or 5m", key))
+		}
+		n.Tag = "!!str"
+	case t.Kind() == reflect.Bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, withClass(classUsage, errors.Errorf("%s: want true or false", key))
+		}
+		n.Tag, n.Value = "!!bool", strconv.FormatBool(v)
+	case t.Kind() == reflect.Int:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, withClass(classUsage, errors.Errorf("%s: want an integer", key))
+		}
+		n.Tag, n.Value = "!!int", strconv.Itoa(v)
+	case t.Kind() == reflect.String:
+		n.Tag = "!!str"
+	case t.Kind() == reflect.Interface:
+		// Untyped: YAML decides, so "true" is a bool and "100" an int.
+	default:
+		return nil, withClass(classUsage, errors.Errorf("%s is a section: set its keys (%s.<key>)", key, key))
+	}
+	return n, nil
+}
+
+// configDoc reads the config at path as a YAML document; a missing or empty
+// file is an empty mapping.
+func configDoc(path string) (*yaml.Node, error) {
+	raw, err := os.ReadFile(path) // #nosec G304 // path provided via flag
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, errors.Wrap(err, "parse config")
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("parse config: top level is not a mapping")
+	}
+	return &doc, nil
+}
+
+// mappingValue returns the value of key in mapping m, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// lookupNode returns the node at path, or nil when it is not set.
+func lookupNode(doc *yaml.Node, path []string) *yaml.Node {
+	n := doc.Content[0]
+	for _, p := range path {
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		if n = mappingValue(n, p); n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// setNode sets the node at path, creating the sections on the way.
+func setNode(doc *yaml.Node, path []string, v *yaml.Node) error {
+	n := doc.Content[0]
+	for i, p := range path {
+		if n.Kind != yaml.MappingNode {
+			return errors.Errorf("%s is not a section", strings.Join(path[:i], "."))
+		}
+		next := mappingValue(n, p)
+		if i == len(path)-1 {
+			if next != nil {
+				*next = *v
+				return nil
+			}
+			n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: p}, v)
+			return nil
+		}
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: p}, next)
+		}
+		n = next
+	}
+	return nil
+}
+
+// unsetNode removes the key at path and reports whether it was set.
+func unsetNode(doc *yaml.Node, path []string) bool {
+	parent := lookupNode(doc, path[:len(path)-1])
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return false
+	}
+	key := path[len(path)-1]
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// encodeConfigDoc renders a config document.
+func encodeConfigDoc(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	e := yaml.NewEncoder(&buf)
+	e.SetIndent(2)
+	if err := e.Encode(doc); err != nil {
+		return nil, errors.Wrap(err, "encode")
+	}
+	if err := e.Close(); err != nil {
+		return nil, errors.Wrap(err, "encode")
+	}
+	return buf.Bytes(), nil
+}
+
+// configProblems is validateConfig plus the defaults keys, which are checked
+// against the command tree under root.
+func configProblems(root *cobra.Command, raw []byte, dir string, resolve bool) []configProblem {
+	problems := validateConfig(raw, dir, resolve)
+	// A config that does not parse is reported above; one with bad values
+	// still has its defaults sections checked.
+	var cfg Config
+	_ = yaml.Unmarshal(raw, &cfg)
+	return append(problems, checkDefaults(root, cfg)...)
+}
+
+// updateConfig applies change to the config document at path, validates the
+// result and writes it, keeping comments and key order. The config lock is
+// held from the read to the write, so concurrent updates are not lost.
+func updateConfig(root *cobra.Command, path string, change func(doc *yaml.Node) error) error {
+	unlock, err := lockedfile.Lock(path)
+	if err != nil {
+		return errors.Wrap(err, "lock config")
+	}
+	defer func() { _ = unlock() }()
+	doc, err := configDoc(path)
+	if err != nil {
+		return err
+	}
+	if err := change(doc); err != nil {
+		return err
+	}
+	raw, err := encodeConfigDoc(doc)
+	if err != nil {
+		return err
+	}
+	if problems := configProblems(root, raw, filepath.Dir(path), false); len(problems) > 0 {
+		return problemsError(path, problems)
+	}
+	// Not saveConfigRaw: it takes the lock held here.
+	if err := lockedfile.WriteFile(path, raw, 0o600); err != nil {
+		return errors.Wrap(err, "write")
+	}
+	return nil
+}
+
+// configKeyCandidates completes dotted keys from the schema and the
+// configured account labels: the first argument, or every other one for set.
+func configKeyCandidates(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 && (cmd.Name() != "set" || len(args)%2 != 0) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	prefix, _, _ := strings.Cut(toComplete, ".")
+	var out []string
+	for k := range yamlKeys(reflect.TypeFor[Config]()) {
+		out = append(out, k)
+	}
+	if prefix == "accounts" || prefix == "encryption" {
+		out = out[:0]
+		if prefix == "encryption" {
+			for k := range yamlKeys(reflect.TypeFor[Encryption]()) {
+				out = append(out, "encryption."+k)
+			}
+		} else if cfg, err := peekConfig(configFlag(cmd)); err == nil {
+			for label := range cfg.Accounts {
+				for k := range yamlKeys(reflect.TypeFor[Account]()) {
+					out = append(out, "accounts."+label+"."+k)
+				}
+			}
+		}
+	}
+	sort.Strings(out)
+	return filterCandidates(out, toComplete), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// configFlag returns the --config value of a command.
+func configFlag(cmd *cobra.Command) string {
+	if f := cmd.Flag("config"); f != nil {
+		return f.Value.String()
+	}
+	return defaultConfigPath()
+}
+
+func (a *app) newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "config",
+		Short:   "Read, change and check the config file",
+		GroupID: groupAuth,
+		Long: `Read and change the config file by dotted key, check it, or edit it.
+
+Keys follow the YAML structure: keychain, default_account, proxy,
+accounts.work.proxy, aliases.boss, encryption.key_file. Changes keep the file's
+comments and are validated before they are written.
+
+Secrets need not be stored in the file: app_hash, bot_token and proxy accept
+"${env:VAR}" (replaced by the environment variable) and "file:<path>" (the
+trimmed contents of a file, relative to the config directory).`,
+		Example: `  tg config get accounts.work.proxy
+  tg config set keychain false
+  tg config set accounts.ci.bot_token '${env:CI_BOT_TOKEN}'
+  tg config set accounts.default.app_hash file:app_hash.txt
+  tg config unset accounts.work.proxy
+  tg config validate`,
+	}
+	cmd.AddCommand(
+		a.newConfigGetCmd(),
+		a.newConfigSetCmd(),
+		a.newConfigUnsetCmd(),
+		a.newConfigValidateCmd(),
+		a.newConfigMigrateCmd(),
+		a.newConfigPathCmd(),
+		a.newConfigEditCmd(),
+	)
+	return cmd
+}
+
+func (a *app) newConfigGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a config value or section",
+		Long: `Print the value at a dotted key, or a whole section as YAML. Secret
+references are printed as written, not resolved. Fails when the key is not
+set.`,
+		Example:           "  tg config get default_account\n  tg config get accounts.work",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: configKeyCandidates,
+		RunE: func(_ *cobra.Command, args []string) error {
+			path, err := splitConfigKey(args[0])
+			if err != nil {
+				return err
+			}
+			if _, err := configKeyType(path); err != nil {
+				return err
+			}
+			doc, err := configDoc(a.configPath)
+			if err != nil {
+				return err
+			}
+			n := lookupNode(doc, path)
+			if n == nil {
+				return errors.Errorf("%s is not set", args[0])
+			}
+			var v any
+			if err := n.Decode(&v); err != nil {
+				return errors.Wrap(err, "decode value")
+			}
+			return a.printer.Emit(configValueResult{Key: args[0], Value: v, node: n})
+		},
+	}
+}
+
+func (a *app) newConfigSetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <key> <value> [<key> <value>...]",
+		Short: "Set config values",
+		Long: `Set the value at a dotted key, creating the sections on the way (so setting
+accounts.<label>.<key> adds the account). The value is checked against the
+key's type, and the whole config is validated before it is written.
+
+Several keys are set at once, and validated together, when given as pairs: use
+that for keys that only make sense together, like app_id and app_hash.`,
+		Example: `  tg config set default_account work
+  tg config set max_flood_wait 2m
+  tg config set accounts.work.proxy socks5://127.0.0.1:1080
+  tg config set accounts.work.app_id 12345 accounts.work.app_hash file:app_hash.txt`,
+		Args: func(_ *cobra.Command, args []string) error {
+			if len(args) < 2 || len(args)%2 != 0 {
+				return withClass(classUsage, errors.Errorf("accepts <key> <value> pairs, received %d arg(s)", len(args)))
+			}
+			return nil
+		},
+		ValidArgsFunction: configKeyCandidates,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paths := make([][]string, 0, len(args)/2)
+			values := make([]*yaml.Node, 0, len(args)/2)
+			for i := 0; i < len(args); i += 2 {
+				path, err := splitConfigKey(args[i])
+				if err != nil {
+					return err
+				}
+				t, err := configKeyType(path)
+				if err != nil {
+					return err
+				}
+				v, err := scalarNode(args[i], t, args[i+1])
+				if err != nil {
+					return err
+				}
+				paths, values = append(paths, path), append(values, v)
+			}
+			if err := updateConfig(cmd.Root(), a.configPath, func(doc *yaml.Node) error {
+				for i, path := range paths {
+					if err := setNode(doc, path, values[i]); err != nil {
+						return err
+					}
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			return a.printer.Emit(okResult{OK: true})
+		},
+	}
+}
+
+func (a *app) newConfigUnsetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "unset <key>",
+		Short:             "Remove a config value or section",
+		Long:              "Remove the value or section at a dotted key. Removing a key that is not set is not an error.",
+		Example:           "  tg config unset accounts.work.proxy\n  tg config unset accounts.old",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: configKeyCandidates,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := splitConfigKey(args[0])
+			if err != nil {
+				return err
+			}
+			if _, err := configKeyType(path); err != nil {
+				return err
+			}
+			if err := updateConfig(cmd.Root(), a.configPath, func(doc *yaml.Node) error {
+				unsetNode(doc, path)
+				return nil
+			}); err != nil {
+				return err
+			}
+			return a.printer.Emit(okResult{OK: true})
+		},
+	}
+}
+
+func (a *app) newConfigValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check the config file",
+		Long: `Check the config file: unknown keys, wrong value types, app_id without
+app_hash, a default_account that does not exist, unset environment variables
+and missing files behind secret references, invalid proxy URLs and a missing
+encryption key file, and defaults keys naming no command or flag. Every
+problem is reported; the exit code is 2 when there is any.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			raw, err := os.ReadFile(a.configPath) // #nosec G304 // path provided via flag
+			if errors.Is(err, os.ErrNotExist) {
+				return errors.Errorf("no config at %s; run `tg init` first", a.configPath)
+			}
+			if err != nil {
+				return err
+			}
+			if problems := configProblems(cmd.Root(), raw, filepath.Dir(a.configPath), true); len(problems) > 0 {
+				return problemsError(a.configPath, problems)
+			}
+			return a.printer.Emit(okResult{OK: true})
+		},
+	}
+}
+
+func (a *app) newConfigPathCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "path",
+		Short: "Print the config file path",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			_, err := os.Stat(a.configPath)
+			return a.printer.Emit(configPathResult{Path: a.configPath, Exists: err == nil})
+		},
+	}
+}
+
+// editorCommand returns the user's editor: $VISUAL, $EDITOR, else vi
+// (notepad on Windows).
+func editorCommand() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if e := os.Getenv(env); e != "" {
+			return e
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// runEditor opens path in the editor, which may carry arguments.
+func runEditor(editor, path string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", editor+" "+path) // #nosec G204 // editor from the user's environment
+	} else {
+		cmd = exec.Command("sh", "-c", editor+` "$@"`, editor, path) // #nosec G204 // editor from the user's environment
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "run editor %q", editor)
+	}
+	return nil
+}
+
+func (a *app) newConfigEditCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "edit",
+		Short: "Edit the config file in $EDITOR",
+		Long: `Open a copy of the config file in $VISUAL or $EDITOR and replace the file
+with it once it validates. On problems you are asked to edit again (on a
+terminal); otherwise the file is left unchanged.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			orig, err := os.ReadFile(a.configPath) // #nosec G304 // path provided via flag
+			if errors.Is(err, os.ErrNotExist) {
+				return errors.Errorf("no config at %s; run `tg init` first", a.configPath)
+			}
+			if err != nil {
+				return err
+			}
+			dir := filepath.Dir(a.configPath)
+			tmp, err := os.CreateTemp(dir, ".gotd.cli.*.yaml")
+			if err != nil {
+				return errors.Wrap(err, "create temp file")
+			}
+			defer func() { _ = os.Remove(tmp.Name()) }()
+			_, err = tmp.Write(orig)
+			if cErr := tmp.Close(); err == nil {
+				err = cErr
+			}
+			if err != nil {
+				return errors.Wrap(err, "write temp file")
+			}
+
+			in := bufio.NewReader(cmd.InOrStdin())
+			interactive := term.IsTerminal(int(os.Stdin.Fd()))
+			for {
+				if err := runEditor(editorCommand(), tmp.Name()); err != nil {
+					return err
+				}
+				raw, err := os.ReadFile(tmp.Name())
+				if err != nil {
+					return errors.Wrap(err, "read edited config")
+				}
+				if bytes.Equal(raw, orig) {
+					_, _ = fmt.Fprintln(os.Stderr, "No changes.")
+					return a.printer.Emit(okResult{OK: true})
+				}
+				problems := configProblems(cmd.Root(), raw, dir, false)
+				if len(problems) == 0 {
+					if err := saveConfigRaw(a.configPath, raw); err != nil {
+						return err
+					}
+					return a.printer.Emit(okResult{OK: true})
+				}
+				vErr := problemsError(a.configPath, problems)
+				if !interactive {
+					return vErr
+				}
+				ta := termAuth{in: in, out: os.Stderr}
+				_, _ = fmt.Fprintln(os.Stderr, vErr)
+				answer, err := ta.prompt("Edit again? [Y/n] ")
+				if err != nil || strings.HasPrefix(strings.ToLower(answer), "n") {
+					return vErr
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hash"), []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TG_TEST_PW", "pw")
+
+	for v, want := range map[string]string{
+		"plain":                              "plain",
+		"file:hash":                          "s3cret",
+		"file:" + filepath.Join(dir, "hash"): "s3cret",
+		"${env:TG_TEST_PW}":                  "pw",
+		"socks5://u:${env:TG_TEST_PW}@h:1":   "socks5://u:pw@h:1",
+	} {
+		got, err := resolveSecret(v, dir)
+		if err != nil || got != want {
+			t.Errorf("resolveSecret(%q) = %q, %v; want %q", v, got, err, want)
+		}
+	}
+	for _, v := range []string{"${env:TG_TEST_UNSET}", "${env:bad-name}", "${env:X", "file:missing", "file:"} {
+		if _, err := resolveSecret(v, dir); err == nil {
+			t.Errorf("resolveSecret(%q): expected error", v)
+		}
+	}
+}
+
+func TestAccountResolvesSecrets(t *testing.T) {
+	t.Setenv("TG_TEST_TOKEN", "123:abc")
+	cfg := Config{Accounts: map[string]Account{"ci": {BotToken: "${env:TG_TEST_TOKEN}"}}}
+	acc, err := cfg.account("ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.BotToken != "123:abc" {
+		t.Errorf("bot token = %q", acc.BotToken)
+	}
+	// The session filename is the same as for the literal token.
+	lit := Account{BotToken: "123:abc"}
+	if acc.sessionPath("d", "ci", kindBot) != lit.sessionPath("d", "ci", kindBot) {
+		t.Error("reference changes the session path")
+	}
+
+	cfg.Accounts["ci"] = Account{BotToken: "${env:TG_TEST_UNSET}"}
+	if _, err := cfg.account("ci"); err == nil || !strings.Contains(err.Error(), "TG_TEST_UNSET") {
+		t.Errorf("unset variable: err = %v", err)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	cases := []struct {
+		name    string
+		raw     string
+		resolve bool
+		want    []string
+	}{
+		{"valid", "app_id: 1\napp_hash: x\naccounts:\n  work:\n    proxy: socks5://h:1\n", true, nil},
+		{"empty", "", true, nil},
+		{"unknown key", "app_idd: 1\n", true, []string{"line 1: unknown key app_idd"}},
+		{"type", "keychain: maybe\n", true, []string{"cannot unmarshal"}},
+		{"default account", "default_account: nope\n", true, []string{"default_account: no account"}},
+		{"half creds", "accounts:\n  w:\n    app_id: 1\n", true, []string{"accounts.w.app_id:"}},
+		{"proxy", "proxy: http://h\n", true, []string{"proxy: unsupported proxy scheme"}},
+		{"reserved", "accounts:\n  all: {}\n", true, []string{`accounts.all: "all" is reserved`}},
+		{"unset env", "bot_token: ${env:TG_TEST_UNSET}\n", true, []string{"bot_token: environment variable"}},
+		{"unset env unresolved", "bot_token: ${env:TG_TEST_UNSET}\n", false, nil},
+		{"malformed ref", "bot_token: ${env:X\n", false, []string{"bot_token: malformed reference"}},
+		{"key file", "encryption:\n  key_file: nokey\n", true, []string{"encryption.key_file:"}},
+		{"legacy key", "version: 3\napp_id: 1\napp_hash: x\n", true, []string{"app_id: belongs in accounts.default", "app_hash: belongs"}},
+		{"normalized", "version: 3\naccounts:\n  default:\n    app_id: 1\n    app_hash: x\n    session_id: abc\n", true, nil},
+		{"session id", "accounts:\n  w:\n    session_id: ../x\n", true, []string{"accounts.w.session_id:"}},
+		{"newer", "version: 9\n", true, []string{"version: version 9 is newer"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			problems := validateConfig([]byte(c.raw), t.TempDir(), c.resolve)
+			if len(problems) != len(c.want) {
+				t.Fatalf("problems = %v, want %d", problems, len(c.want))
+			}
+			for i, p := range problems {
+				if !strings.Contains(p.String(), c.want[i]) {
+					t.Errorf("problem %q, want %q", p, c.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestConfigSetGetUnset(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "gotd.cli.yaml")
+	if err := os.WriteFile(configPath, []byte("# tg config\napp_id: 1 # mine\napp_hash: x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) (string, error) {
+		return runRoot(t, append([]string{"-c", configPath, "config"}, args...)...)
+	}
+	for _, args := range [][]string{
+		{"set", "accounts.work.proxy", "socks5://127.0.0.1:1080"},
+		{"set", "keychain", "false"},
+		{"set", "max_flood_wait", "2m"},
+		{"set", "aliases.boss", "@jane"},
+		{"set", "accounts.ci.bot_token", "${env:TG_TEST_UNSET}"},
+		{"set", "accounts.own.app_id", "12345", "accounts.own.app_hash", "abc"},
+	} {
+		if _, err := run(args...); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# tg config", "app_id: 1 # mine", "keychain: false", "max_flood_wait: 2m", "proxy: socks5://127.0.0.1:1080"} {
+		if !strings.Contains(string(raw), want) {
+			t.Errorf("config lacks %q:\n%s", want, raw)
+		}
+	}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Keychain == nil || *cfg.Keychain || cfg.Aliases["boss"] != "@jane" || cfg.Accounts["work"].Proxy == "" ||
+		cfg.Accounts["own"].AppID != 12345 {
+		t.Errorf("config = %+v", cfg)
+	}
+
+	if out, err := run("get", "accounts.work.proxy"); err != nil || out != "socks5://127.0.0.1:1080\n" {
+		t.Errorf("get = %q, %v", out, err)
+	}
+	if out, err := run("get", "accounts.work"); err != nil || !strings.HasPrefix(out, "proxy: socks5://127.0.0.1:1080\n") {
+		t.Errorf("get section = %q, %v", out, err)
+	}
+	if _, err := run("unset", "accounts.work.proxy"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run("get", "accounts.work.proxy"); err == nil {
+		t.Error("get after unset: expected error")
+	}
+
+	for _, args := range [][]string{
+		{"set", "accounts.work.proxyy", "x"},
+		{"set", "keychain", "maybe"},
+		{"set", "accounts.work", "x"},
+		{"set", "proxy", "http://h"},
+		{"set", "accounts..proxy", "x"},
+		{"set", "accounts.other.app_id", "1"},
+		{"set", "keychain", "false", "proxy"},
+	} {
+		if _, err := run(args...); classify(err) != classUsage {
+			t.Errorf("%v: class = %v (%v), want usage", args, classify(err), err)
+		}
+	}
+}
+
+func TestConfigEdit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("editor script needs sh")
+	}
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "gotd.cli.yaml")
+	if err := os.WriteFile(configPath, []byte("app_id: 1\napp_hash: x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	editor := filepath.Join(dir, "editor.sh")
+	script := "#!/bin/sh\nprintf 'app_id: 2\\napp_hash: y\\n' > \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0o700); err != nil { // #nosec G306 // test script
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", editor)
+	if _, err := runRoot(t, "-c", configPath, "config", "edit"); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc, err := cfg.account(defaultAccount); err != nil || acc.AppID != 2 || acc.AppHash != "y" {
+		t.Errorf("config after edit = %+v, %v", cfg, err)
+	}
+
+	// An invalid edit leaves the file alone when there is no terminal.
+	bad := "#!/bin/sh\nprintf 'app_idd: 3\\n' > \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(bad), 0o700); err != nil { // #nosec G306 // test script
+		t.Fatal(err)
+	}
+	if _, err := runRoot(t, "-c", configPath, "config", "edit"); classify(err) != classUsage {
+		t.Errorf("invalid edit: err = %v", err)
+	}
+	if cfg, _ := loadConfig(configPath); cfg.defaultAcc().AppID != 2 {
+		t.Errorf("invalid edit was written: %+v", cfg)
+	}
+}
+
+func TestConfigSetConcurrently(t *testing.T) {
+	configPath, _ := newTestConfig(t)
+	const n = 32
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			label := fmt.Sprintf("accounts.a%d", i)
+			if _, err := runRoot(t, "-c", configPath, "config", "set",
+				label+".app_id", "1", label+".app_hash", "x"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No update was lost to another's write.
+	for i := range n {
+		if label := fmt.Sprintf("a%d", i); cfg.Accounts[label].AppID != 1 {
+			t.Errorf("account %s lost", label)
+		}
+	}
+}
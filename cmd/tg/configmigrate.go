@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/gotd/cli/internal/lockedfile"
+)
+
+// configVersion is the config schema version this tg writes.
+const configVersion = 3
+
+// configMigration upgrades a config document from version to-1 to version to.
+// apply edits the document in place, keeping comments, and describes each
+// change; dir is the config directory. It reports whether the upgrade is
+// complete: if not, the document stays below version to and a later run tries
+// again.
+type configMigration struct {
+	to    int
+	apply func(doc *yaml.Node, dir string) (changes []string, complete bool, err error)
+}
+
+// configMigrations lists the migrations in order.
+//
+//nolint:gochecknoglobals // static table
+var configMigrations = []configMigration{
+	{to: 2, apply: migrateDefaultAccount},
+	{to: 3, apply: migrateSessionIDs},
+}
+
+// legacyAccountKeys are the top-level keys of the version 1 default account.
+//
+//nolint:gochecknoglobals // static table
+var legacyAccountKeys = []string{"app_id", "app_hash", "bot_token", "proxy", "test"}
+
+// keyNode returns a mapping key node.
+func keyNode(key string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+}
+
+// migrateDefaultAccount moves the top-level credentials of the default account
+// into accounts.default, so every account is configured the same way. The
+// top-level max_flood_wait, aliases and defaults stay: they apply to every
+// account.
+func migrateDefaultAccount(doc *yaml.Node, _ string) ([]string, bool, error) {
+	root := doc.Content[0]
+	var moved, kept []*yaml.Node
+	var names []string
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		k, v := root.Content[i], root.Content[i+1]
+		if slices.Contains(legacyAccountKeys, k.Value) {
+			moved = append(moved, k, v)
+			names = append(names, k.Value)
+			continue
+		}
+		kept = append(kept, k, v)
+	}
+	if existing := lookupNode(doc, []string{"accounts", defaultAccount}); existing != nil {
+		if len(moved) > 0 {
+			return nil, false, errors.Errorf("both top-level %s and accounts.%s are set: keep one", strings.Join(names, ", "), defaultAccount)
+		}
+		return nil, true, nil
+	}
+	root.Content = kept
+
+	accounts := mappingValue(root, "accounts")
+	if accounts == nil {
+		accounts = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		root.Content = append(root.Content, keyNode("accounts"), accounts)
+	}
+	if accounts.Kind != yaml.MappingNode {
+		return nil, false, errors.New("accounts is not a section")
+	}
+	accounts.Style = 0
+	def := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: moved}
+	accounts.Content = append([]*yaml.Node{keyNode(defaultAccount), def}, accounts.Content...)
+	if len(names) == 0 {
+		return []string{"added accounts.default"}, true, nil
+	}
+	return []string{fmt.Sprintf("moved %s to accounts.default", strings.Join(names, ", "))}, true, nil
+}
+
+// migrateSessionIDs pins the user session id of every account, as derived
+// from its current credentials, so later edits of app_id or bot_token keep the
+// session. It is incomplete while an account's secret references cannot be
+// resolved here (an unset environment variable, a missing file): that account
+// is pinned by a later run that can resolve them.
+func migrateSessionIDs(doc *yaml.Node, dir string) ([]string, bool, error) {
+	var cfg Config
+	if err := doc.Decode(&cfg); err != nil {
+		return nil, false, errors.Wrap(err, "parse config")
+	}
+	cfg.dir = dir
+	var changes []string
+	complete := true
+	for _, label := range cfg.labels() {
+		if cfg.Accounts[label].SessionID != "" {
+			continue
+		}
+		acc, err := cfg.account(label)
+		if err != nil {
+			changes = append(changes, fmt.Sprintf("accounts.%s.session_id not pinned yet: %s", label, err))
+			complete = false
+			continue
+		}
+		id := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: acc.seed(label, kindUser)}
+		if err := setNode(doc, []string{"accounts", label, "session_id"}, id); err != nil {
+			return nil, false, err
+		}
+		changes = append(changes, fmt.Sprintf("pinned accounts.%s.session_id", label))
+	}
+	return changes, complete, nil
+}
+
+// docVersion returns the version of a config document; unset is 1.
+func docVersion(doc *yaml.Node) (int, error) {
+	n := mappingValue(doc.Content[0], "version")
+	if n == nil {
+		return 1, nil
+	}
+	v, err := strconv.Atoi(n.Value)
+	if err != nil || v < 1 {
+		return 0, errors.Errorf("version: want a positive integer, got %q", n.Value)
+	}
+	return v, nil
+}
+
+// configMigrateResult is the result of `tg config migrate`.
+type configMigrateResult struct {
+	From    int      `json:"from"`
+	To      int      `json:"to"`
+	Changes []string `json:"changes"`
+	// Backup is the copy of the previous config, when one was written.
+	Backup string `json:"backup,omitempty"`
+	DryRun bool   `json:"dry_run,omitempty"`
+	// Config is the migrated config, with --dry-run.
+	Config string `json:"config,omitempty"`
+}
+
+// MarshalText lists the changes.
+func (r configMigrateResult) MarshalText(w io.Writer) error {
+	if r.From == r.To && len(r.Changes) == 0 {
+		_, err := fmt.Fprintf(w, "Config is at version %d; nothing to migrate.\n", r.To)
+		return err
+	}
+	if r.From == r.To {
+		// Only what keeps the next version from being reached.
+		_, err := fmt.Fprintf(w, "Config stays at version %d:\n", r.To)
+		if err != nil {
+			return err
+		}
+	} else if _, err := fmt.Fprintf(w, "Version %d -> %d:\n", r.From, r.To); err != nil {
+		return err
+	}
+	for _, c := range r.Changes {
+		if _, err := fmt.Fprintf(w, "  %s\n", c); err != nil {
+			return err
+		}
+	}
+	if r.From == r.To {
+		return nil
+	}
+	if r.DryRun {
+		_, err := fmt.Fprintf(w, "\nDry run, nothing written. The result:\n\n%s", r.Config)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "Backup: %s\n", r.Backup)
+	return err
+}
+
+// migrateConfig upgrades the encoded config at path to configVersion in
+// memory, or as far as its migrations complete (res.To). It returns the
+// migrated encoding, which is raw itself when the version does not change.
+func migrateConfig(path string, raw []byte) (configMigrateResult, []byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return configMigrateResult{}, nil, errors.Wrap(err, "parse config")
+	}
+	if doc.Kind == 0 || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		// Empty, or not a config at all: leave it to the decoder.
+		return configMigrateResult{From: configVersion, To: configVersion, Changes: []string{}}, raw, nil
+	}
+	from, err := docVersion(&doc)
+	if err != nil {
+		return configMigrateResult{}, nil, err
+	}
+	res := configMigrateResult{From: from, To: configVersion, Changes: []string{}}
+	switch {
+	case from > configVersion:
+		return res, nil, errors.Errorf("config version %d is newer than this tg supports (%d): upgrade tg", from, configVersion)
+	case from == configVersion:
+		return res, raw, nil
+	}
+
+	// A comment right above the first key most likely heads the file: keep it
+	// there, whatever the migrations move.
+	root := doc.Content[0]
+	var head string
+	if len(root.Content) > 0 {
+		head, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+	}
+	dir := filepath.Dir(path)
+	for _, m := range configMigrations {
+		if m.to <= from {
+			continue
+		}
+		changes, complete, err := m.apply(&doc, dir)
+		if err != nil {
+			return res, nil, errors.Wrapf(err, "migrate config to version %d", m.to)
+		}
+		res.Changes = append(res.Changes, changes...)
+		if !complete {
+			res.To = m.to - 1
+			break
+		}
+	}
+	if res.To == from {
+		return res, raw, nil
+	}
+	version := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(res.To)}
+	if n := mappingValue(root, "version"); n != nil {
+		*n = *version
+	} else {
+		root.Content = append([]*yaml.Node{keyNode("version"), version}, root.Content...)
+	}
+	root.Content[0].HeadComment = head
+	out, err := encodeConfigDoc(&doc)
+	if err != nil {
+		return res, nil, err
+	}
+	return res, out, nil
+}
+
+// saveMigrated migrates the config at path and writes it, keeping the
+// original as <path>.v<from>.bak. It holds the config lock from reading to
+// writing, so of several tg processes loading an older config only the first
+// migrates it. It returns the migration done, if any, and the current config.
+func saveMigrated(path string) (configMigrateResult, []byte, error) {
+	unlock, err := lockedfile.Lock(path)
+	if err != nil {
+		return configMigrateResult{}, nil, errors.Wrap(err, "lock config")
+	}
+	defer func() { _ = unlock() }()
+	raw, err := os.ReadFile(path) // #nosec G304 // path provided via flag
+	if err != nil {
+		return configMigrateResult{}, nil, err
+	}
+	res, out, err := migrateConfig(path, raw)
+	if err != nil || res.From == res.To {
+		return res, out, err
+	}
+	backup := fmt.Sprintf("%s.v%d.bak", path, res.From)
+	if err := lockedfile.WriteFile(backup, raw, 0o600); err != nil {
+		return res, nil, errors.Wrap(err, "back up config")
+	}
+	if err := lockedfile.WriteFile(path, out, 0o600); err != nil {
+		return res, nil, errors.Wrap(err, "write")
+	}
+	res.Backup = backup
+	return res, out, nil
+}
+
+func (a *app) newConfigMigrateCmd() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade the config file to the current version",
+		Long: `Upgrade the config file to the current schema version. Any tg command does
+this on its own when it loads an older config; migrate lets you see and
+review the changes first.
+
+Version 2 moves the top-level credentials of the default account (app_id,
+app_hash, bot_token, proxy, test) into accounts.default. Version 3 pins each
+account's user session id, derived from its current credentials, so later
+edits of app_id or bot_token no longer orphan the login. While an account's
+credentials reference an unset environment variable or a missing file, its
+id cannot be derived and the config stays at version 2 until a run where they
+resolve.
+
+The previous file is kept as <config>.v<version>.bak. With --dry-run nothing
+is written and the migrated config is printed.`,
+		Example: `  tg config migrate --dry-run
+  tg config migrate`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			raw, err := os.ReadFile(a.configPath) // #nosec G304 // path provided via flag
+			if errors.Is(err, os.ErrNotExist) {
+				return errors.Errorf("no config at %s; run `tg init` first", a.configPath)
+			}
+			if err != nil {
+				return err
+			}
+			if !dryRun {
+				res, _, err := saveMigrated(a.configPath)
+				if err != nil {
+					return err
+				}
+				return a.printer.Emit(res)
+			}
+			res, out, err := migrateConfig(a.configPath, raw)
+			if err != nil {
+				return err
+			}
+			if res.From != res.To {
+				res.DryRun, res.Config = true, string(out)
+			}
+			return a.printer.Emit(res)
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the changes and the result without writing")
+	return cmd
+}
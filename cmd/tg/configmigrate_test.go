@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	const legacy = "# mine\napp_id: 10 # own app\napp_hash: abcd\nmax_flood_wait: 2m\naccounts:\n  work:\n    proxy: socks5://h:1\n"
+	var old Config
+	if err := yaml.Unmarshal([]byte(legacy), &old); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	wantPath := map[string]string{}
+	for _, label := range old.labels() {
+		acc, err := old.account(label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantPath[label] = acc.sessionPath(dir, label, kindUser)
+	}
+
+	res, out, err := migrateConfig(filepath.Join(dir, "gotd.cli.yaml"), []byte(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.From != 1 || res.To != configVersion || len(res.Changes) != 3 {
+		t.Errorf("result = %+v", res)
+	}
+	for _, want := range []string{"# mine\nversion: 3\n", "app_id: 10 # own app", "max_flood_wait: 2m"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("migrated config lacks %q:\n%s", want, out)
+		}
+	}
+	if problems := validateConfig(out, dir, true); len(problems) > 0 {
+		t.Errorf("migrated config is invalid: %v", problems)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(out, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AppID != 0 || cfg.Accounts[defaultAccount].AppID != 10 {
+		t.Errorf("default account not moved: %+v", cfg)
+	}
+	if got := cfg.labels(); strings.Join(got, ",") != "default,work" {
+		t.Errorf("labels = %v", got)
+	}
+	for label, want := range wantPath {
+		acc, err := cfg.account(label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := acc.sessionPath(dir, label, kindUser); got != want {
+			t.Errorf("%s: session path %s, want %s", label, got, want)
+		}
+		// Editing the credentials keeps the session.
+		acc.AppID, acc.AppHash = 99, "other"
+		if got := acc.sessionPath(dir, label, kindUser); got != want {
+			t.Errorf("%s: session path changed with app_id", label)
+		}
+	}
+
+	// Current configs are left alone.
+	if res, again, err := migrateConfig("c.yaml", out); err != nil || res.From != res.To || string(again) != string(out) {
+		t.Errorf("second migration: %+v, %v", res, err)
+	}
+}
+
+func TestMigrateConfigErrors(t *testing.T) {
+	for name, raw := range map[string]string{
+		"newer":    "version: 99\n",
+		"conflict": "app_id: 1\napp_hash: x\naccounts:\n  default:\n    app_id: 2\n",
+		"version":  "version: two\n",
+	} {
+		if _, _, err := migrateConfig("c.yaml", []byte(raw)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestMigrateConfigPendingSessionID(t *testing.T) {
+	const legacy = "app_id: 1\napp_hash: x\naccounts:\n  work:\n    app_id: 2\n    app_hash: ${env:TG_TEST_PENDING_HASH}\n"
+	path := filepath.Join(t.TempDir(), "gotd.cli.yaml")
+	t.Setenv("TG_TEST_PENDING_HASH", "")
+	if err := os.Unsetenv("TG_TEST_PENDING_HASH"); err != nil {
+		t.Fatal(err)
+	}
+
+	// work cannot be pinned: the config stops at version 2.
+	res, out, err := migrateConfig(path, []byte(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.To != 2 || !strings.Contains(string(out), "version: 2\n") {
+		t.Fatalf("result = %+v:\n%s", res, out)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(out, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Accounts["work"].SessionID != "" {
+		t.Errorf("work pinned without its app_hash: %+v", cfg.Accounts["work"])
+	}
+
+	// Still unresolved: nothing to write, the reason is reported.
+	again, out2, err := migrateConfig(path, out)
+	if err != nil || again.From != 2 || again.To != 2 || string(out2) != string(out) {
+		t.Fatalf("again = %+v, %v", again, err)
+	}
+	if len(again.Changes) == 0 || !strings.Contains(again.Changes[len(again.Changes)-1], "accounts.work.session_id not pinned yet") {
+		t.Errorf("changes = %v", again.Changes)
+	}
+
+	// Once it resolves, work is pinned and the config reaches version 3.
+	t.Setenv("TG_TEST_PENDING_HASH", "abcd")
+	res, out, err = migrateConfig(path, out)
+	if err != nil || res.From != 2 || res.To != configVersion {
+		t.Fatalf("resolved = %+v, %v", res, err)
+	}
+	cfg = Config{}
+	if err := yaml.Unmarshal(out, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if id := cfg.Accounts["work"].SessionID; id == "" || id != (Account{AppID: 2, AppHash: "abcd"}).seed("work", kindUser) {
+		t.Errorf("work session_id = %q", id)
+	}
+}
+
+func TestLoadConfigMigrates(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "gotd.cli.yaml")
+	const legacy = "app_id: 1\napp_hash: x\n"
+	if err := os.WriteFile(configPath, []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// --dry-run writes nothing.
+	out, err := runRoot(t, "-c", configPath, "config", "migrate", "--dry-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Dry run") {
+		t.Errorf("dry run output = %q", out)
+	}
+	if raw, _ := os.ReadFile(configPath); string(raw) != legacy {
+		t.Errorf("dry run wrote the config:\n%s", raw)
+	}
+
+	// Completion and doctor migrate in memory only.
+	if cfg, err := peekConfig(configPath); err != nil || cfg.Version != configVersion {
+		t.Errorf("peek = %+v, %v", cfg, err)
+	}
+	if raw, _ := os.ReadFile(configPath); string(raw) != legacy {
+		t.Errorf("peek wrote the config:\n%s", raw)
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Version != configVersion || cfg.Accounts[defaultAccount].SessionID == "" {
+		t.Errorf("loaded config = %+v", cfg)
+	}
+	backup, err := os.ReadFile(configPath + ".v1.bak")
+	if err != nil || string(backup) != legacy {
+		t.Errorf("backup = %q, %v", backup, err)
+	}
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(raw), "version: 3\n") {
+		t.Errorf("config not migrated on disk:\n%s", raw)
+	}
+}
+
+func TestLoadConfigMigratesConcurrently(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "gotd.cli.yaml")
+	if err := os.WriteFile(configPath, []byte("app_id: 1\napp_hash: x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Go(func() {
+			cfg, err := loadConfig(configPath)
+			if err == nil && cfg.Accounts[defaultAccount].AppID != 1 {
+				err = errors.New("default account lost")
+			}
+			errs[i] = err
+		})
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	// The backup is the original, not a config another process migrated.
+	if backup, _ := os.ReadFile(configPath + ".v1.bak"); string(backup) != "app_id: 1\napp_hash: x\n" {
+		t.Errorf("backup = %q", backup)
+	}
+}
@@ -61,9 +61,12 @@ func (a *app) newContactsListCmd() *cobra.Command {
 				}
 				contacts, ok := res.(*tg.ContactsContacts)
 				if !ok {
-					return a.printer.Emit(peerListResult{})
+					return a.out(ctx).Emit(peerListResult{})
 				}
-				return a.printer.Emit(usersToPeerList(contacts.Users))
+				if err := a.cachePeers(ctx, api, contacts.Users, nil); err != nil {
+					return err
+				}
+				return a.out(ctx).Emit(usersToPeerList(contacts.Users))
 			})
 		},
 	}
@@ -81,12 +84,15 @@ func (a *app) newContactsSearchCmd() *cobra.Command {
 				if err != nil {
 					return errors.Wrap(err, "contacts.search")
 				}
+				if err := a.cachePeers(ctx, api, found.Users, found.Chats); err != nil {
+					return err
+				}
 				ent := entitiesOf(found.Users, found.Chats)
 				var out peerListResult
 				for _, pc := range found.MyResults {
 					out.Peers = append(out.Peers, describePeer(pc, ent))
 				}
-				return a.printer.Emit(out)
+				return a.out(ctx).Emit(out)
 			})
 		},
 	}
@@ -111,7 +117,7 @@ func (a *app) newContactsAddCmd() *cobra.Command {
 				if err != nil {
 					return errors.Wrap(err, "contacts.importContacts")
 				}
-				return a.printer.Emit(usersToPeerList(res.Users))
+				return a.out(ctx).Emit(usersToPeerList(res.Users))
 			})
 		},
 	}
@@ -133,7 +139,7 @@ func (a *app) newContactsImportCmd() *cobra.Command {
 				if err != nil {
 					return errors.Wrap(err, "contacts.importContacts")
 				}
-				return a.printer.Emit(usersToPeerList(res.Users))
+				return a.out(ctx).Emit(usersToPeerList(res.Users))
 			})
 		},
 	}
@@ -182,7 +188,7 @@ func (a *app) newContactsDeleteCmd() *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -197,7 +203,7 @@ func (a *app) newContactsDeleteCmd() *cobra.Command {
 				if _, err := api.ContactsDeleteContacts(ctx, []tg.InputUserClass{user.InputUser()}); err != nil {
 					return errors.Wrap(err, "contacts.deleteContacts")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -219,7 +225,7 @@ func (a *app) blockCmd(use, short string, unblock bool) *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -235,7 +241,7 @@ func (a *app) blockCmd(use, short string, unblock bool) *cobra.Command {
 				if err != nil {
 					return errors.Wrap(err, "contacts block/unblock")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -254,11 +260,11 @@ func (a *app) newContactsBlockedCmd() *cobra.Command {
 				}
 				switch v := res.(type) {
 				case *tg.ContactsBlocked:
-					return a.printer.Emit(usersToPeerList(v.Users))
+					return a.out(ctx).Emit(usersToPeerList(v.Users))
 				case *tg.ContactsBlockedSlice:
-					return a.printer.Emit(usersToPeerList(v.Users))
+					return a.out(ctx).Emit(usersToPeerList(v.Users))
 				default:
-					return a.printer.Emit(peerListResult{})
+					return a.out(ctx).Emit(peerListResult{})
 				}
 			})
 		},
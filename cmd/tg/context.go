@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"sort"
-	"strconv"
 
 	"github.com/go-faster/errors"
 	"github.com/spf13/cobra"
@@ -42,28 +41,29 @@ func (a *app) newContextCmd() *cobra.Command {
 		GroupID: groupMessaging,
 		Long:    "Show the messages immediately before and after a given message id.",
 		Example: `  tg context @durov 12345
-  tg context me 1000 --radius 10`,
-		Args:              cobra.ExactArgs(2),
+  tg context me 1000 --radius 10
+  tg context https://t.me/c/1234567890/42`,
+		Args:              messageRefArgs(0),
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			id, err := strconv.Atoi(args[1])
+			ref, _, err := splitMessageRef(args)
 			if err != nil {
-				return errors.Wrap(err, "message-id must be an integer")
+				return err
 			}
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
-				peer, err := resolvePeer(ctx, m, args[0])
+				peer, err := resolvePeer(ctx, m, ref.Peer)
 				if err != nil {
 					return err
 				}
-				res, err := messageContext(ctx, api, peer, id, radius)
+				res, err := messageContext(ctx, api, peer, ref.ID, radius)
 				if err != nil {
 					return err
 				}
-				return a.printer.Emit(res)
+				return a.out(ctx).Emit(res)
 			})
 		},
 	}
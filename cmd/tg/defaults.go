@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Flags that pick the config and the account cannot be defaulted by them.
+const (
+	flagConfig  = "config"
+	flagAccount = "account"
+)
+
+// flattenDefaults flattens a defaults section into dotted keys, so
+// `history: {limit: 100}` and `history.limit: 100` are the same.
+func flattenDefaults(prefix string, m map[string]any, out map[string]any) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if sub, ok := v.(map[string]any); ok {
+			flattenDefaults(key, sub, out)
+			continue
+		}
+		out[key] = v
+	}
+}
+
+// defaultLayers returns the flattened defaults that apply to an account, in
+// increasing precedence: the top-level section, then the account's own. With
+// --account all only the top-level section applies.
+func (c Config) defaultLayers(label string) []map[string]any {
+	global := map[string]any{}
+	flattenDefaults("", c.Defaults, global)
+	layers := []map[string]any{global}
+	if acc, ok := c.Accounts[label]; ok && len(acc.Defaults) > 0 {
+		own := map[string]any{}
+		flattenDefaults("", acc.Defaults, own)
+		layers = append(layers, own)
+	}
+	return layers
+}
+
+// commandKey returns the dotted path of cmd below the root ("chats.list"); it
+// is empty for the root.
+func commandKey(cmd *cobra.Command) string {
+	var parts []string
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		parts = append([]string{c.Name()}, parts...)
+	}
+	return strings.Join(parts, ".")
+}
+
+// splitDefaultKey splits "chats.list.limit" into the command key and the flag.
+func splitDefaultKey(key string) (command, flag string) {
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return "", key
+}
+
+// setFlagDefault sets a flag from a config value without marking it as given
+// on the command line, so it acts as the flag's default.
+func setFlagDefault(f *pflag.Flag, v any) error {
+	if list, ok := v.([]any); ok {
+		sv, ok := f.Value.(pflag.SliceValue)
+		if !ok {
+			return errors.Errorf("--%s takes a single value, not a list", f.Name)
+		}
+		vals := make([]string, len(list))
+		for i, item := range list {
+			vals[i] = fmt.Sprint(item)
+		}
+		return sv.Replace(vals)
+	}
+	if v == nil {
+		return errors.Errorf("--%s: empty value", f.Name)
+	}
+	return f.Value.Set(fmt.Sprint(v))
+}
+
+// applyDefaults sets the flags of cmd that the config defaults and the command
+// line leaves unset. Bare keys ("output", "limit") apply to every command
+// with that flag; dotted keys ("history.limit") to one command, where they
+// take precedence. Account defaults take precedence over top-level ones. It
+// returns the names of the flags it set.
+func applyDefaults(cmd *cobra.Command, layers []map[string]any) (map[string]bool, error) {
+	key := commandKey(cmd)
+	values := map[string]any{}
+	sources := map[string]string{}
+	for _, specific := range []bool{false, true} {
+		for _, layer := range layers {
+			for k, v := range layer {
+				command, name := splitDefaultKey(k)
+				if (command == "") == specific || (specific && command != key) {
+					continue
+				}
+				values[name], sources[name] = v, k
+			}
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	set := map[string]bool{}
+	for _, name := range names {
+		src := sources[name]
+		f := cmd.Flags().Lookup(name)
+		switch {
+		case name == flagConfig || name == flagAccount:
+			return nil, withClass(classUsage, errors.Errorf("defaults: %s: --%s cannot be set from the config", src, name))
+		case f == nil && strings.Contains(src, "."):
+			return nil, withClass(classUsage, errors.Errorf("defaults: %s: tg %s has no --%s flag",
+				src, strings.ReplaceAll(key, ".", " "), name))
+		case f == nil || f.Changed:
+			continue
+		}
+		if err := setFlagDefault(f, values[name]); err != nil {
+			return nil, withClass(classUsage, errors.Wrapf(err, "defaults: %s", src))
+		}
+		set[f.Name] = true
+	}
+	return set, nil
+}
+
+// checkDefaults reports defaults keys that name no command or flag of the
+// tree under root, for `tg config validate` and the config edits.
+func checkDefaults(root *cobra.Command, cfg Config) []configProblem {
+	var problems []configProblem
+	check := func(section string, m map[string]any) {
+		flat := map[string]any{}
+		flattenDefaults("", m, flat)
+		for k := range flat {
+			command, name := splitDefaultKey(k)
+			if name == flagConfig || name == flagAccount {
+				problems = append(problems, configProblem{Key: section + "." + k,
+					Message: "--" + name + " cannot be set from the config"})
+				continue
+			}
+			c := root
+			if command != "" {
+				found, rest, err := root.Find(strings.Split(command, "."))
+				if err != nil || len(rest) > 0 || found == root {
+					problems = append(problems, configProblem{Key: section + "." + k,
+						Message: "no command tg " + strings.ReplaceAll(command, ".", " ")})
+					continue
+				}
+				c = found
+			}
+			switch {
+			case command == "" && !anyHasFlag(root, name):
+				problems = append(problems, configProblem{Key: section + "." + k, Message: "no command has a --" + name + " flag"})
+			case command != "" && c.Flags().Lookup(name) == nil && c.InheritedFlags().Lookup(name) == nil:
+				problems = append(problems, configProblem{Key: section + "." + k,
+					Message: fmt.Sprintf("tg %s has no --%s flag", strings.ReplaceAll(command, ".", " "), name)})
+			}
+		}
+	}
+	check("defaults", cfg.Defaults)
+	for label, acc := range cfg.Accounts {
+		check("accounts."+label+".defaults", acc.Defaults)
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].Key < problems[j].Key })
+	return problems
+}
+
+// anyHasFlag reports whether cmd or a command below it has the flag name.
+func anyHasFlag(cmd *cobra.Command, name string) bool {
+	if cmd.Flags().Lookup(name) != nil || cmd.PersistentFlags().Lookup(name) != nil {
+		return true
+	}
+	for _, c := range cmd.Commands() {
+		if anyHasFlag(c, name) {
+			return true
+		}
+	}
+	return false
+}
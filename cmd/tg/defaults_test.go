@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestFlattenDefaults(t *testing.T) {
+	out := map[string]any{}
+	flattenDefaults("", map[string]any{
+		"output":       "json",
+		"history":      map[string]any{"limit": 100},
+		"chats.list":   map[string]any{"limit": 5},
+		"upload.parts": 4,
+	}, out)
+	want := map[string]any{"output": "json", "history.limit": 100, "chats.list.limit": 5, "upload.parts": 4}
+	if len(out) != len(want) {
+		t.Fatalf("flatten = %v", out)
+	}
+	for k, v := range want {
+		if out[k] != v {
+			t.Errorf("%s = %v, want %v", k, out[k], v)
+		}
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	newCmd := func() (*cobra.Command, *int, *bool, *[]string) {
+		root := &cobra.Command{Use: "tg"}
+		cmd := &cobra.Command{Use: "history"}
+		root.AddCommand(cmd)
+		var (
+			limit  int
+			silent bool
+			types  []string
+		)
+		cmd.Flags().IntVar(&limit, "limit", 30, "")
+		cmd.Flags().BoolVar(&silent, "silent", false, "")
+		cmd.Flags().StringSliceVar(&types, "type", nil, "")
+		return cmd, &limit, &silent, &types
+	}
+
+	cmd, limit, silent, types := newCmd()
+	if err := cmd.Flags().Parse([]string{"--silent=false"}); err != nil {
+		t.Fatal(err)
+	}
+	set, err := applyDefaults(cmd, []map[string]any{
+		{"limit": 10, "history.limit": 100, "silent": true, "type": []any{"photo", "video"}, "other": 1},
+		{"limit": 50},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The command's own key beats the account's bare one; the command line
+	// beats both.
+	if *limit != 100 || *silent || strings.Join(*types, ",") != "photo,video" {
+		t.Errorf("limit = %d, silent = %v, types = %v", *limit, *silent, *types)
+	}
+	if !set["limit"] || set["silent"] || cmd.Flags().Changed("limit") {
+		t.Errorf("set = %v, changed = %v", set, cmd.Flags().Changed("limit"))
+	}
+
+	cmd, limit, _, _ = newCmd()
+	if _, err := applyDefaults(cmd, []map[string]any{{"history.limit": 100}, {"history.limit": 7}}); err != nil || *limit != 7 {
+		t.Errorf("account layer: limit = %d, %v", *limit, err)
+	}
+
+	for _, layer := range []map[string]any{
+		{"history.limitt": 1},
+		{"limit": "many"},
+		{"limit": []any{1, 2}},
+		{"account": "work"},
+	} {
+		cmd, _, _, _ := newCmd()
+		if _, err := applyDefaults(cmd, []map[string]any{layer}); classify(err) != classUsage {
+			t.Errorf("%v: err = %v, want usage error", layer, err)
+		}
+	}
+}
+
+func TestDefaultsOutput(t *testing.T) {
+	configPath, _ := newTestConfig(t)
+	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("defaults:\n  output: json\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runRoot(t, "-c", configPath, "alias", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"schema": 1`) {
+		t.Errorf("defaults output: %q", out)
+	}
+	out, err = runRoot(t, "-c", configPath, "-o", "text", "alias", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(out, "{") {
+		t.Errorf("explicit --output lost: %q", out)
+	}
+}
+
+func TestCheckDefaults(t *testing.T) {
+	root := newRootCmd()
+	cfg := Config{
+		Defaults: map[string]any{"output": "json", "history": map[string]any{"limit": 100}, "nosuchflag": 1},
+		Accounts: map[string]Account{"work": {Defaults: map[string]any{"send.nowebpage": true, "nosuch.cmd.limit": 1}}},
+	}
+	var got []string
+	for _, p := range checkDefaults(root, cfg) {
+		got = append(got, p.Key)
+	}
+	want := "accounts.work.defaults.nosuch.cmd.limit defaults.nosuchflag"
+	if strings.Join(got, " ") != want {
+		t.Errorf("problems = %v, want %s", got, want)
+	}
+}
+
+func TestConfigChecksDefaults(t *testing.T) {
+	configPath, _ := newTestConfig(t)
+	run := func(args ...string) error {
+		_, err := runRoot(t, append([]string{"-c", configPath, "config"}, args...)...)
+		return err
+	}
+	if err := run("set", "defaults.history.limitt", "1"); classify(err) != classUsage {
+		t.Errorf("set unknown defaults key: err = %v, want usage error", err)
+	}
+
+	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("max_flood_wait: -1s\ndefaults:\n  nosuchflag: 1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Both problems are reported, not just the first kind found.
+	err = run("validate")
+	for _, want := range []string{"max_flood_wait", "defaults.nosuchflag"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("validate: %v, want %s", err, want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 
 	"github.com/go-faster/errors"
 	"github.com/spf13/cobra"
@@ -12,17 +13,33 @@ import (
 	"github.com/gotd/td/tg"
 )
 
-// deletedResult is the result of a delete command.
+// deletedResult is the result of a delete command. With --dry-run nothing is
+// deleted and IDs lists the messages that would be.
 type deletedResult struct {
-	Count int `json:"count"`
+	Count  int   `json:"count"`
+	DryRun bool  `json:"dry_run,omitempty"`
+	IDs    []int `json:"ids,omitempty"`
 }
 
 // MarshalText renders a short summary.
 func (r deletedResult) MarshalText(w io.Writer) error {
+	if r.DryRun {
+		_, err := fmt.Fprintf(w, "would delete %d: %s\n", r.Count, joinIDs(r.IDs))
+		return err
+	}
 	_, err := fmt.Fprintf(w, "deleted %d\n", r.Count)
 	return err
 }
 
+// joinIDs renders message ids as a comma-separated list.
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
+
 // parseIDs converts string args to message ids.
 func parseIDs(args []string) ([]int, error) {
 	ids := make([]int, 0, len(args))
@@ -36,66 +53,105 @@ func parseIDs(args []string) ([]int, error) {
 	return ids, nil
 }
 
-// deleteMessages deletes messages by id, branching on peer type.
+// maxBatchIDs is the most message ids one delete or forward request takes.
+const maxBatchIDs = 100
+
+// deleteMessages deletes messages by id, branching on peer type, in batches of
+// maxBatchIDs.
 func deleteMessages(ctx context.Context, api *tg.Client, peer tg.InputPeerClass, ids []int, revoke bool) error {
-	if ch, ok := peer.(*tg.InputPeerChannel); ok {
-		if _, err := api.ChannelsDeleteMessages(ctx, &tg.ChannelsDeleteMessagesRequest{
-			Channel: &tg.InputChannel{ChannelID: ch.ChannelID, AccessHash: ch.AccessHash},
-			ID:      ids,
+	for _, batch := range chunkIDs(ids, maxBatchIDs) {
+		if ch, ok := peer.(*tg.InputPeerChannel); ok {
+			if _, err := api.ChannelsDeleteMessages(ctx, &tg.ChannelsDeleteMessagesRequest{
+				Channel: &tg.InputChannel{ChannelID: ch.ChannelID, AccessHash: ch.AccessHash},
+				ID:      batch,
+			}); err != nil {
+				return errors.Wrap(err, "channels.deleteMessages")
+			}
+			continue
+		}
+		if _, err := api.MessagesDeleteMessages(ctx, &tg.MessagesDeleteMessagesRequest{
+			Revoke: revoke,
+			ID:     batch,
 		}); err != nil {
-			return errors.Wrap(err, "channels.deleteMessages")
+			return errors.Wrap(err, "messages.deleteMessages")
 		}
-		return nil
-	}
-	if _, err := api.MessagesDeleteMessages(ctx, &tg.MessagesDeleteMessagesRequest{
-		Revoke: revoke,
-		ID:     ids,
-	}); err != nil {
-		return errors.Wrap(err, "messages.deleteMessages")
 	}
 	return nil
 }
 
+// errNeedYes refuses a destructive action on n selected messages without
+// --yes, telling how many messages it would touch.
+func errNeedYes(verb string, n int) error {
+	return withClass(classUsage, errors.Errorf(
+		"%d message(s) match; refusing to %s without --yes (list them with --dry-run)", n, verb))
+}
+
 func (a *app) newDeleteCmd() *cobra.Command {
 	var (
 		yes    bool
 		revoke bool
+		dryRun bool
 	)
 
 	cmd := &cobra.Command{
-		Use:     "delete <peer> <message-id>...",
+		Use:     "delete <peer> <message-id|selector>...",
 		Aliases: []string{"del", "rm"},
 		Short:   "Delete one or more messages",
 		GroupID: groupMessaging,
-		Long: `Delete one or more messages by id. Destructive: requires --yes.
-By default deletes for everyone (revoke); pass --revoke=false to delete only
-your own copy.`,
+		Long: `Delete messages by id or selector. Destructive: requires --yes; without it
+the selection is resolved and the number of matching messages reported, and
+--dry-run lists them. By default deletes for everyone (revoke); pass
+--revoke=false to delete only your own copy.
+
+Selectors (resolved against the chat's history before anything is deleted):
+
+  100-200           existing messages with ids in the range
+  last:20           the 20 newest messages
+  since:2026-01-01  messages since a date, an RFC3339 time or a duration ago
+  from:@user        messages sent by a peer; narrows the other selectors
+  -                 ids from stdin, one per line or tg -o jsonl output`,
 		Example: `  tg delete @durov 12345 --yes
-  tg delete me 1 2 3 --yes`,
-		Args:              cobra.MinimumNArgs(2),
+  tg delete me 1 2 3 --yes
+  tg delete me 100-200 --dry-run
+  tg delete @group last:50 from:me --yes
+  tg search @group spam -o jsonl | tg delete @group - --yes`,
+		Args:              messageSelectArgs,
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if !yes {
-				return errors.New("refusing to delete without --yes")
-			}
-			ids, err := parseIDs(args[1:])
+			peerArg, selectors := splitMessageSelect(args)
+			sel, err := parseSelection(selectors, cmd.InOrStdin())
 			if err != nil {
 				return err
 			}
+			if !yes && !dryRun && sel.literal() {
+				return errNeedYes("delete", len(sel.ids))
+			}
 
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
-				peer, err := resolvePeer(ctx, m, args[0])
+				peer, err := resolvePeer(ctx, m, peerArg)
 				if err != nil {
 					return err
 				}
+				ids, err := resolveSelection(ctx, api, m, peer, sel)
+				if err != nil {
+					return err
+				}
+				switch {
+				case dryRun:
+					return a.out(ctx).Emit(deletedResult{Count: len(ids), DryRun: true, IDs: ascending(ids)})
+				case !yes:
+					return errNeedYes("delete", len(ids))
+				case len(ids) == 0:
+					return a.out(ctx).Emit(deletedResult{})
+				}
 				if err := deleteMessages(ctx, api, peer, ids, revoke); err != nil {
 					return err
 				}
-				return a.printer.Emit(deletedResult{Count: len(ids)})
+				return a.out(ctx).Emit(deletedResult{Count: len(ids)})
 			})
 		},
 	}
@@ -103,6 +159,7 @@ your own copy.`,
 	fs := cmd.Flags()
 	fs.BoolVar(&yes, "yes", false, "confirm deletion")
 	fs.BoolVar(&revoke, "revoke", true, "delete for everyone (not just your copy)")
+	fs.BoolVar(&dryRun, "dry-run", false, "list the selected messages without deleting them")
 
 	return cmd
 }
@@ -150,7 +207,7 @@ By default only clears your own copy; pass --revoke to delete for everyone.`,
 				return errors.New("refusing to delete history without --yes")
 			}
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -161,7 +218,7 @@ By default only clears your own copy; pass --revoke to delete for everyone.`,
 				if err := deleteHistory(ctx, api, peer, revoke, !revoke); err != nil {
 					return err
 				}
-				return a.printer.Emit(deletedResult{Count: 1})
+				return a.out(ctx).Emit(deletedResult{Count: 1})
 			})
 		},
 	}
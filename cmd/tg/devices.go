@@ -186,6 +186,42 @@ func writeDevice(w io.Writer, d deviceInfo) error {
 	return nil
 }
 
+// Items implements output.ItemLister: the current device (if any) first, then
+// the other sessions.
+func (r devicesResult) Items() []any {
+	items := output.Items(r.Others)
+	if r.Current != nil {
+		items = append([]any{*r.Current}, items...)
+	}
+	return items
+}
+
+// Table implements output.Tabler for CSV/TSV; text mode keeps the Devices-screen
+// layout of MarshalText.
+func (r devicesResult) Table() output.Table {
+	devices := make([]deviceInfo, 0, len(r.Others)+1)
+	if r.Current != nil {
+		devices = append(devices, *r.Current)
+	}
+	devices = append(devices, r.Others...)
+	return output.NewTable([]output.Column[deviceInfo]{
+		{Name: "hash", Value: func(d deviceInfo) string { return strconv.FormatInt(d.Hash, 10) }},
+		{Name: "current", Value: func(d deviceInfo) string { return strconv.FormatBool(d.Current) }},
+		{Name: "official_app", Value: func(d deviceInfo) string { return strconv.FormatBool(d.OfficialApp) }},
+		{Name: "device_model", Value: func(d deviceInfo) string { return d.DeviceModel }},
+		{Name: "platform", Value: func(d deviceInfo) string { return d.Platform }},
+		{Name: "system_version", Value: func(d deviceInfo) string { return d.SystemVersion }},
+		{Name: "app_name", Value: func(d deviceInfo) string { return d.AppName }},
+		{Name: "app_version", Value: func(d deviceInfo) string { return d.AppVersion }},
+		{Name: "app_id", Value: func(d deviceInfo) string { return strconv.Itoa(d.APIID) }},
+		{Name: "ip", Value: func(d deviceInfo) string { return d.IP }},
+		{Name: "country", Value: func(d deviceInfo) string { return d.Country }},
+		{Name: "region", Value: func(d deviceInfo) string { return d.Region }},
+		{Name: "date_created", Value: func(d deviceInfo) string { return dateCell(d.DateCreated) }},
+		{Name: "date_active", Value: func(d deviceInfo) string { return dateCell(d.DateActive) }},
+	}, devices)
+}
+
 // MarshalText renders the current device and active sessions like Telegram
 // Desktop's Devices screen.
 func (r devicesResult) MarshalText(w io.Writer) error {
@@ -242,7 +278,7 @@ device, app (with its app_id in plaintext), location, and last-active time.`,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				return runDevices(ctx, api, a.printer)
+				return runDevices(ctx, api, a.out(ctx))
 			})
 		},
 	}
@@ -270,7 +306,7 @@ terminate the current session this way; use ` + "`tg logout`" + ` instead.`,
 				return errors.Wrapf(err, "invalid session hash %q", args[0])
 			}
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				return runTerminateSession(ctx, api, hash, a.printer)
+				return runTerminateSession(ctx, api, hash, a.out(ctx))
 			})
 		},
 	}
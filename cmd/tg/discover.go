@@ -20,7 +20,7 @@ func (a *app) newResolveCmd() *cobra.Command {
 		Args:    cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -28,7 +28,7 @@ func (a *app) newResolveCmd() *cobra.Command {
 				if err != nil {
 					return errors.Wrapf(err, "resolve %q", args[0])
 				}
-				return a.printer.Emit(describeManagedPeer(p))
+				return a.out(ctx).Emit(describeManagedPeer(p))
 			})
 		},
 	}
@@ -65,7 +65,7 @@ func (a *app) newSearchPublicCmd() *cobra.Command {
 						out.Peers = append(out.Peers, ref)
 					}
 				}
-				return a.printer.Emit(out)
+				return a.out(ctx).Emit(out)
 			})
 		},
 	}
@@ -86,7 +86,7 @@ func (a *app) newSubscribeCmd() *cobra.Command {
 		Args:    cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -101,7 +101,7 @@ func (a *app) newSubscribeCmd() *cobra.Command {
 				if _, err := api.ChannelsJoinChannel(ctx, ch.InputChannel()); err != nil {
 					return errors.Wrap(err, "channels.joinChannel")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/gotd/td/session"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/output"
+	"github.com/gotd/cli/internal/peercache"
+	"github.com/gotd/cli/internal/proxy"
+)
+
+// Doctor check outcomes.
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// Clock skew thresholds: MTProto rejects messages whose time is too far from
+// the server's, and 2FA and logins are sensitive long before that.
+const (
+	skewWarn = 30 * time.Second
+	skewFail = 5 * time.Minute
+)
+
+// defaultDoctorTimeout bounds each account's connection check.
+const defaultDoctorTimeout = 20 * time.Second
+
+// doctorCheck is one line of the `tg doctor` report.
+type doctorCheck struct {
+	Check   string `json:"check"`
+	Account string `json:"account,omitempty"`
+	Status  string `json:"status"`
+	Detail  string `json:"detail"`
+}
+
+// doctorResult is the result of `tg doctor`.
+type doctorResult struct {
+	// OK reports that no check failed; warnings do not count.
+	OK     bool          `json:"ok"`
+	Passed int           `json:"passed"`
+	Warned int           `json:"warned"`
+	Failed int           `json:"failed"`
+	Checks []doctorCheck `json:"checks"`
+}
+
+// Items implements output.ItemLister.
+func (r doctorResult) Items() []any { return output.Items(r.Checks) }
+
+// MarshalText renders one check per line and a summary.
+func (r doctorResult) MarshalText(w io.Writer) error {
+	for _, c := range r.Checks {
+		if _, err := fmt.Fprintf(w, "%-4s  %-16s %-12s %s\n", c.Status, c.Check, c.Account, c.Detail); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%d passed, %d warnings, %d failed\n", r.Passed, r.Warned, r.Failed)
+	return err
+}
+
+// doctor collects checks into a report.
+type doctor struct {
+	res doctorResult
+}
+
+func (d *doctor) add(check, account, status, detail string) {
+	d.res.Checks = append(d.res.Checks, doctorCheck{Check: check, Account: account, Status: status, Detail: detail})
+	switch status {
+	case checkPass:
+		d.res.Passed++
+	case checkWarn:
+		d.res.Warned++
+	default:
+		d.res.Failed++
+	}
+}
+
+func (d *doctor) pass(check, account, format string, args ...any) {
+	d.add(check, account, checkPass, fmt.Sprintf(format, args...))
+}
+
+func (d *doctor) warn(check, account, format string, args ...any) {
+	d.add(check, account, checkWarn, fmt.Sprintf(format, args...))
+}
+
+func (d *doctor) fail(check, account string, err error) {
+	d.add(check, account, checkFail, err.Error())
+}
+
+func (d *doctor) result() doctorResult {
+	d.res.OK = d.res.Failed == 0
+	return d.res
+}
+
+// filePerm reports whether a secret file is readable by other users; it is
+// empty when the mode is private or the platform has no such bits.
+func filePerm(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" || fi.Mode().Perm()&0o077 == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("mode %04o lets other users read it: chmod 600 %s", fi.Mode().Perm(), path), nil
+}
+
+// proxyLabel renders a proxy URL without its credentials or secret.
+func proxyLabel(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "proxy"
+	}
+	if q := u.Query(); q.Get("server") != "" {
+		return fmt.Sprintf("%s://%s:%s", u.Scheme, q.Get("server"), q.Get("port"))
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// checkConfig loads and checks the config file; ok is false when there is no
+// usable config to check further.
+func (a *app) checkConfig(d *doctor) (ok bool) {
+	// Doctor only reports: an older config is migrated in memory.
+	cfg, err := peekConfig(a.configPath)
+	if err != nil {
+		d.fail("config", "", err)
+		return false
+	}
+	a.cfg = cfg
+	a.sessionKey = newSessionKey(cfg.Encryption, filepath.Dir(a.configPath), true)
+	d.pass("config", "", "%s: %d account(s)", a.configPath, len(cfg.labels()))
+	if msg, err := filePerm(a.configPath); err == nil && msg != "" {
+		d.warn("config perms", "", "%s", msg)
+	}
+	if raw, err := os.ReadFile(a.configPath); err == nil { // #nosec G304 // path provided via flag
+		if res, _, err := migrateConfig(a.configPath, raw); err == nil && res.From != res.To {
+			d.warn("config version", "", "version %d, current is %d: run tg config migrate", res.From, res.To)
+		}
+	}
+	return true
+}
+
+// checkStorage checks the session backend and at-rest encryption.
+func (a *app) checkStorage(d *doctor) {
+	switch {
+	case a.cfg.SessionHelper != "":
+		d.pass("session backend", "", "session_helper %q", a.cfg.SessionHelper)
+	case a.useKeychain() && runtime.GOOS == "darwin":
+		if _, err := exec.LookPath("security"); err != nil {
+			d.fail("session backend", "", errors.Wrap(err, "keychain: security tool not found (set keychain: false)"))
+		} else {
+			d.pass("session backend", "", "macOS Keychain")
+		}
+	case a.sessionKey != nil:
+		d.pass("session backend", "", "encrypted files in %s", filepath.Dir(a.configPath))
+	default:
+		d.pass("session backend", "", "files in %s", filepath.Dir(a.configPath))
+	}
+	if a.sessionKey == nil {
+		return
+	}
+	// Check the secret is at hand without prompting.
+	key := newSessionKey(a.cfg.Encryption, filepath.Dir(a.configPath), false)
+	switch _, err := key.get(false); {
+	case err == nil && key.keyFile != "" && key.env == "":
+		if msg, err := filePerm(key.keyFile); err == nil && msg != "" {
+			d.warn("encryption", "", "key file %s", msg)
+		} else {
+			d.pass("encryption", "", "key file %s", key.keyFile)
+		}
+	case err == nil:
+		d.pass("encryption", "", "key from %s", envSessionKey)
+	case key.keyFile == "":
+		d.warn("encryption", "", "no %s or key_file: the passphrase is asked on the terminal, so headless runs fail", envSessionKey)
+	default:
+		d.fail("encryption", "", err)
+	}
+}
+
+// checkAccount runs the local checks of one account and, unless offline, the
+// connection checks.
+func (a *app) checkAccount(ctx context.Context, d *doctor, label string, offline bool, timeout time.Duration) {
+	acc, err := a.cfg.account(label)
+	if err != nil {
+		d.fail("account", label, err)
+		return
+	}
+	dir := filepath.Dir(a.configPath)
+
+	appID, _, err := effectiveCreds(acc)
+	switch {
+	case err != nil:
+		d.fail("credentials", label, err)
+	case acc.AppID != 0 && acc.AppHash != "":
+		d.pass("credentials", label, "configured app_id %d", appID)
+	default:
+		d.pass("credentials", label, "built-in app_id %d", appID)
+	}
+
+	hasSession, err := a.sessionStore(label, acc, kindUser).Exists(ctx)
+	switch {
+	case err != nil:
+		d.fail("session", label, err)
+	case !hasSession && acc.BotToken != "":
+		d.pass("session", label, "no user session; bot token configured")
+	case !hasSession:
+		d.warn("session", label, "no user session: run tg login --account %s", label)
+	default:
+		path := acc.sessionPath(dir, label, kindUser)
+		if msg, err := filePerm(path); err == nil && msg != "" {
+			d.warn("session", label, "session file %s", msg)
+		} else {
+			d.pass("session", label, "user session present")
+		}
+	}
+
+	cachePath := acc.peerCachePath(dir, label, kindUser)
+	if _, err := os.Stat(cachePath); errors.Is(err, os.ErrNotExist) {
+		d.pass("peer cache", label, "not created yet")
+	} else if store, err := peercache.OpenCodec(cachePath, cacheCodec(a.cfg, a.sessionKey)); err != nil {
+		d.fail("peer cache", label, err)
+	} else {
+		d.pass("peer cache", label, "%d peers", len(store.Entries()))
+	}
+
+	proxyURL := a.proxyURL
+	if proxyURL == "" {
+		proxyURL = acc.Proxy
+	}
+	via := "direct"
+	if proxyURL != "" {
+		if _, err := proxy.Resolver(proxyURL); err != nil {
+			d.fail("proxy", label, err)
+			return
+		}
+		via = proxyLabel(proxyURL)
+		d.pass("proxy", label, "%s", via)
+	}
+
+	if offline {
+		return
+	}
+	a.checkConnection(ctx, d, label, hasSession, via, timeout)
+}
+
+// checkConnection connects to Telegram and checks reachability, clock skew and
+// (with a session) the auth status. Without a session it connects with a
+// throwaway one, so nothing is written.
+func (a *app) checkConnection(ctx context.Context, d *doctor, label string, hasSession bool, via string, timeout time.Duration) {
+	st, err := a.accountState(label)
+	if err != nil {
+		d.fail("connect", label, err)
+		return
+	}
+	rp := runParams{auth: authUser}
+	if !hasSession {
+		rp.storage = &session.StorageMemory{}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	connected := false
+	err = a.connectWith(ctx, st, rp, func(ctx context.Context, client *telegram.Client, _ tg.UpdateDispatcher) error {
+		connected = true
+		cfg, err := client.API().HelpGetConfig(ctx)
+		if err != nil {
+			return errors.Wrap(err, "help.getConfig")
+		}
+		d.pass("connect", label, "DC %d (%s) in %s", cfg.ThisDC, via, time.Since(start).Round(time.Millisecond))
+
+		skew := time.Since(time.Unix(int64(cfg.Date), 0)).Round(time.Second)
+		switch abs := max(skew, -skew); {
+		case abs >= skewFail:
+			d.fail("clock", label, errors.Errorf("local clock is %s off the server's: sync it (NTP)", skew))
+		case abs >= skewWarn:
+			d.warn("clock", label, "local clock is %s off the server's", skew)
+		default:
+			d.pass("clock", label, "within %s of the server", skewWarn)
+		}
+
+		if !hasSession {
+			return nil
+		}
+		status, err := client.Auth().Status(ctx)
+		switch {
+		case err != nil:
+			d.fail("auth", label, errors.Wrap(err, "auth status"))
+		case !status.Authorized:
+			d.fail("auth", label, errors.Errorf("session is not authorized (revoked?): run tg login --account %s", label))
+		default:
+			name := fmt.Sprintf("id %d", status.User.ID)
+			if status.User.Username != "" {
+				name = "@" + status.User.Username
+			}
+			d.pass("auth", label, "authorized as %s", name)
+		}
+		return nil
+	})
+	if err == nil {
+		return
+	}
+	if !connected {
+		d.fail("connect", label, errors.Wrapf(err, "DC unreachable (%s)", via))
+		return
+	}
+	d.fail("connect", label, err)
+}
+
+func (a *app) newDoctorCmd() *cobra.Command {
+	var (
+		offline bool
+		timeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:     "doctor",
+		Short:   "Check the setup and report problems",
+		GroupID: groupAuth,
+		Long: `Check everything a run might trip over and report each check as pass, warn
+or fail: the config file and its permissions, the session backend and
+encryption key, and per account the app credentials, the session, the peer
+cache, the proxy, a real connection to Telegram, the clock skew against the
+server and the auth status.
+
+Every configured account is checked unless --account selects one. The
+connection check uses a throwaway session for accounts without one, so doctor
+never creates sessions. --offline skips the network checks.
+
+The command itself succeeds even when checks fail: read "ok" (or "failed") in
+the JSON report. Attach "tg doctor -o json" to support requests.`,
+		Example: `  tg doctor
+  tg doctor --account work -o json
+  tg doctor --offline`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var d doctor
+			if !a.checkConfig(&d) {
+				return a.printer.Emit(d.result())
+			}
+			a.checkStorage(&d)
+
+			labels := a.cfg.labels()
+			if a.accountFlag != "" && a.accountFlag != "all" {
+				labels = []string{a.accountFlag}
+			}
+			for _, label := range labels {
+				if err := cmd.Context().Err(); err != nil {
+					return err
+				}
+				a.checkAccount(cmd.Context(), &d, label, offline, timeout)
+			}
+			return a.printer.Emit(d.result())
+		},
+	}
+
+	cmd.Flags().BoolVar(&offline, "offline", false, "skip the checks that connect to Telegram")
+	cmd.Flags().DurationVar(&timeout, "timeout", defaultDoctorTimeout, "time limit for each account's connection checks")
+
+	return cmd
+}
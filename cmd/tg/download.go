@@ -145,32 +145,33 @@ message. --out may be a file path or a directory; by default the media's own
 filename is used in the current directory.`,
 		Example: `  tg download @durov 12345
   tg download me 1000 --out ./downloads/
-  tg download @channel 42 --out file.bin`,
-		Args:              cobra.ExactArgs(2),
+  tg download @channel 42 --out file.bin
+  tg download https://t.me/c/1234567890/42`,
+		Args:              messageRefArgs(0),
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			id, err := strconv.Atoi(args[1])
+			ref, _, err := splitMessageRef(args)
 			if err != nil {
-				return errors.Wrap(err, "message-id must be an integer")
+				return err
 			}
 
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
-				peer, err := resolvePeer(ctx, m, args[0])
+				peer, err := resolvePeer(ctx, m, ref.Peer)
 				if err != nil {
 					return err
 				}
 
-				msg, err := getMessage(ctx, api, peer, id)
+				msg, err := getMessage(ctx, api, peer, ref.ID)
 				if err != nil {
 					return err
 				}
 				media, ok := msg.GetMedia()
 				if !ok {
-					return errors.Errorf("message #%d has no media", id)
+					return errors.Errorf("message #%d has no media", ref.ID)
 				}
 				loc, name, err := mediaLocation(media)
 				if err != nil {
@@ -186,7 +187,7 @@ filename is used in the current directory.`,
 				if err != nil {
 					return errors.Wrap(err, "stat downloaded file")
 				}
-				return a.printer.Emit(downloadResult{Path: path, Size: info.Size()})
+				return a.out(ctx).Emit(downloadResult{Path: path, Size: info.Size()})
 			})
 		},
 	}
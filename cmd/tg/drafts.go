@@ -10,6 +10,8 @@ import (
 
 	"github.com/gotd/td/telegram/query"
 	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/output"
 )
 
 // draftItem is one saved draft.
@@ -24,6 +26,9 @@ type draftsResult struct {
 	Drafts []draftItem `json:"drafts"`
 }
 
+// Items implements output.ItemLister.
+func (r draftsResult) Items() []any { return output.Items(r.Drafts) }
+
 // MarshalText renders one draft per line.
 func (r draftsResult) MarshalText(w io.Writer) error {
 	for _, d := range r.Drafts {
@@ -47,12 +52,23 @@ func saveDraft(ctx context.Context, api *tg.Client, peer tg.InputPeerClass, text
 
 // listDrafts collects drafts from the dialog list.
 func listDrafts(ctx context.Context, api *tg.Client) (draftsResult, error) {
+	var out draftsResult
+	if err := streamDrafts(ctx, api, func(d draftItem) error {
+		out.Drafts = append(out.Drafts, d)
+		return nil
+	}); err != nil {
+		return draftsResult{}, err
+	}
+	return out, nil
+}
+
+// streamDrafts is listDrafts, handing each draft to each as it is found.
+func streamDrafts(ctx context.Context, api *tg.Client, each func(draftItem) error) error {
 	// Scan every dialog to collect drafts. The iterator's default batch size is
 	// 1 (one messages.getDialogs RPC per dialog), so without batching this walks
 	// the whole account one round trip at a time. 100 is the per-request maximum
 	// Telegram serves.
 	iter := query.GetDialogs(api).BatchSize(100).Iter()
-	var out draftsResult
 	for iter.Next(ctx) {
 		elem := iter.Value()
 		dlg, ok := elem.Dialog.(*tg.Dialog)
@@ -63,16 +79,18 @@ func listDrafts(ctx context.Context, api *tg.Client) (draftsResult, error) {
 		if !ok || draft.Message == "" {
 			continue
 		}
-		out.Drafts = append(out.Drafts, draftItem{
+		if err := each(draftItem{
 			Peer:    describePeer(dlg.Peer, elem.Entities),
 			Message: draft.Message,
 			Date:    draft.Date,
-		})
+		}); err != nil {
+			return err
+		}
 	}
 	if err := iter.Err(); err != nil {
-		return draftsResult{}, errors.Wrap(err, "iterate dialogs")
+		return errors.Wrap(err, "iterate dialogs")
 	}
-	return out, nil
+	return nil
 }
 
 func (a *app) newDraftCmd() *cobra.Command {
@@ -94,7 +112,7 @@ func (a *app) newDraftSetCmd() *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -105,7 +123,7 @@ func (a *app) newDraftSetCmd() *cobra.Command {
 				if err := saveDraft(ctx, api, peer, args[1]); err != nil {
 					return err
 				}
-				return a.printer.Emit(pinResult{OK: true})
+				return a.out(ctx).Emit(pinResult{OK: true})
 			})
 		},
 	}
@@ -120,7 +138,7 @@ func (a *app) newDraftClearCmd() *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -131,7 +149,7 @@ func (a *app) newDraftClearCmd() *cobra.Command {
 				if err := saveDraft(ctx, api, peer, ""); err != nil {
 					return err
 				}
-				return a.printer.Emit(pinResult{OK: true})
+				return a.out(ctx).Emit(pinResult{OK: true})
 			})
 		},
 	}
@@ -146,11 +164,12 @@ func (a *app) newDraftsCmd() *cobra.Command {
 		Args:    cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				res, err := listDrafts(ctx, api)
-				if err != nil {
+				var res draftsResult
+				a.out(ctx).Begin()
+				if err := streamDrafts(ctx, api, output.Collect(a.out(ctx), &res.Drafts)); err != nil {
 					return err
 				}
-				return a.printer.Emit(res)
+				return a.out(ctx).End(res)
 			})
 		},
 	}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"strconv"
 
 	"github.com/go-faster/errors"
 	"github.com/spf13/cobra"
@@ -22,22 +21,23 @@ func (a *app) newEditCmd() *cobra.Command {
 		GroupID: groupMessaging,
 		Long:    "Edit the text of a message you sent. The peer is me/self, @username, phone, or a t.me link.",
 		Example: `  tg edit @durov 12345 "updated text"
-  tg edit me 1000 --html "<b>bold</b>"`,
-		Args:              cobra.ExactArgs(3),
+  tg edit me 1000 --html "<b>bold</b>"
+  tg edit https://t.me/c/1234567890/42 "fixed typo"`,
+		Args:              messageRefArgs(1),
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			id, err := strconv.Atoi(args[1])
+			ref, rest, err := splitMessageRef(args)
 			if err != nil {
-				return errors.Wrap(err, "message-id must be an integer")
+				return err
 			}
-			text := args[2]
+			text := rest[0]
 
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				sender, m, err := a.sender(api)
+				sender, m, err := a.sender(ctx, api)
 				if err != nil {
 					return err
 				}
-				bf, err := builderFor(ctx, m, sender, args[0])
+				bf, err := builderFor(ctx, m, sender, ref.Peer)
 				if err != nil {
 					return err
 				}
@@ -46,11 +46,11 @@ func (a *app) newEditCmd() *cobra.Command {
 				if useHTML {
 					opt = html.String(nil, text)
 				}
-				newID, err := unpack.MessageID(bf.Edit(id).StyledText(ctx, opt))
+				newID, err := unpack.MessageID(bf.Edit(ref.ID).StyledText(ctx, opt))
 				if err != nil {
 					return errors.Wrap(err, "edit")
 				}
-				return a.printer.Emit(sentResult{Peer: args[0], MessageID: newID})
+				return a.out(ctx).Emit(sentResult{Peer: ref.Peer, MessageID: newID})
 			})
 		},
 	}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/tgerr"
+
+	"github.com/gotd/cli/internal/output"
+)
+
+// errorClass is the stable, documented category of a failed command. Each
+// class maps to its own process exit code and to the "code" field of the JSON
+// error envelope, so agents can decide whether to retry, re-login or give up
+// without parsing messages.
+type errorClass string
+
+// Error classes. Keep the exit codes in sync with the README.
+const (
+	classError      errorClass = "error"             // anything unclassified
+	classUsage      errorClass = "usage"             // bad flags or arguments
+	classAuth       errorClass = "not_authorized"    // no session, or it was revoked
+	classPeer       errorClass = "peer_not_found"    // peer cannot be resolved
+	classFloodWait  errorClass = "flood_wait"        // rate limited; see retry_after
+	classPermission errorClass = "permission_denied" // missing rights in the chat
+	classTimeout    errorClass = "timeout"           // deadline exceeded
+	classCanceled   errorClass = "canceled"          // interrupted (Ctrl-C)
+)
+
+// ExitCode returns the process exit code of the class.
+func (c errorClass) ExitCode() int {
+	switch c {
+	case classUsage:
+		return 2
+	case classAuth:
+		return 3
+	case classPeer:
+		return 4
+	case classFloodWait:
+		return 5
+	case classPermission:
+		return 6
+	case classTimeout:
+		return 7
+	case classCanceled:
+		return 130
+	default:
+		return 1
+	}
+}
+
+// classifiedError tags err with a class without changing its message.
+type classifiedError struct {
+	class errorClass
+	err   error
+}
+
+func (e *classifiedError) Error() string { return e.err.Error() }
+func (e *classifiedError) Unwrap() error { return e.err }
+
+// withClass tags err with class c; classify reports c for it and anything
+// wrapping it.
+func withClass(c errorClass, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{class: c, err: err}
+}
+
+// RPC error types by class. Types not listed fall back to the RPC error code
+// (401, 403, 420) where that is unambiguous.
+//
+//nolint:gochecknoglobals // immutable lookup table
+var rpcClasses = map[string]errorClass{
+	"AUTH_KEY_UNREGISTERED": classAuth,
+	"AUTH_KEY_INVALID":      classAuth,
+	"AUTH_KEY_DUPLICATED":   classAuth,
+	"SESSION_REVOKED":       classAuth,
+	"SESSION_EXPIRED":       classAuth,
+	"USER_DEACTIVATED":      classAuth,
+	"USER_DEACTIVATED_BAN":  classAuth,
+
+	"PEER_ID_INVALID":       classPeer,
+	"USERNAME_NOT_OCCUPIED": classPeer,
+	"USERNAME_INVALID":      classPeer,
+	"CHANNEL_INVALID":       classPeer,
+	"CHAT_ID_INVALID":       classPeer,
+	"USER_ID_INVALID":       classPeer,
+	"PHONE_NOT_OCCUPIED":    classPeer,
+	"INVITE_HASH_INVALID":   classPeer,
+	"INVITE_HASH_EXPIRED":   classPeer,
+
+	"CHAT_ADMIN_REQUIRED":      classPermission,
+	"CHAT_WRITE_FORBIDDEN":     classPermission,
+	"CHAT_FORBIDDEN":           classPermission,
+	"CHANNEL_PRIVATE":          classPermission,
+	"RIGHT_FORBIDDEN":          classPermission,
+	"USER_PRIVACY_RESTRICTED":  classPermission,
+	"USER_NOT_MUTUAL_CONTACT":  classPermission,
+	"USER_BANNED_IN_CHANNEL":   classPermission,
+	"MESSAGE_DELETE_FORBIDDEN": classPermission,
+	"MESSAGE_AUTHOR_REQUIRED":  classPermission,
+
+	"FLOOD_WAIT":         classFloodWait,
+	"FLOOD_PREMIUM_WAIT": classFloodWait,
+	"SLOWMODE_WAIT":      classFloodWait,
+}
+
+// usagePrefixes are the starts of cobra/pflag messages for invalid command
+// lines that are not routed through the FlagErrorFunc.
+//
+//nolint:gochecknoglobals // immutable lookup table
+var usagePrefixes = []string{
+	"unknown command",
+	"required flag(s)",
+	"if any flags in the group",
+	"at least one of the flags in the group",
+	"accepts ",
+	"requires at least",
+	"invalid argument",
+}
+
+// classify returns the class of err.
+func classify(err error) errorClass {
+	var ce *classifiedError
+	if errors.As(err, &ce) {
+		return ce.class
+	}
+	var fo *fanOutError
+	if errors.As(err, &fo) {
+		return fo.class()
+	}
+	var fw *floodWaitError
+	switch {
+	case errors.As(err, &fw):
+		return classFloodWait
+	case errors.Is(err, errNotAuthorized):
+		return classAuth
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return classTimeout
+	case errors.Is(err, context.Canceled):
+		return classCanceled
+	}
+	if rpcErr, ok := tgerr.As(err); ok {
+		if c, ok := rpcClasses[rpcErr.Type]; ok {
+			return c
+		}
+		switch {
+		case strings.HasPrefix(rpcErr.Type, "CHAT_SEND_") && strings.HasSuffix(rpcErr.Type, "_FORBIDDEN"):
+			return classPermission
+		case rpcErr.Code == 401:
+			return classAuth
+		case rpcErr.Code == 403:
+			return classPermission
+		case rpcErr.Code == 420:
+			return classFloodWait
+		}
+		return classError
+	}
+	msg := err.Error()
+	for _, p := range usagePrefixes {
+		if strings.HasPrefix(msg, p) {
+			return classUsage
+		}
+	}
+	return classError
+}
+
+// errorInfo builds the JSON error envelope body for err.
+func errorInfo(err error) output.ErrorInfo {
+	info := output.ErrorInfo{Code: string(classify(err)), Message: err.Error()}
+	if rpcErr, ok := tgerr.As(err); ok {
+		info.RPCError = rpcErr.Type
+		if info.Code == string(classFloodWait) {
+			info.RetryAfter = rpcErr.Argument
+		}
+	}
+	var fw *floodWaitError
+	if errors.As(err, &fw) {
+		info.RetryAfter = int(fw.RetryAfter / time.Second)
+	} else if d, ok := tgerr.AsFloodWait(err); ok {
+		info.RetryAfter = int(d / time.Second)
+	}
+	return info
+}
+
+// reportError prints err to stderr (with the full chain and stack when debug
+// is set), writes the error envelope to stdout in JSON modes, and returns the
+// process exit code.
+func reportError(stdout, stderr io.Writer, format string, debug bool, err error) int {
+	if debug {
+		_, _ = fmt.Fprintf(stderr, "%+v\n", err)
+	} else {
+		_, _ = fmt.Fprintf(stderr, "tg: %v\n", err)
+	}
+	// A fan-out wrote each account's error with its results already.
+	var fo *fanOutError
+	if f, perr := output.ParseFormat(format); perr == nil && !errors.As(err, &fo) {
+		_ = output.New(f, stdout).EmitError(errorInfo(err))
+	}
+	return classify(err).ExitCode()
+}
+
+// requestedFormat returns the --output value for error reporting. Usage errors
+// can abort flag parsing before --output is seen, so when the flag was not
+// parsed the raw arguments are scanned for it.
+func requestedFormat(flag string, changed bool, args []string) string {
+	if changed {
+		return flag
+	}
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return flag
+		case arg == "-o" || arg == "--output":
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+		case strings.HasPrefix(arg, "--output="):
+			return strings.TrimPrefix(arg, "--output=")
+		case strings.HasPrefix(arg, "-o"):
+			return strings.TrimPrefix(strings.TrimPrefix(arg, "-o"), "=")
+		}
+	}
+	return flag
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/tgerr"
+)
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want errorClass
+		exit int
+	}{
+		{"plain", errors.New("boom"), classError, 1},
+		{"not authorized", errors.Wrap(errNotAuthorized, "whoami"), classAuth, 3},
+		{"revoked", tgerr.New(401, "SESSION_REVOKED"), classAuth, 3},
+		{"peer invalid", errors.Wrap(tgerr.New(400, "PEER_ID_INVALID"), "send"), classPeer, 4},
+		{"username", tgerr.New(400, "USERNAME_NOT_OCCUPIED"), classPeer, 4},
+		{"flood", tgerr.New(420, "FLOOD_WAIT_30"), classFloodWait, 5},
+		{"admin", tgerr.New(400, "CHAT_ADMIN_REQUIRED"), classPermission, 6},
+		{"send media", tgerr.New(403, "CHAT_SEND_MEDIA_FORBIDDEN"), classPermission, 6},
+		{"deadline", errors.Wrap(context.DeadlineExceeded, "wait"), classTimeout, 7},
+		{"canceled", context.Canceled, classCanceled, 130},
+		{"tagged", errors.Wrap(withClass(classPeer, errors.New("not cached")), "resolve"), classPeer, 4},
+		{"usage", errors.New(`unknown command "x" for "tg"`), classUsage, 2},
+		{"args", errors.New("accepts 1 arg(s), received 2"), classUsage, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := classify(tc.err)
+			if got != tc.want {
+				t.Errorf("classify = %q, want %q", got, tc.want)
+			}
+			if code := got.ExitCode(); code != tc.exit {
+				t.Errorf("exit code = %d, want %d", code, tc.exit)
+			}
+		})
+	}
+}
+
+func TestReportErrorJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := errors.Wrap(tgerr.New(420, "FLOOD_WAIT_30"), "send")
+	code := reportError(&stdout, &stderr, "jsonl", false, err)
+	if code != 5 {
+		t.Errorf("exit code = %d, want 5", code)
+	}
+	want := `{"schema":1,"error":{"code":"flood_wait","message":"send: rpc error code 420: FLOOD_WAIT (30)",` +
+		`"rpc_error":"FLOOD_WAIT","retry_after":30}}` + "\n"
+	if got := stdout.String(); got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(stderr.String(), "tg: send:") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+
+	stdout.Reset()
+	reportError(&stdout, &stderr, "text", false, err)
+	if stdout.Len() != 0 {
+		t.Errorf("text mode wrote %q to stdout", stdout.String())
+	}
+}
+
+func TestRequestedFormat(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"nosuch", "-o", "json"}, "json"},
+		{[]string{"--output=jsonl", "x"}, "jsonl"},
+		{[]string{"-ojson"}, "json"},
+		{[]string{"send", "--", "-o", "json"}, "text"},
+		{[]string{"send"}, "text"},
+	} {
+		if got := requestedFormat("text", false, tc.args); got != tc.want {
+			t.Errorf("requestedFormat(%q) = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
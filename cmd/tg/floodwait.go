@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/contrib/middleware/floodwait"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+
+	"github.com/gotd/cli/internal/lockedfile"
+)
+
+// defaultMaxFloodWait is the flood-wait budget when neither --max-flood-wait
+// nor the config sets one (the waiter's own default).
+const defaultMaxFloodWait = time.Minute
+
+// floodWaitError is returned when Telegram rate-limits a request for longer
+// than the account's flood-wait budget, or keeps rate-limiting it after the
+// waiter's retries. RetryAfter is the server's last requested wait.
+type floodWaitError struct {
+	Account    string
+	RetryAfter time.Duration
+	Budget     time.Duration
+	err        error
+}
+
+func (e *floodWaitError) Error() string {
+	if e.RetryAfter > e.Budget {
+		return fmt.Sprintf("account %q is rate limited: retry after %s (exceeds --max-flood-wait %s)",
+			e.Account, e.RetryAfter, e.Budget)
+	}
+	return fmt.Sprintf("account %q is rate limited: retry after %s (retries exhausted)", e.Account, e.RetryAfter)
+}
+
+func (e *floodWaitError) Unwrap() error { return e.err }
+
+// floodBudget returns the flood-wait budget for acc: --max-flood-wait when
+// given, else the account's (or top-level) max_flood_wait, else the default.
+func (a *app) floodBudget(acc Account) (time.Duration, error) {
+	budget := defaultMaxFloodWait
+	switch {
+	case a.maxFloodWaitSet:
+		budget = a.maxFloodWait
+	case acc.MaxFloodWait != 0:
+		budget = acc.MaxFloodWait
+	}
+	if budget < 0 {
+		return 0, errors.Errorf("negative flood-wait budget %s", budget)
+	}
+	return budget, nil
+}
+
+// floodTally collects the flood waits of one request, for floodWaitMiddleware
+// to count once the request's outcome is known.
+type floodTally struct {
+	mu    sync.Mutex
+	waits []time.Duration
+}
+
+// floodTallyKey is the context key of a request's floodTally.
+type floodTallyKey struct{}
+
+func (t *floodTally) add(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.waits = append(t.waits, d)
+}
+
+func (t *floodTally) list() []time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.waits)
+}
+
+// newFloodWaiter returns the flood-wait middleware for one account. Each
+// account gets its own waiter, so with --account all a throttled account
+// neither delays nor fails the others. Waits within budget are announced on
+// stderr; longer ones fail immediately. Every wait is noted in the request's
+// floodTally.
+func newFloodWaiter(label string, budget time.Duration) *floodwait.Waiter {
+	maxWait := budget
+	if maxWait == 0 {
+		// The waiter treats 0 as unlimited; a zero budget means never wait.
+		maxWait = time.Nanosecond
+	}
+	return floodwait.NewWaiter().
+		WithMaxWait(maxWait).
+		WithCallback(func(ctx context.Context, w floodwait.FloodWait) {
+			if t, ok := ctx.Value(floodTallyKey{}).(*floodTally); ok {
+				t.add(w.Duration)
+			}
+			if w.Duration > maxWait {
+				return
+			}
+			_, _ = fmt.Fprintf(os.Stderr, "tg: %s: rate limited by Telegram, retrying in %s\n", label, w.Duration)
+		})
+}
+
+// floodWaitMiddleware turns flood-wait errors the waiter gave up on into a
+// floodWaitError. It counts a request's flood waits in meter once the request
+// returns, each either sat out or given up on. It must run outside (before)
+// the waiter.
+func floodWaitMiddleware(label string, budget time.Duration, meter *floodMeter) telegram.Middleware {
+	return telegram.MiddlewareFunc(func(next tg.Invoker) telegram.InvokeFunc {
+		return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			tally := &floodTally{}
+			err := next.Invoke(context.WithValue(ctx, floodTallyKey{}, tally), input, output)
+			waits := tally.list()
+			d, gaveUp := tgerr.AsFloodWait(err)
+			if gaveUp && len(waits) > 0 {
+				// The last wait is the one given up on, not sat out.
+				waits = waits[:len(waits)-1]
+			}
+			for _, w := range waits {
+				meter.record(w, false)
+			}
+			if gaveUp {
+				meter.record(d, true)
+				return &floodWaitError{Account: label, RetryAfter: d, Budget: budget, err: err}
+			}
+			return err
+		}
+	})
+}
+
+// floodStats are an account's flood-wait counters. They add up across runs in
+// a file next to the config (see floodStatsPath) and show in tg accounts.
+type floodStats struct {
+	// Waits counts the flood waits sat out, and WaitedSeconds their total.
+	Waits         int `json:"waits"`
+	WaitedSeconds int `json:"waited_seconds"`
+	// GaveUp counts the requests that failed rate limited: over the budget
+	// or out of retries.
+	GaveUp int `json:"gave_up"`
+	// Last is when the account was last rate limited, and LastSeconds the
+	// wait Telegram asked for then.
+	Last        *time.Time `json:"last,omitempty"`
+	LastSeconds int        `json:"last_seconds,omitempty"`
+}
+
+// add adds the counters of a later run.
+func (s *floodStats) add(o floodStats) {
+	s.Waits += o.Waits
+	s.WaitedSeconds += o.WaitedSeconds
+	s.GaveUp += o.GaveUp
+	if o.Last != nil {
+		s.Last, s.LastSeconds = o.Last, o.LastSeconds
+	}
+}
+
+// floodMeter counts the flood waits of one account during a run. A nil meter
+// counts nothing.
+type floodMeter struct {
+	mu    sync.Mutex
+	stats floodStats
+}
+
+func (m *floodMeter) record(d time.Duration, gaveUp bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if gaveUp {
+		m.stats.GaveUp++
+	} else {
+		m.stats.Waits++
+		m.stats.WaitedSeconds += int(d.Round(time.Second) / time.Second)
+	}
+	now := time.Now().UTC()
+	m.stats.Last, m.stats.LastSeconds = &now, int(d/time.Second)
+}
+
+// save adds the counters recorded so far to the file at path and resets them.
+func (m *floodMeter) save(path string) error {
+	m.mu.Lock()
+	run := m.stats
+	m.stats = floodStats{}
+	m.mu.Unlock()
+	if run.Last == nil {
+		return nil
+	}
+
+	unlock, err := lockedfile.Lock(path)
+	if err != nil {
+		return errors.Wrap(err, "lock flood stats")
+	}
+	defer func() { _ = unlock() }()
+	stats, err := readFloodStats(path)
+	if err != nil {
+		return err
+	}
+	stats.add(run)
+	raw, err := json.Marshal(stats)
+	if err != nil {
+		return errors.Wrap(err, "encode flood stats")
+	}
+	return lockedfile.WriteFile(path, raw, 0o600)
+}
+
+// floodStatsPath returns the flood-wait counters file of an account label.
+func floodStatsPath(dir, label string) string {
+	return filepath.Join(dir, fmt.Sprintf("gotd.flood.%s.json", label))
+}
+
+// readFloodStats reads the counters at path; a missing file is all zero.
+func readFloodStats(path string) (floodStats, error) {
+	var stats floodStats
+	raw, err := os.ReadFile(path) // #nosec G304 // derived from the config path
+	if errors.Is(err, os.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, errors.Wrap(err, "read flood stats")
+	}
+	if err := json.Unmarshal(raw, &stats); err != nil {
+		return stats, errors.Wrapf(err, "parse %s", path)
+	}
+	return stats, nil
+}
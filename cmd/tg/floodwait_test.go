@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+func TestFloodBudget(t *testing.T) {
+	cfg := Config{
+		MaxFloodWait: 30 * time.Second,
+		Accounts: map[string]Account{
+			"work": {MaxFloodWait: 5 * time.Second},
+			"home": {},
+		},
+	}
+	for _, tc := range []struct {
+		label string
+		flag  time.Duration
+		set   bool
+		want  time.Duration
+	}{
+		{label: "default", want: 30 * time.Second},
+		{label: "work", want: 5 * time.Second},
+		{label: "home", want: 30 * time.Second},
+		{label: "work", flag: 0, set: true, want: 0},
+		{label: "home", flag: time.Hour, set: true, want: time.Hour},
+	} {
+		a := &app{cfg: cfg, maxFloodWait: tc.flag, maxFloodWaitSet: tc.set}
+		acc, err := cfg.account(tc.label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := a.floodBudget(acc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("floodBudget(%s, flag=%v set=%v) = %v, want %v", tc.label, tc.flag, tc.set, got, tc.want)
+		}
+	}
+
+	a := &app{}
+	if got, _ := a.floodBudget(Account{}); got != defaultMaxFloodWait {
+		t.Errorf("unset budget = %v, want %v", got, defaultMaxFloodWait)
+	}
+}
+
+// TestFloodWaitOverBudget checks that a FLOOD_WAIT above the budget fails
+// fast with a floodWaitError instead of sleeping.
+func TestFloodWaitOverBudget(t *testing.T) {
+	const budget = 10 * time.Second
+	meter := &floodMeter{}
+	waiter := newFloodWaiter("work", budget)
+	calls := 0
+	var next tg.Invoker = telegram.InvokeFunc(func(context.Context, bin.Encoder, bin.Decoder) error {
+		calls++
+		return tgerr.New(420, "FLOOD_WAIT_3600")
+	})
+	inv := floodWaitMiddleware("work", budget, meter).Handle(waiter.Handle(next))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var invokeErr error
+	if err := waiter.Run(ctx, func(ctx context.Context) error {
+		invokeErr = inv.Invoke(ctx, &tg.HelpGetConfigRequest{}, &tg.Config{})
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var fw *floodWaitError
+	if !errors.As(invokeErr, &fw) {
+		t.Fatalf("error = %v, want floodWaitError", invokeErr)
+	}
+	if fw.RetryAfter != time.Hour || fw.Account != "work" {
+		t.Errorf("floodWaitError = %+v", fw)
+	}
+	if calls != 1 {
+		t.Errorf("invoked %d times, want 1 (no retry over budget)", calls)
+	}
+	if c := classify(invokeErr); c != classFloodWait {
+		t.Errorf("classify = %q, want %q", c, classFloodWait)
+	}
+	if info := errorInfo(invokeErr); info.RetryAfter != 3600 || info.RPCError != "FLOOD_WAIT" {
+		t.Errorf("errorInfo = %+v", info)
+	}
+	if st := meter.stats; st.GaveUp != 1 || st.Waits != 0 || st.LastSeconds != 3600 {
+		t.Errorf("meter = %+v", st)
+	}
+}
+
+// TestFloodWaitRetriesExhausted checks that each flood wait is counted once:
+// the ones sat out as waits, the last one, given up on, only as such.
+func TestFloodWaitRetriesExhausted(t *testing.T) {
+	const budget = 10 * time.Second
+	for _, tc := range []struct {
+		name      string
+		floods    int // FLOOD_WAITs before the request goes through
+		waits     int
+		gaveUp    int
+		wantFlood bool
+	}{
+		{name: "through", floods: 1, waits: 1},
+		{name: "exhausted", floods: 10, waits: 1, gaveUp: 1, wantFlood: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel() // each waits out a real FLOOD_WAIT_1
+			meter := &floodMeter{}
+			waiter := newFloodWaiter("work", budget).WithMaxRetries(1)
+			calls := 0
+			var next tg.Invoker = telegram.InvokeFunc(func(context.Context, bin.Encoder, bin.Decoder) error {
+				calls++
+				if calls <= tc.floods {
+					return tgerr.New(420, "FLOOD_WAIT_1")
+				}
+				return nil
+			})
+			inv := floodWaitMiddleware("work", budget, meter).Handle(waiter.Handle(next))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			var invokeErr error
+			if err := waiter.Run(ctx, func(ctx context.Context) error {
+				invokeErr = inv.Invoke(ctx, &tg.HelpGetConfigRequest{}, &tg.Config{})
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			var fw *floodWaitError
+			if errors.As(invokeErr, &fw) != tc.wantFlood {
+				t.Fatalf("error = %v", invokeErr)
+			}
+			if st := meter.stats; st.Waits != tc.waits || st.GaveUp != tc.gaveUp {
+				t.Errorf("meter = %+v, want %d waits and %d given up", st, tc.waits, tc.gaveUp)
+			}
+		})
+	}
+}
+
+func TestFloodStatsSave(t *testing.T) {
+	path := floodStatsPath(t.TempDir(), "work")
+	meter := &floodMeter{}
+	if err := meter.save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("saved without flood waits: %v", err)
+	}
+
+	for range 2 {
+		meter.record(3*time.Second, false)
+		meter.record(time.Hour, true)
+		if err := meter.save(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st, err := readFloodStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Waits != 2 || st.WaitedSeconds != 6 || st.GaveUp != 2 || st.Last == nil || st.LastSeconds != 3600 {
+		t.Errorf("stats = %+v", st)
+	}
+}
@@ -2,14 +2,14 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"io"
 	"strconv"
 
 	"github.com/go-faster/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/output"
 )
 
 // folderItem is one dialog filter (folder).
@@ -24,14 +24,16 @@ type foldersResult struct {
 	Folders []folderItem `json:"folders"`
 }
 
-// MarshalText renders one folder per line.
-func (r foldersResult) MarshalText(w io.Writer) error {
-	for _, f := range r.Folders {
-		if _, err := fmt.Fprintf(w, "#%d %s\n", f.ID, f.Title); err != nil {
-			return err
-		}
-	}
-	return nil
+// Items implements output.ItemLister.
+func (r foldersResult) Items() []any { return output.Items(r.Folders) }
+
+// Table implements output.Tabler.
+func (r foldersResult) Table() output.Table {
+	return output.NewTable([]output.Column[folderItem]{
+		{Name: "id", Value: func(f folderItem) string { return strconv.Itoa(f.ID) }},
+		{Name: "title", Value: func(f folderItem) string { return f.Title }},
+		{Name: "chats", Value: func(f folderItem) string { return intCell(f.Chats) }},
+	}, r.Folders)
 }
 
 // getFilters fetches all dialog filters.
@@ -94,7 +96,7 @@ func (a *app) newFoldersListCmd() *cobra.Command {
 						Chats: len(df.IncludePeers),
 					})
 				}
-				return a.printer.Emit(out)
+				return a.out(ctx).Emit(out)
 			})
 		},
 	}
@@ -123,7 +125,7 @@ func (a *app) newFoldersCreateCmd() *cobra.Command {
 				if _, err := api.MessagesUpdateDialogFilter(ctx, req); err != nil {
 					return errors.Wrap(err, "messages.updateDialogFilter")
 				}
-				return a.printer.Emit(folderItem{ID: id, Title: args[0]})
+				return a.out(ctx).Emit(folderItem{ID: id, Title: args[0]})
 			})
 		},
 	}
@@ -142,7 +144,7 @@ func (a *app) modifyFolderChat(use, short string, add bool) *cobra.Command {
 				return errors.Wrap(err, "folder-id must be an integer")
 			}
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -165,7 +167,7 @@ func (a *app) modifyFolderChat(use, short string, add bool) *cobra.Command {
 				if _, err := api.MessagesUpdateDialogFilter(ctx, req); err != nil {
 					return errors.Wrap(err, "messages.updateDialogFilter")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -226,7 +228,7 @@ func (a *app) newFoldersDeleteCmd() *cobra.Command {
 				if _, err := api.MessagesUpdateDialogFilter(ctx, &tg.MessagesUpdateDialogFilterRequest{ID: id}); err != nil {
 					return errors.Wrap(err, "messages.updateDialogFilter")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -246,7 +248,7 @@ func (a *app) newFoldersReorderCmd() *cobra.Command {
 				if _, err := api.MessagesUpdateDialogFiltersOrder(ctx, order); err != nil {
 					return errors.Wrap(err, "messages.updateDialogFiltersOrder")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -5,12 +5,16 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/go-faster/errors"
 
 	"github.com/gotd/td/telegram/message/peer"
 	"github.com/gotd/td/telegram/peers"
 	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/output"
 )
 
 // Peer type names.
@@ -59,14 +63,36 @@ type peerListResult struct {
 	Peers []peerRef `json:"peers"`
 }
 
-// MarshalText renders one peer per line.
-func (r peerListResult) MarshalText(w io.Writer) error {
-	for _, p := range r.Peers {
-		if err := p.MarshalText(w); err != nil {
-			return err
-		}
+// Items implements output.ItemLister.
+func (r peerListResult) Items() []any { return output.Items(r.Peers) }
+
+// Table implements output.Tabler.
+func (r peerListResult) Table() output.Table { return output.NewTable(peerColumns(), r.Peers) }
+
+// peerColumns are the tabular columns of a peer list.
+func peerColumns() []output.Column[peerRef] {
+	return []output.Column[peerRef]{
+		{Name: "id", Value: func(p peerRef) string { return strconv.FormatInt(p.ID, 10) }},
+		{Name: "type", Value: func(p peerRef) string { return p.Type }},
+		{Name: "username", Value: func(p peerRef) string { return p.Username }},
+		{Name: "name", Width: 40, Value: func(p peerRef) string { return p.Name }},
+	}
+}
+
+// dateCell renders a unix timestamp for tabular output, empty when unset.
+func dateCell(unix int) string {
+	if unix == 0 {
+		return ""
 	}
-	return nil
+	return time.Unix(int64(unix), 0).Format(time.RFC3339)
+}
+
+// intCell renders an optional integer for tabular output, empty when zero.
+func intCell(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
 }
 
 // label renders a peer reference for text output, e.g. "@durov" or "Some Group".
@@ -81,6 +107,27 @@ func (p peerRef) label() string {
 	}
 }
 
+// templateFuncs are the tg-specific --template helpers, added to
+// output.TemplateFuncs.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// peer renders a peerRef (or *peerRef) label: {{peer .Peer}}.
+		"peer": func(v any) string {
+			switch p := v.(type) {
+			case peerRef:
+				return p.label()
+			case *peerRef:
+				if p == nil {
+					return ""
+				}
+				return p.label()
+			default:
+				return fmt.Sprint(v)
+			}
+		},
+	}
+}
+
 // describePeer resolves a peer to a peerRef using the entities from a query.
 func describePeer(p tg.PeerClass, ent peer.Entities) peerRef {
 	switch v := p.(type) {
@@ -155,8 +202,10 @@ func messagesFrom(res tg.MessagesMessagesClass) ([]tg.MessageClass, peer.Entitie
 
 // buildMessageItem maps a raw message to a messageItem using entities for names.
 func buildMessageItem(msg *tg.Message, ent peer.Entities) messageItem {
+	chat := describePeer(msg.PeerID, ent)
 	item := messageItem{
 		ID:   msg.ID,
+		Peer: &chat,
 		Date: msg.Date,
 		Out:  msg.Out,
 		Text: msg.Message,
@@ -16,24 +16,27 @@ func (a *app) newForwardCmd() *cobra.Command {
 	)
 
 	cmd := &cobra.Command{
-		Use:     "forward <to-peer> <message-id>...",
+		Use:     "forward <to-peer> <message-id|selector>...",
 		Aliases: []string{"fwd"},
 		Short:   "Forward messages to a peer",
 		GroupID: groupMessaging,
 		Long: `Forward one or more messages from a source chat (--from) to a target peer.
-Peers are me/self, @username, phone, or a t.me link.`,
+Peers are me/self, @username, phone, or a t.me link. Messages are given by id
+or by the selectors of "tg delete" (100-200, last:N, since:DATE, from:PEER, -
+for stdin), resolved against the source chat and forwarded oldest-first.`,
 		Example: `  tg forward @friend --from @channel 100 101 102
-  tg forward me --from @durov 12345`,
+  tg forward me --from @durov 12345
+  tg forward me --from @channel last:10`,
 		Args:              cobra.MinimumNArgs(2),
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ids, err := parseIDs(args[1:])
+			sel, err := parseSelection(args[1:], cmd.InOrStdin())
 			if err != nil {
 				return err
 			}
 
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -41,7 +44,7 @@ Peers are me/self, @username, phone, or a t.me link.`,
 				if err != nil {
 					return err
 				}
-				sender, _, err := a.sender(api)
+				sender, _, err := a.sender(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -50,14 +53,27 @@ Peers are me/self, @username, phone, or a t.me link.`,
 					return err
 				}
 
-				fwd := bf.ForwardIDs(fromPeer, ids[0], ids[1:]...)
-				if dropAuthor {
-					fwd = fwd.DropAuthor()
+				ids, err := resolveSelection(ctx, api, m, fromPeer, sel)
+				if err != nil {
+					return err
+				}
+				if len(ids) == 0 {
+					return withClass(classUsage, errors.New("no messages match"))
 				}
-				if _, err := fwd.Send(ctx); err != nil {
-					return errors.Wrap(err, "forward")
+				if !sel.literal() {
+					ids = ascending(ids)
+				}
+
+				for _, batch := range chunkIDs(ids, maxBatchIDs) {
+					fwd := bf.ForwardIDs(fromPeer, batch[0], batch[1:]...)
+					if dropAuthor {
+						fwd = fwd.DropAuthor()
+					}
+					if _, err := fwd.Send(ctx); err != nil {
+						return errors.Wrap(err, "forward")
+					}
 				}
-				return a.printer.Emit(sentResult{Peer: args[0], MessageID: ids[len(ids)-1]})
+				return a.out(ctx).Emit(sentResult{Peer: args[0], MessageID: ids[len(ids)-1]})
 			})
 		},
 	}
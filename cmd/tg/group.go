@@ -10,11 +10,15 @@ import (
 	"github.com/gotd/td/tg"
 )
 
-// resolvePeerArg resolves a single peer string (supporting the "id:" prefix) to
-// a typed peers.Peer.
+// resolvePeerArg resolves a single peer string (supporting aliases and the
+// "id:" and "name:" cache selectors) to a typed peers.Peer.
 func resolvePeerArg(ctx context.Context, m *peerManager, arg string) (peers.Peer, error) {
-	if isIDArg(arg) {
-		return m.resolveID(ctx, arg)
+	arg, err := m.peerSpec(arg)
+	if err != nil {
+		return nil, err
+	}
+	if isCachedArg(arg) {
+		return m.resolveCached(ctx, arg)
 	}
 	return m.Resolve(ctx, arg)
 }
@@ -59,7 +63,7 @@ func (a *app) newCreateGroupCmd() *cobra.Command {
   tg create-group "Just me"`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -72,9 +76,9 @@ func (a *app) newCreateGroupCmd() *cobra.Command {
 					return errors.Wrap(err, "messages.createChat")
 				}
 				if ref, ok := firstChatRef(res.Updates); ok {
-					return a.printer.Emit(ref)
+					return a.out(ctx).Emit(ref)
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -109,9 +113,9 @@ func (a *app) newCreateChannelCmd() *cobra.Command {
 					return errors.Wrap(err, "channels.createChannel")
 				}
 				if ref, ok := firstChatRef(res); ok {
-					return a.printer.Emit(ref)
+					return a.out(ctx).Emit(ref)
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -133,7 +137,7 @@ func (a *app) newInviteCmd() *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -167,7 +171,7 @@ func (a *app) newInviteCmd() *cobra.Command {
 				if err != nil {
 					return errors.Wrap(err, "invite")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -183,7 +187,7 @@ func (a *app) newLeaveCmd() *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -205,7 +209,7 @@ func (a *app) newLeaveCmd() *cobra.Command {
 				if err != nil {
 					return errors.Wrap(err, "leave")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/gotd/td/bin"
 	"github.com/gotd/td/tg"
 	"github.com/gotd/td/tgmock"
+
+	"github.com/gotd/cli/internal/peercache"
 )
 
 // newFuncAPI returns a tg.Client whose invoker dispatches each request through
@@ -14,3 +20,35 @@ import (
 func newFuncAPI(_ *testing.T, fn func(req bin.Encoder) (bin.Encoder, error)) *tg.Client {
 	return tg.NewClient(tgmock.Invoker(fn))
 }
+
+// newTestConfig writes a minimal config to a temp dir and opens the default
+// account's peer cache next to it, for commands that work offline.
+func newTestConfig(t *testing.T) (string, *peercache.Storage) {
+	t.Helper()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "gotd.cli.yaml")
+	raw := fmt.Sprintf("version: %d\naccounts:\n  default:\n    app_id: 1\n    app_hash: x\n", configVersion)
+	if err := os.WriteFile(configPath, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := peercache.Open(cfg.defaultAcc().peerCachePath(dir, defaultAccount, authUser.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return configPath, store
+}
+
+// runRoot runs the tg command tree with args and returns its stdout.
+func runRoot(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	root := newRootCmd()
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetArgs(args)
+	err := root.Execute()
+	return out.String(), err
+}
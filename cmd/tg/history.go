@@ -2,19 +2,23 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"io"
+	"strconv"
 
 	"github.com/go-faster/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/gotd/td/telegram/query"
 	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/output"
 )
 
 // messageItem describes one message.
 type messageItem struct {
-	ID      int      `json:"id"`
+	ID int `json:"id"`
+	// Peer is the chat the message is in, so that piped JSONL lines can be
+	// checked against the chat they are applied to.
+	Peer    *peerRef `json:"peer,omitempty"`
 	Date    int      `json:"date"`
 	Out     bool     `json:"out"`
 	From    *peerRef `json:"from,omitempty"`
@@ -29,36 +33,60 @@ type historyResult struct {
 	Messages []messageItem `json:"messages"`
 }
 
-// MarshalText renders messages oldest-first, one per line.
-func (h historyResult) MarshalText(w io.Writer) error {
-	for _, m := range h.Messages {
-		dir := "<"
-		if m.Out {
-			dir = ">"
-		}
-		line := fmt.Sprintf("#%d %s", m.ID, dir)
-		if m.From != nil {
-			line += " " + m.From.label()
-		}
-		if m.Text != "" {
-			line += " " + m.Text
-		}
-		if m.Media != "" {
-			line += " [" + m.Media + "]"
-		}
-		if m.ReplyTo != 0 {
-			line += fmt.Sprintf(" (reply to #%d)", m.ReplyTo)
-		}
-		if _, err := fmt.Fprintln(w, line); err != nil {
-			return err
-		}
+// Items implements output.ItemLister.
+func (h historyResult) Items() []any { return output.Items(h.Messages) }
+
+// Table implements output.Tabler.
+func (h historyResult) Table() output.Table { return output.NewTable(messageColumns(), h.Messages) }
+
+// messageColumns are the tabular columns of a message list.
+func messageColumns() []output.Column[messageItem] {
+	return []output.Column[messageItem]{
+		{Name: "id", Value: func(m messageItem) string { return strconv.Itoa(m.ID) }},
+		{Name: "date", Value: func(m messageItem) string { return dateCell(m.Date) }},
+		{Name: "dir", Value: func(m messageItem) string {
+			if m.Out {
+				return "out"
+			}
+			return "in"
+		}},
+		{Name: "from", Width: 24, Value: func(m messageItem) string {
+			if m.From == nil {
+				return ""
+			}
+			return m.From.label()
+		}},
+		{Name: "text", Width: 80, Value: func(m messageItem) string { return m.Text }},
+		{Name: "media", Value: func(m messageItem) string { return m.Media }},
+		{Name: "reply_to", Value: func(m messageItem) string { return intCell(m.ReplyTo) }},
 	}
-	return nil
 }
 
 // listHistory reads up to limit recent messages from peer (newest-first from the
 // API), returning them oldest-first.
 func listHistory(ctx context.Context, api *tg.Client, peer tg.InputPeerClass, limit int) (historyResult, error) {
+	var res historyResult
+	if err := streamHistory(ctx, api, peer, limit, &res.Peer, func(m messageItem) error {
+		res.Messages = append(res.Messages, m)
+		return nil
+	}); err != nil {
+		return historyResult{}, err
+	}
+	reverseMessages(res.Messages)
+	return res, nil
+}
+
+// streamHistory is listHistory, handing each message to each in API order
+// (newest-first) as soon as its page is fetched. The peer description is
+// stored into ref once the first message arrives.
+func streamHistory(
+	ctx context.Context,
+	api *tg.Client,
+	peer tg.InputPeerClass,
+	limit int,
+	ref *peerRef,
+	each func(messageItem) error,
+) error {
 	// Fetch in large batches to minimize round trips. The iterator's default
 	// batch size is 1, so it issues one messages.getHistory RPC per message,
 	// which makes larger --limit values extremely slow. Telegram does not
@@ -68,9 +96,9 @@ func listHistory(ctx context.Context, api *tg.Client, peer tg.InputPeerClass, li
 	batch := max(1, min(limit, 100))
 	iter := query.Messages(api).GetHistory(peer).BatchSize(batch).Iter()
 
-	var res historyResult
+	n := 0
 	for iter.Next(ctx) {
-		if len(res.Messages) >= limit {
+		if n >= limit {
 			break
 		}
 		elem := iter.Value()
@@ -78,20 +106,26 @@ func listHistory(ctx context.Context, api *tg.Client, peer tg.InputPeerClass, li
 		if !ok {
 			continue
 		}
-		if res.Peer.Type == "" {
-			res.Peer = describePeer(msg.PeerID, elem.Entities)
+		if ref.Type == "" {
+			*ref = describePeer(msg.PeerID, elem.Entities)
+		}
+		if err := each(buildMessageItem(msg, elem.Entities)); err != nil {
+			return err
 		}
-		res.Messages = append(res.Messages, buildMessageItem(msg, elem.Entities))
+		n++
 	}
 	if err := iter.Err(); err != nil {
-		return historyResult{}, errors.Wrap(err, "iterate history")
+		return errors.Wrap(err, "iterate history")
 	}
+	return nil
+}
 
-	// API returns newest-first; present chronologically.
-	for i, j := 0, len(res.Messages)-1; i < j; i, j = i+1, j-1 {
-		res.Messages[i], res.Messages[j] = res.Messages[j], res.Messages[i]
+// reverseMessages reverses msgs in place (API order is newest-first; results
+// are presented chronologically).
+func reverseMessages(msgs []messageItem) {
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
 	}
-	return res, nil
 }
 
 func (a *app) newHistoryCmd() *cobra.Command {
@@ -103,15 +137,19 @@ func (a *app) newHistoryCmd() *cobra.Command {
 		Short:   "Read recent messages from a peer",
 		GroupID: groupMessaging,
 		Long: `Read recent messages from a peer (newest-first from the API, printed
-oldest-first). The peer is me/self, @username, phone, or a t.me link.`,
+oldest-first). The peer is me/self, @username, phone, or a t.me link.
+
+With --output jsonl each message is written as soon as it is fetched, so the
+stream is newest-first.`,
 		Example: `  tg history me
   tg history @durov --limit 20
-  tg history @somechannel --output json`,
+  tg history @somechannel --output json
+  tg history @somechannel --limit 5000 --output jsonl`,
 		Args:              cobra.ExactArgs(1),
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -119,11 +157,13 @@ oldest-first). The peer is me/self, @username, phone, or a t.me link.`,
 				if err != nil {
 					return err
 				}
-				res, err := listHistory(ctx, api, peer, limit)
-				if err != nil {
+				var res historyResult
+				a.out(ctx).Begin()
+				if err := streamHistory(ctx, api, peer, limit, &res.Peer, output.Collect(a.out(ctx), &res.Messages)); err != nil {
 					return err
 				}
-				return a.printer.Emit(res)
+				reverseMessages(res.Messages)
+				return a.out(ctx).End(res)
 			})
 		},
 	}
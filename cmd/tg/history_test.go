@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"testing"
 
@@ -8,6 +9,8 @@ import (
 
 	"github.com/gotd/td/bin"
 	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/output"
 )
 
 func TestListHistory(t *testing.T) {
@@ -102,3 +105,57 @@ func TestListHistoryBatchSize(t *testing.T) {
 		}
 	}
 }
+
+// TestStreamHistory asserts messages are handed out in API order (newest-first)
+// as they are fetched, with the peer filled in from the first message.
+func TestStreamHistory(t *testing.T) {
+	api := newFuncAPI(t, func(req bin.Encoder) (bin.Encoder, error) {
+		if _, ok := req.(*tg.MessagesGetHistoryRequest); ok {
+			return &tg.MessagesMessages{
+				Messages: []tg.MessageClass{
+					&tg.Message{ID: 2, PeerID: &tg.PeerUser{UserID: 5}, Date: 2},
+					&tg.Message{ID: 1, PeerID: &tg.PeerUser{UserID: 5}, Date: 1},
+				},
+				Users: []tg.UserClass{&tg.User{ID: 5, Username: "alice"}},
+			}, nil
+		}
+		return nil, errors.Errorf("unexpected request %T", req)
+	})
+
+	var (
+		ref peerRef
+		ids []int
+	)
+	if err := streamHistory(context.Background(), api, &tg.InputPeerSelf{}, 30, &ref, func(m messageItem) error {
+		ids = append(ids, m.ID)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 2 || ids[1] != 1 {
+		t.Errorf("ids = %v, want [2 1]", ids)
+	}
+	if ref.Username != "alice" {
+		t.Errorf("peer = %+v", ref)
+	}
+}
+
+func TestHistoryTemplate(t *testing.T) {
+	tmpl, err := output.ParseTemplate(`{{range .Messages}}#{{.ID}} {{peer .From}}: {{.Text}}{{"\n"}}{{end}}`, templateFuncs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := historyResult{Messages: []messageItem{
+		{ID: 1, From: &peerRef{ID: 5, Type: peerUser, Username: "alice"}, Text: "hi"},
+		{ID: 2, Text: "anon"},
+	}}
+	var buf bytes.Buffer
+	p := output.New(output.Text, &buf)
+	p.SetTemplate(tmpl)
+	if err := p.Emit(res); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "#1 @alice: hi\n#2 : anon\n"; got != want {
+		t.Errorf("template = %q, want %q", got, want)
+	}
+}
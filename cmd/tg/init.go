@@ -50,12 +50,16 @@ A bot token is optional — most commands use a personal user session created wi
 			}
 
 			cfg := Config{
-				AppID:    appID,
-				AppHash:  appHash,
-				BotToken: token,
-				Proxy:    proxy,
-				Test:     test,
+				Version: configVersion,
+				Accounts: map[string]Account{defaultAccount: {
+					AppID:    appID,
+					AppHash:  appHash,
+					BotToken: token,
+					Proxy:    proxy,
+					Test:     test,
+				}},
 			}
+			cfg.pinSessionID(defaultAccount)
 			if err := writeConfig(a.configPath, cfg); err != nil {
 				return err
 			}
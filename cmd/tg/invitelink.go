@@ -82,7 +82,7 @@ If the chat has no invite link yet, use ` + "`tg invite-link new`" + ` to create
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -95,9 +95,9 @@ If the chat has no invite link yet, use ` + "`tg invite-link new`" + ` to create
 					return err
 				}
 				if link == "" {
-					return a.printer.Emit(noLinkResult{})
+					return a.out(ctx).Emit(noLinkResult{})
 				}
-				return a.printer.Emit(linkResult{Link: link})
+				return a.out(ctx).Emit(linkResult{Link: link})
 			})
 		},
 	}
@@ -121,7 +121,7 @@ Each call creates an additional link; the chat's primary link is unchanged.`,
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -144,7 +144,7 @@ Each call creates an additional link; the chat's primary link is unchanged.`,
 				if !ok {
 					return errors.Errorf("unexpected invite type %T", res)
 				}
-				return a.printer.Emit(linkResult{Link: inv.Link})
+				return a.out(ctx).Emit(linkResult{Link: inv.Link})
 			})
 		},
 	}
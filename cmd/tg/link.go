@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"strconv"
 
 	"github.com/go-faster/errors"
 	"github.com/spf13/cobra"
@@ -31,20 +30,21 @@ func (a *app) newLinkCmd() *cobra.Command {
 		GroupID: groupMessaging,
 		Long:    "Export a t.me link to a message. Only works for channels and supergroups.",
 		Example: `  tg link @durov 12345
-  tg link @somechannel 42 --output json`,
-		Args:              cobra.ExactArgs(2),
+  tg link @somechannel 42 --output json
+  tg link https://t.me/c/1234567890/42`,
+		Args:              messageRefArgs(0),
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			id, err := strconv.Atoi(args[1])
+			ref, _, err := splitMessageRef(args)
 			if err != nil {
-				return errors.Wrap(err, "message-id must be an integer")
+				return err
 			}
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
-				peer, err := resolvePeer(ctx, m, args[0])
+				peer, err := resolvePeer(ctx, m, ref.Peer)
 				if err != nil {
 					return err
 				}
@@ -54,12 +54,12 @@ func (a *app) newLinkCmd() *cobra.Command {
 				}
 				res, err := api.ChannelsExportMessageLink(ctx, &tg.ChannelsExportMessageLinkRequest{
 					Channel: &tg.InputChannel{ChannelID: ch.ChannelID, AccessHash: ch.AccessHash},
-					ID:      id,
+					ID:      ref.ID,
 				})
 				if err != nil {
 					return errors.Wrap(err, "channels.exportMessageLink")
 				}
-				return a.printer.Emit(linkResult{Link: res.Link, HTML: res.HTML})
+				return a.out(ctx).Emit(linkResult{Link: res.Link, HTML: res.HTML})
 			})
 		},
 	}
@@ -142,6 +142,7 @@ func (a *app) ensureAccount() error {
 		a.cfg.Accounts = map[string]Account{}
 	}
 	a.cfg.Accounts[label] = Account{}
+	a.cfg.pinSessionID(label)
 	if err := saveConfig(a.configPath, a.cfg); err != nil {
 		return err
 	}
@@ -153,6 +154,9 @@ func (a *app) newLoginCmd() *cobra.Command {
 	var (
 		phone    string
 		password string
+		tdataDir string
+		passcode string
+		tdataAcc string
 	)
 
 	cmd := &cobra.Command{
@@ -170,6 +174,11 @@ credentials), so you don't need "tg accounts add" first; use that only to pre-se
 custom credentials, a bot token, or a per-account proxy. Run "tg init" once before
 logging in.
 
+--from-tdata imports the session of an existing Telegram Desktop profile (its
+tdata directory) with no interaction. The session is shared with Desktop:
+logging out of either ends both. Profiles with several accounts need
+--tdata-account (a position or a user id) unless a terminal can ask.
+
 The QR code and all prompts are written to stderr; the resulting account is
 printed to stdout (honoring --output).`,
 		Example: `  # QR login (default)
@@ -182,7 +191,10 @@ printed to stdout (honoring --output).`,
   tg login --phone +123456789
 
   # Non-interactive 2FA password
-  TG_PASSWORD=secret tg login`,
+  TG_PASSWORD=secret tg login
+
+  # Reuse a Telegram Desktop login
+  tg login --from-tdata ~/.local/share/TelegramDesktop/tdata --tdata-account 2`,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			if err := a.ensureAccount(); err != nil {
@@ -191,6 +203,15 @@ printed to stdout (honoring --output).`,
 			if password == "" {
 				password = os.Getenv("TG_PASSWORD")
 			}
+			if tdataDir != "" {
+				if !cmd.Flags().Changed("passcode") {
+					passcode = os.Getenv(envTDataPasscode)
+				}
+				return a.runLoginTData(cmd.Context(), tdataDir, passcode, tdataAcc)
+			}
+			if cmd.Flags().Changed("passcode") || tdataAcc != "" {
+				return withClass(classUsage, errors.New("--passcode and --tdata-account need --from-tdata"))
+			}
 			usePhone := cmd.Flags().Changed("phone")
 			phone = strings.TrimSpace(phone) // bare --phone (NoOptDefVal) trims to empty → prompt
 			// QR login requires updates (the login-token signal); phone does not.
@@ -219,7 +240,7 @@ printed to stdout (honoring --output).`,
 				if err != nil {
 					return err
 				}
-				return a.printer.Emit(newWhoamiResult(user))
+				return a.out(ctx).Emit(newWhoamiResult(user))
 			})
 		},
 	}
@@ -227,6 +248,37 @@ printed to stdout (honoring --output).`,
 	fs := cmd.Flags()
 	fs.StringVar(&phone, "phone", "", "phone-code login with this number (international format; use --phone= to be prompted)")
 	fs.StringVar(&password, "password", "", "2FA cloud password (or set TG_PASSWORD)")
+	fs.StringVar(&tdataDir, "from-tdata", "", "import the session of a Telegram Desktop tdata directory")
+	fs.StringVar(&passcode, "passcode", "", "Telegram Desktop local passcode (or set "+envTDataPasscode+")")
+	fs.StringVar(&tdataAcc, "tdata-account", "", "Telegram Desktop account to import: position (1, 2, …) or user id")
+	_ = cmd.MarkFlagDirname("from-tdata")
+	cmd.MarkFlagsMutuallyExclusive("from-tdata", "phone")
 
 	return cmd
 }
+
+// runLoginTData imports a Telegram Desktop session, then checks it is still
+// authorized and prints the account. A revoked session is removed again.
+func (a *app) runLoginTData(ctx context.Context, dir, passcode, sel string) error {
+	var code []byte
+	if passcode != "" {
+		code = []byte(passcode)
+	}
+	if err := a.loginTData(ctx, dir, code, sel); err != nil {
+		return err
+	}
+	return a.connect(ctx, runParams{auth: authUser}, func(ctx context.Context, client *telegram.Client, _ tg.UpdateDispatcher) error {
+		status, err := client.Auth().Status(ctx)
+		if err != nil {
+			return errors.Wrap(err, "auth status")
+		}
+		if !status.Authorized {
+			if err := a.sessionStore(a.active.label, a.active.acc, kindUser).Delete(ctx); err != nil {
+				return errors.Wrap(err, "remove session")
+			}
+			return withClass(classAuth, errors.New("the Telegram Desktop session is no longer authorized"))
+		}
+		fmt.Fprintln(os.Stderr, "Imported Telegram Desktop session.")
+		return a.out(ctx).Emit(newWhoamiResult(status.User))
+	})
+}
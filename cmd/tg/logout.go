@@ -11,6 +11,8 @@ import (
 
 	"github.com/gotd/td/telegram"
 	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/lockedfile"
 )
 
 func (a *app) newLogoutCmd() *cobra.Command {
@@ -61,7 +63,7 @@ files are removed even if it fails (e.g. the session is already invalid).`,
 			if rmErr := store.Delete(cmd.Context()); rmErr != nil {
 				return errors.Wrap(rmErr, "remove session")
 			}
-			if rmErr := os.Remove(cachePath); rmErr != nil && !os.IsNotExist(rmErr) {
+			if rmErr := lockedfile.Remove(cachePath); rmErr != nil {
 				return errors.Wrapf(rmErr, "remove %s", cachePath)
 			}
 			return a.printer.Emit(okResult{OK: true})
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"os"
 	"os/signal"
 )
@@ -11,14 +10,15 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
-	if err := newRootCmd().ExecuteContext(ctx); err != nil {
+	root := newRootCmd()
+	if err := root.ExecuteContext(ctx); err != nil {
 		// Concise message by default; full chain + stack with TG_DEBUG for
-		// troubleshooting. All diagnostics go to stderr.
-		if os.Getenv("TG_DEBUG") != "" {
-			fmt.Fprintf(os.Stderr, "%+v\n", err)
-		} else {
-			fmt.Fprintf(os.Stderr, "tg: %v\n", err)
-		}
-		os.Exit(1)
+		// troubleshooting. Diagnostics go to stderr; in JSON modes an error
+		// envelope also goes to stdout. The exit code reflects the error class.
+		f := root.PersistentFlags().Lookup("output")
+		format := requestedFormat(f.Value.String(), f.Changed, os.Args[1:])
+		code := reportError(os.Stdout, os.Stderr, format, os.Getenv("TG_DEBUG") != "", err)
+		cancel()
+		os.Exit(code)
 	}
 }
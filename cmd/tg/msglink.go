@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/gotd/td/constant"
+)
+
+// messageRef addresses one message: a peer argument and a message id, given
+// either as two arguments or as one t.me message link.
+type messageRef struct {
+	Peer string
+	ID   int
+}
+
+// parseMessageLink parses a t.me message link:
+//
+//	https://t.me/c/1234567890/42     private chat by channel id
+//	https://t.me/c/1234567890/7/42   the same, in forum topic 7
+//	https://t.me/durov/42            public chat by username
+//	https://t.me/durov/7/42          the same, in forum topic 7
+//
+// The peer of a private link is returned as a marked id ("-1001234567890"),
+// of a public one as "@username". Links without a message id are not message
+// links.
+func parseMessageLink(s string) (messageRef, bool) {
+	s = strings.TrimSpace(s)
+	for _, scheme := range []string{"https://", "http://"} {
+		s = strings.TrimPrefix(s, scheme)
+	}
+	s = strings.TrimPrefix(s, "www.")
+	host, path, ok := strings.Cut(s, "/")
+	if !ok || (host != "t.me" && host != "telegram.me") {
+		return messageRef{}, false
+	}
+	path, _, _ = strings.Cut(path, "?")
+	path, _, _ = strings.Cut(path, "#")
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+
+	private := parts[0] == "c"
+	if private {
+		parts = parts[1:]
+	}
+	if len(parts) < 2 || len(parts) > 3 {
+		return messageRef{}, false
+	}
+	msgID, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil || msgID <= 0 {
+		return messageRef{}, false
+	}
+	if len(parts) == 3 {
+		if _, err := strconv.Atoi(parts[1]); err != nil {
+			return messageRef{}, false
+		}
+	}
+
+	if private {
+		id, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || id <= 0 {
+			return messageRef{}, false
+		}
+		var marked constant.TDLibPeerID
+		marked.Channel(id)
+		return messageRef{Peer: strconv.FormatInt(int64(marked), 10), ID: msgID}, true
+	}
+	if !isUsername(parts[0]) {
+		return messageRef{}, false
+	}
+	return messageRef{Peer: "@" + parts[0], ID: msgID}, true
+}
+
+// isUsername reports whether s is made of username characters.
+func isUsername(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// messageRefArgs validates the arguments of a "<peer> <message-id> ..." command
+// followed by extra arguments, where the first two may be one message link.
+func messageRefArgs(extra int) cobra.PositionalArgs {
+	return func(_ *cobra.Command, args []string) error {
+		want := 2 + extra
+		if len(args) > 0 {
+			if _, ok := parseMessageLink(args[0]); ok {
+				want = 1 + extra
+			}
+		}
+		if len(args) != want {
+			return errors.Errorf("accepts %d arg(s), received %d", want, len(args))
+		}
+		return nil
+	}
+}
+
+// splitMessageRef splits args validated by messageRefArgs into the message and
+// the remaining arguments.
+func splitMessageRef(args []string) (messageRef, []string, error) {
+	if ref, ok := parseMessageLink(args[0]); ok {
+		return ref, args[1:], nil
+	}
+	id, err := strconv.Atoi(args[1])
+	if err != nil {
+		return messageRef{}, nil, errors.Wrap(err, "message-id must be an integer")
+	}
+	return messageRef{Peer: args[0], ID: id}, args[2:], nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gotd/td/telegram/peers"
+	"github.com/gotd/td/tg"
+)
+
+func TestParseMessageLink(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want messageRef
+		ok   bool
+	}{
+		{"https://t.me/c/1234567890/42", messageRef{Peer: "-1001234567890", ID: 42}, true},
+		{"t.me/c/1234567890/7/42", messageRef{Peer: "-1001234567890", ID: 42}, true},
+		{"https://t.me/durov/42", messageRef{Peer: "@durov", ID: 42}, true},
+		{"http://telegram.me/durov/7/42?single", messageRef{Peer: "@durov", ID: 42}, true},
+		{"https://t.me/durov", messageRef{}, false},
+		{"https://t.me/+AbCdEf/1", messageRef{}, false},
+		{"https://t.me/c/abc/42", messageRef{}, false},
+		{"https://t.me/durov/topic/42", messageRef{}, false},
+		{"https://example.com/durov/42", messageRef{}, false},
+		{"@durov", messageRef{}, false},
+	} {
+		got, ok := parseMessageLink(c.in)
+		if ok != c.ok || got != c.want {
+			t.Errorf("parseMessageLink(%q) = %+v, %v; want %+v, %v", c.in, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestSplitMessageRef(t *testing.T) {
+	for _, c := range []struct {
+		args  []string
+		extra int
+		want  messageRef
+		rest  int
+	}{
+		{[]string{"@durov", "12"}, 0, messageRef{Peer: "@durov", ID: 12}, 0},
+		{[]string{"https://t.me/durov/12"}, 0, messageRef{Peer: "@durov", ID: 12}, 0},
+		{[]string{"https://t.me/durov/12", "hi"}, 1, messageRef{Peer: "@durov", ID: 12}, 1},
+		{[]string{"me", "5", "hi"}, 1, messageRef{Peer: "me", ID: 5}, 1},
+	} {
+		if err := messageRefArgs(c.extra)(nil, c.args); err != nil {
+			t.Errorf("messageRefArgs(%d)(%q) = %v", c.extra, c.args, err)
+			continue
+		}
+		got, rest, err := splitMessageRef(c.args)
+		if err != nil || got != c.want || len(rest) != c.rest {
+			t.Errorf("splitMessageRef(%q) = %+v, %q, %v", c.args, got, rest, err)
+		}
+	}
+
+	if err := messageRefArgs(0)(nil, []string{"@durov"}); classify(err) != classUsage {
+		t.Errorf("missing message id: %v", err)
+	}
+	if err := messageRefArgs(0)(nil, []string{"https://t.me/durov/1", "2"}); classify(err) != classUsage {
+		t.Errorf("link plus message id: %v", err)
+	}
+	if _, _, err := splitMessageRef([]string{"@durov", "x"}); err == nil {
+		t.Error("non-numeric message id accepted")
+	}
+}
+
+func TestResolveMarkedID(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(t)
+
+	// An uncached channel is a peer_not_found error naming the plain id.
+	if _, err := resolvePeer(ctx, m, "-1001234567890"); classify(err) != classPeer {
+		t.Fatalf("uncached marked channel: %v", err)
+	}
+
+	if err := m.store.Save(ctx, peers.Key{Prefix: "channel_", ID: 1234567890}, peers.Value{AccessHash: 77}); err != nil {
+		t.Fatal(err)
+	}
+	api, mock := newTestAPI(t)
+	mock.Expect().ThenResult(&tg.MessagesChats{Chats: []tg.ChatClass{
+		&tg.Channel{ID: 1234567890, AccessHash: 77, Title: "News", Photo: &tg.ChatPhotoEmpty{}},
+	}})
+	m.Manager = peers.Options{Storage: m.store}.Build(api)
+
+	// A private message link resolves to its channel.
+	p, err := resolvePeer(ctx, m, "https://t.me/c/1234567890/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch, ok := p.(*tg.InputPeerChannel); !ok || ch.ChannelID != 1234567890 || ch.AccessHash != 77 {
+		t.Fatalf("resolvePeer(link) = %+v", p)
+	}
+
+	if _, err := resolvePeer(ctx, m, "-99999999999999999"); err == nil {
+		t.Fatal("out-of-range marked id accepted")
+	}
+}
@@ -0,0 +1,507 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/gotd/td/constant"
+	"github.com/gotd/td/telegram/query"
+	"github.com/gotd/td/tg"
+)
+
+// Message selectors accepted wherever a command takes message ids:
+//
+//	12345             one message
+//	100-200           every existing message with an id in the range
+//	last:20           the 20 newest messages
+//	since:2026-01-01  messages sent since a date (local time), an RFC3339
+//	                  time, or a duration ago ("36h", "7d")
+//	from:@user        messages sent by a peer ("from:me" for your own)
+//	-                 ids read from stdin: one per line, or JSONL such as
+//	                  `tg search -o jsonl` output
+//
+// Ids, ranges, last: and stdin add messages; since: and from: narrow them (or
+// the whole history when given alone).
+const (
+	selectLast  = "last:"
+	selectSince = "since:"
+	selectFrom  = "from:"
+	selectStdin = "-"
+)
+
+// idRange is an inclusive message id range.
+type idRange struct{ lo, hi int }
+
+// msgSelection is a parsed list of message selectors.
+type msgSelection struct {
+	ids    []int
+	ranges []idRange
+	last   int
+	since  time.Time
+	from   []string
+	// chats are the chats named by JSON lines on stdin.
+	chats []stdinChat
+}
+
+// stdinChat is the chat a JSON line on stdin says its message is in.
+type stdinChat struct {
+	line int
+	peer peerRef
+}
+
+// parseSelection parses message selectors; stdin is read for "-".
+func parseSelection(args []string, stdin io.Reader) (msgSelection, error) {
+	var (
+		s         msgSelection
+		readStdin bool
+	)
+	for _, arg := range args {
+		arg = strings.TrimSpace(arg)
+		switch {
+		case arg == selectStdin:
+			if readStdin {
+				return msgSelection{}, withClass(classUsage, errors.New(`"-" may be given only once`))
+			}
+			readStdin = true
+			ids, chats, err := readSelectionIDs(stdin)
+			if err != nil {
+				return msgSelection{}, err
+			}
+			s.ids = append(s.ids, ids...)
+			s.chats = chats
+		case strings.HasPrefix(arg, selectLast):
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, selectLast))
+			if err != nil || n <= 0 {
+				return msgSelection{}, withClass(classUsage, errors.Errorf("invalid selector %q: want last:<count>", arg))
+			}
+			s.last = max(s.last, n)
+		case strings.HasPrefix(arg, selectSince):
+			t, err := parseSince(strings.TrimPrefix(arg, selectSince), time.Now())
+			if err != nil {
+				return msgSelection{}, withClass(classUsage, errors.Wrapf(err, "invalid selector %q", arg))
+			}
+			if t.After(s.since) {
+				s.since = t
+			}
+		case strings.HasPrefix(arg, selectFrom):
+			peer := strings.TrimSpace(strings.TrimPrefix(arg, selectFrom))
+			if peer == "" {
+				return msgSelection{}, withClass(classUsage, errors.Errorf("invalid selector %q: want from:<peer>", arg))
+			}
+			s.from = append(s.from, peer)
+		default:
+			if lo, hi, ok := strings.Cut(arg, "-"); ok && lo != "" {
+				r, err := parseRange(lo, hi)
+				if err != nil {
+					return msgSelection{}, withClass(classUsage, errors.Wrapf(err, "invalid range %q", arg))
+				}
+				s.ranges = append(s.ranges, r)
+				continue
+			}
+			id, err := parseMessageID(arg)
+			if err != nil {
+				return msgSelection{}, withClass(classUsage, err)
+			}
+			s.ids = append(s.ids, id)
+		}
+	}
+	if s.empty() {
+		return msgSelection{}, withClass(classUsage, errors.New("no message ids selected"))
+	}
+	return s, nil
+}
+
+// parseMessageID parses one positive message id.
+func parseMessageID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid message id %q", s)
+	}
+	if id <= 0 {
+		return 0, errors.Errorf("invalid message id %q", s)
+	}
+	return id, nil
+}
+
+// parseRange parses the bounds of an "A-B" range.
+func parseRange(lo, hi string) (idRange, error) {
+	a, err := parseMessageID(lo)
+	if err != nil {
+		return idRange{}, err
+	}
+	b, err := parseMessageID(hi)
+	if err != nil {
+		return idRange{}, err
+	}
+	if a > b {
+		return idRange{}, errors.Errorf("start %d is after end %d", a, b)
+	}
+	return idRange{lo: a, hi: b}, nil
+}
+
+// parseSince parses the argument of a since: selector relative to now: a date
+// (local midnight), an RFC3339 time, or a duration ago with an optional "d"
+// (days) unit.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, errors.Errorf("want YYYY-MM-DD, RFC3339 or a duration like 36h or 7d, got %q", s)
+}
+
+// readSelectionIDs reads message ids from r: whitespace-separated integers, or
+// JSON lines carrying an "id", either directly or in an output envelope's
+// "data" (as written by `tg history|search -o jsonl`). The chats of JSON lines
+// that name one are returned too.
+func readSelectionIDs(r io.Reader) ([]int, []stdinChat, error) {
+	var (
+		ids   []int
+		chats []stdinChat
+	)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "{") {
+			for _, f := range strings.Fields(line) {
+				id, err := parseMessageID(f)
+				if err != nil {
+					return nil, nil, errors.Wrapf(err, "stdin line %d", n)
+				}
+				ids = append(ids, id)
+			}
+			continue
+		}
+		id, chat, err := jsonLineID([]byte(line))
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "stdin line %d", n)
+		}
+		ids = append(ids, id)
+		if chat != nil {
+			chats = append(chats, stdinChat{line: n, peer: *chat})
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, errors.Wrap(err, "read stdin")
+	}
+	return ids, chats, nil
+}
+
+// jsonLineID extracts the message id, and the chat if given, from one JSON
+// line.
+func jsonLineID(line []byte) (int, *peerRef, error) {
+	var v struct {
+		ID    int             `json:"id"`
+		Peer  *peerRef        `json:"peer"`
+		Data  json.RawMessage `json:"data"`
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(line, &v); err != nil {
+		return 0, nil, errors.Wrap(err, "decode")
+	}
+	if v.Error != nil {
+		return 0, nil, errors.New("input is an error envelope")
+	}
+	if v.ID == 0 && v.Data != nil {
+		return jsonLineID(v.Data)
+	}
+	if v.ID <= 0 {
+		return 0, nil, errors.New(`no positive "id" field`)
+	}
+	return v.ID, v.Peer, nil
+}
+
+// checkChats rejects a selection whose stdin lines name messages in a chat
+// other than peer: message ids are per chat, so they would select unrelated
+// messages there.
+func (s msgSelection) checkChats(ctx context.Context, m *peerManager, peer tg.InputPeerClass) error {
+	if len(s.chats) == 0 {
+		return nil
+	}
+	var target peerRef
+	switch p := peer.(type) {
+	case *tg.InputPeerSelf:
+		self, err := m.Self(ctx)
+		if err != nil {
+			return errors.Wrap(err, "get self")
+		}
+		target = peerRef{ID: self.ID(), Type: peerUser}
+	case *tg.InputPeerUser:
+		target = peerRef{ID: p.UserID, Type: peerUser}
+	case *tg.InputPeerChat:
+		target = peerRef{ID: p.ChatID, Type: peerChat}
+	case *tg.InputPeerChannel:
+		target = peerRef{ID: p.ChannelID, Type: peerChannel}
+	default:
+		return nil
+	}
+	for _, c := range s.chats {
+		if c.peer.ID != target.ID || c.peer.Type != target.Type {
+			return withClass(classUsage, errors.Errorf(
+				"stdin line %d is a message in %s, not in the target chat", c.line, c.peer.label()))
+		}
+	}
+	return nil
+}
+
+// empty reports whether s selects nothing.
+func (s msgSelection) empty() bool {
+	return len(s.ids) == 0 && len(s.ranges) == 0 && s.last == 0 && s.since.IsZero() && len(s.from) == 0
+}
+
+// literal reports whether s is a plain id list that needs no history lookup.
+func (s msgSelection) literal() bool {
+	return len(s.ranges) == 0 && s.last == 0 && s.since.IsZero() && len(s.from) == 0
+}
+
+// hasSet reports whether s names messages (ids, ranges or last:) rather than
+// only filtering the history.
+func (s msgSelection) hasSet() bool {
+	return len(s.ids) > 0 || len(s.ranges) > 0 || s.last > 0
+}
+
+// bounds returns the smallest and largest id named by ids and ranges.
+func (s msgSelection) bounds() (lo, hi int) {
+	lo, hi = math.MaxInt, 0
+	for _, id := range s.ids {
+		lo, hi = min(lo, id), max(hi, id)
+	}
+	for _, r := range s.ranges {
+		lo, hi = min(lo, r.lo), max(hi, r.hi)
+	}
+	return lo, hi
+}
+
+// named reports whether id is one of the ids or in one of the ranges.
+func (s msgSelection) named(id int) bool {
+	if slices.Contains(s.ids, id) {
+		return true
+	}
+	for _, r := range s.ranges {
+		if id >= r.lo && id <= r.hi {
+			return true
+		}
+	}
+	return false
+}
+
+// selectionScan matches messages against a selection while walking a history
+// newest-first.
+type selectionScan struct {
+	sel  msgSelection
+	lo   int
+	self int64
+	from map[int64]bool // marked ids of the from: peers
+	seen int
+	ids  []int
+}
+
+// newSelectionScan resolves the selection's from: peers.
+func newSelectionScan(ctx context.Context, m *peerManager, sel msgSelection) (*selectionScan, error) {
+	sc := &selectionScan{sel: sel}
+	sc.lo, _ = sel.bounds()
+	if len(sel.from) == 0 {
+		return sc, nil
+	}
+	self, err := m.Self(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get self")
+	}
+	sc.self = int64(self.TDLibPeerID())
+	sc.from = map[int64]bool{}
+	for _, arg := range sel.from {
+		spec, err := m.peerSpec(arg)
+		if err != nil {
+			return nil, err
+		}
+		if isSelf(spec) {
+			sc.from[sc.self] = true
+			continue
+		}
+		p, err := m.Resolve(ctx, arg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolve from:%s", arg)
+		}
+		sc.from[int64(p.TDLibPeerID())] = true
+	}
+	return sc, nil
+}
+
+// visit matches one message and reports whether older messages can still
+// match.
+func (sc *selectionScan) visit(msg tg.NotEmptyMessage) bool {
+	s := sc.sel
+	if !s.since.IsZero() && int64(msg.GetDate()) < s.since.Unix() {
+		return false
+	}
+	id := msg.GetID()
+	sc.seen++
+	if s.hasSet() && sc.seen > s.last && !s.named(id) {
+		return sc.more(id)
+	}
+	if sc.from == nil || sc.from[sc.sender(msg)] {
+		sc.ids = append(sc.ids, id)
+	}
+	return sc.more(id)
+}
+
+// more reports whether messages older than id can still be named by the
+// selection's ids, ranges or last:.
+func (sc *selectionScan) more(id int) bool {
+	s := sc.sel
+	if !s.hasSet() {
+		return true
+	}
+	return sc.seen < s.last || id > sc.lo
+}
+
+// sender returns the marked id of a message's sender: its from_id, else you for
+// outgoing messages, else the peer it was posted in (private chats and
+// channel posts).
+func (sc *selectionScan) sender(msg tg.NotEmptyMessage) int64 {
+	if from, ok := msg.GetFromID(); ok {
+		return markedPeerID(from)
+	}
+	if msg.GetOut() {
+		return sc.self
+	}
+	return markedPeerID(msg.GetPeerID())
+}
+
+// markedPeerID returns the Bot API style id of a peer.
+func markedPeerID(p tg.PeerClass) int64 {
+	var id constant.TDLibPeerID
+	switch p := p.(type) {
+	case *tg.PeerUser:
+		id.User(p.UserID)
+	case *tg.PeerChat:
+		id.Chat(p.ChatID)
+	case *tg.PeerChannel:
+		id.Channel(p.ChannelID)
+	}
+	return int64(id)
+}
+
+// resolveSelection returns the ids of the messages in peer's history matching
+// sel, newest first. A plain id list is returned as given without a lookup.
+func resolveSelection(ctx context.Context, api *tg.Client, m *peerManager, peer tg.InputPeerClass, sel msgSelection) ([]int, error) {
+	if err := sel.checkChats(ctx, m, peer); err != nil {
+		return nil, err
+	}
+	if sel.literal() {
+		return sel.ids, nil
+	}
+	sc, err := newSelectionScan(ctx, m, sel)
+	if err != nil {
+		return nil, err
+	}
+	q := query.Messages(api).GetHistory(peer).BatchSize(100)
+	if _, hi := sel.bounds(); sel.last == 0 && hi > 0 {
+		// Skip straight to the newest named message.
+		q = q.OffsetID(hi + 1)
+	}
+	iter := q.Iter()
+	for iter.Next(ctx) {
+		if !sc.visit(iter.Value().Msg) {
+			break
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate history")
+	}
+	return sc.ids, nil
+}
+
+// selectMessages is resolveSelection over an already fetched message list,
+// such as the scheduled messages of a chat. Their dates need not follow their
+// ids, so every message is visited rather than stopping at the first one
+// before since:.
+func selectMessages(ctx context.Context, m *peerManager, msgs []tg.MessageClass, sel msgSelection) ([]int, error) {
+	if sel.literal() {
+		return sel.ids, nil
+	}
+	sc, err := newSelectionScan(ctx, m, sel)
+	if err != nil {
+		return nil, err
+	}
+	var list []tg.NotEmptyMessage
+	for _, msg := range msgs {
+		if msg, ok := msg.AsNotEmpty(); ok {
+			list = append(list, msg)
+		}
+	}
+	slices.SortFunc(list, func(a, b tg.NotEmptyMessage) int { return b.GetID() - a.GetID() })
+	for _, msg := range list {
+		sc.visit(msg)
+	}
+	return sc.ids, nil
+}
+
+// messageSelectArgs validates "<peer> <selector>..." arguments, where a single
+// message link may stand for both.
+func messageSelectArgs(_ *cobra.Command, args []string) error {
+	if len(args) == 1 {
+		if _, ok := parseMessageLink(args[0]); ok {
+			return nil
+		}
+	}
+	if len(args) < 2 {
+		return errors.Errorf("accepts at least 2 arg(s), received %d", len(args))
+	}
+	return nil
+}
+
+// splitMessageSelect splits args validated by messageSelectArgs into the peer
+// and the selectors.
+func splitMessageSelect(args []string) (string, []string) {
+	if len(args) == 1 {
+		ref, _ := parseMessageLink(args[0])
+		return ref.Peer, []string{strconv.Itoa(ref.ID)}
+	}
+	return args[0], args[1:]
+}
+
+// chunkIDs splits ids into batches of at most n, the per-request limit of
+// Telegram's bulk message methods.
+func chunkIDs(ids []int, n int) [][]int {
+	var out [][]int
+	for len(ids) > n {
+		out = append(out, ids[:n])
+		ids = ids[n:]
+	}
+	if len(ids) > 0 {
+		out = append(out, ids)
+	}
+	return out
+}
+
+// ascending returns ids sorted oldest-first (selections resolve newest-first).
+func ascending(ids []int) []int {
+	out := slices.Clone(ids)
+	slices.Sort(out)
+	return out
+}
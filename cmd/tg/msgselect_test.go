@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+)
+
+func TestParseSelection(t *testing.T) {
+	s, err := parseSelection([]string{"5", "10-20", "last:3", "since:2026-01-02", "from:@alice"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(s.ids, []int{5}) || len(s.ranges) != 1 || s.ranges[0] != (idRange{lo: 10, hi: 20}) {
+		t.Errorf("ids/ranges = %v %v", s.ids, s.ranges)
+	}
+	if s.last != 3 || s.since.Day() != 2 || !slices.Equal(s.from, []string{"@alice"}) {
+		t.Errorf("selection = %+v", s)
+	}
+	if s.literal() {
+		t.Error("selection with selectors reported literal")
+	}
+
+	lit, err := parseSelection([]string{"1", "2"}, nil)
+	if err != nil || !lit.literal() {
+		t.Errorf("literal = %+v, %v", lit, err)
+	}
+
+	for _, bad := range [][]string{{"x"}, {"0"}, {"20-10"}, {"1-x"}, {"last:0"}, {"since:soon"}, {"from:"}, {"-", "-"}} {
+		if _, err := parseSelection(bad, strings.NewReader("1\n")); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	for in, want := range map[string]time.Time{
+		"2026-03-01T00:00:00Z": time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		"36h":                  now.Add(-36 * time.Hour),
+		"7d":                   now.AddDate(0, 0, -7),
+	} {
+		got, err := parseSince(in, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	got, err := parseSince("2026-01-01", now)
+	if err != nil || got.Year() != 2026 || got.YearDay() != 1 || got.Hour() != 0 {
+		t.Errorf("date = %v, %v", got, err)
+	}
+}
+
+func TestReadSelectionIDs(t *testing.T) {
+	in := "1 2\n\n" +
+		`{"schema":1,"data":{"id":3,"peer":{"id":7,"type":"channel"},"date":1,"out":false}}` + "\n" +
+		`{"id":4}` + "\n"
+	ids, chats, err := readSelectionIDs(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, []int{1, 2, 3, 4}) {
+		t.Errorf("ids = %v", ids)
+	}
+	if len(chats) != 1 || chats[0].line != 3 || chats[0].peer.ID != 7 {
+		t.Errorf("chats = %+v", chats)
+	}
+	if _, _, err := readSelectionIDs(strings.NewReader(`{"schema":1,"error":{"message":"x"}}`)); err == nil {
+		t.Error("expected error for an error envelope")
+	}
+	if _, _, err := readSelectionIDs(strings.NewReader("1\nfoo\n")); err == nil {
+		t.Error("expected error for a bad id")
+	}
+}
+
+func TestSelectionCheckChats(t *testing.T) {
+	// `tg search --global` output mixes chats.
+	in := `{"schema":1,"data":{"id":3,"peer":{"id":7,"type":"channel"}}}` + "\n" +
+		`{"schema":1,"data":{"id":4,"peer":{"id":8,"type":"channel"}}}` + "\n"
+	sel, err := parseSelection([]string{"-"}, strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	api := newFuncAPI(t, func(req bin.Encoder) (bin.Encoder, error) {
+		return nil, errors.Errorf("unexpected request %T", req)
+	})
+	_, err = resolveSelection(ctx, api, nil, &tg.InputPeerChannel{ChannelID: 7}, sel)
+	if err == nil || classify(err) != classUsage || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("mixed chats: err = %v", err)
+	}
+	if _, err := deleteScheduled(ctx, api, nil, &tg.InputPeerChannel{ChannelID: 8}, sel, true, false); err == nil {
+		t.Error("schedule delete: expected error for mixed chats")
+	}
+	// The same ids in another chat type do not match either.
+	if err := sel.checkChats(ctx, nil, &tg.InputPeerChat{ChatID: 7}); err == nil {
+		t.Error("expected error for a chat with a channel's id")
+	}
+
+	sel.chats = sel.chats[:1]
+	ids, err := resolveSelection(ctx, api, nil, &tg.InputPeerChannel{ChannelID: 7}, sel)
+	if err != nil || !slices.Equal(ids, []int{3, 4}) {
+		t.Errorf("ids = %v, %v", ids, err)
+	}
+}
+
+// historyAPI serves a fixed newest-first history, honoring offset_id like
+// Telegram does, and records the requests.
+func historyAPI(t *testing.T, msgs []tg.MessageClass, reqs *[]*tg.MessagesGetHistoryRequest) *tg.Client {
+	return newFuncAPI(t, func(req bin.Encoder) (bin.Encoder, error) {
+		r, ok := req.(*tg.MessagesGetHistoryRequest)
+		if !ok {
+			return nil, errors.Errorf("unexpected request %T", req)
+		}
+		*reqs = append(*reqs, r)
+		var page []tg.MessageClass
+		for _, m := range msgs {
+			if r.OffsetID == 0 || m.GetID() < r.OffsetID {
+				page = append(page, m)
+			}
+		}
+		return &tg.MessagesMessages{Messages: page, Users: []tg.UserClass{&tg.User{ID: 5}}}, nil
+	})
+}
+
+func TestResolveSelection(t *testing.T) {
+	var msgs []tg.MessageClass
+	for id := 10; id >= 1; id-- {
+		// Messages 1-10, one per day; even ones sent by user 5, odd ones by 6.
+		msgs = append(msgs, &tg.Message{
+			ID:     id,
+			Date:   id * 86400,
+			PeerID: &tg.PeerChat{ChatID: 1},
+			FromID: &tg.PeerUser{UserID: int64(5 + id%2)},
+		})
+	}
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name   string
+		sel    msgSelection
+		want   []int
+		offset int
+	}{
+		{name: "Range", sel: msgSelection{ranges: []idRange{{lo: 3, hi: 5}}}, want: []int{5, 4, 3}, offset: 6},
+		{name: "Last", sel: msgSelection{last: 2}, want: []int{10, 9}},
+		{name: "LastAndID", sel: msgSelection{last: 1, ids: []int{2}}, want: []int{10, 2}},
+		{name: "Since", sel: msgSelection{since: time.Unix(8*86400, 0)}, want: []int{10, 9, 8}},
+		{name: "SinceRange", sel: msgSelection{ranges: []idRange{{lo: 1, hi: 9}}, since: time.Unix(8*86400, 0)}, want: []int{9, 8}, offset: 10},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var reqs []*tg.MessagesGetHistoryRequest
+			api := historyAPI(t, msgs, &reqs)
+			ids, err := resolveSelection(ctx, api, nil, &tg.InputPeerChat{ChatID: 1}, tt.sel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("ids = %v, want %v", ids, tt.want)
+			}
+			if len(reqs) == 0 || reqs[0].OffsetID != tt.offset {
+				t.Errorf("requests = %+v, want offset_id %d", reqs, tt.offset)
+			}
+		})
+	}
+
+	t.Run("Literal", func(t *testing.T) {
+		var reqs []*tg.MessagesGetHistoryRequest
+		api := historyAPI(t, msgs, &reqs)
+		ids, err := resolveSelection(ctx, api, nil, &tg.InputPeerChat{ChatID: 1}, msgSelection{ids: []int{7, 3}})
+		if err != nil || !slices.Equal(ids, []int{7, 3}) || len(reqs) != 0 {
+			t.Errorf("ids = %v, %v; %d requests", ids, err, len(reqs))
+		}
+	})
+}
+
+func TestSelectionScanFrom(t *testing.T) {
+	sc := &selectionScan{
+		sel:  msgSelection{last: 4},
+		lo:   math.MaxInt,
+		self: markedPeerID(&tg.PeerUser{UserID: 1}),
+		from: map[int64]bool{markedPeerID(&tg.PeerUser{UserID: 1}): true},
+	}
+	for _, m := range []*tg.Message{
+		{ID: 4, Out: true, PeerID: &tg.PeerUser{UserID: 2}},
+		{ID: 3, PeerID: &tg.PeerUser{UserID: 2}},
+		{ID: 2, FromID: &tg.PeerUser{UserID: 1}, PeerID: &tg.PeerChat{ChatID: 9}},
+		{ID: 1, FromID: &tg.PeerUser{UserID: 3}, PeerID: &tg.PeerChat{ChatID: 9}},
+	} {
+		m.SetFlags()
+		sc.visit(m)
+	}
+	if !slices.Equal(sc.ids, []int{4, 2}) {
+		t.Errorf("ids = %v, want [4 2]", sc.ids)
+	}
+}
+
+func TestSelectMessagesScheduled(t *testing.T) {
+	// Scheduled messages: the dates do not follow the ids.
+	var msgs []tg.MessageClass
+	for id, day := range map[int]int{1: 9, 2: 3, 3: 7, 4: 1, 5: 8} {
+		msgs = append(msgs, &tg.Message{ID: id, Date: day * 86400, PeerID: &tg.PeerChat{ChatID: 1}})
+	}
+	ids, err := selectMessages(context.Background(), nil, msgs, msgSelection{since: time.Unix(7*86400, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, []int{5, 3, 1}) {
+		t.Errorf("ids = %v, want [5 3 1]", ids)
+	}
+}
+
+func TestSplitMessageSelect(t *testing.T) {
+	peer, sel := splitMessageSelect([]string{"https://t.me/durov/42"})
+	if peer != "@durov" || !slices.Equal(sel, []string{"42"}) {
+		t.Errorf("link = %q %v", peer, sel)
+	}
+	if err := messageSelectArgs(nil, []string{"@durov"}); err == nil {
+		t.Error("expected error for a peer without ids")
+	}
+	if err := messageSelectArgs(nil, []string{"@durov", "last:5"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestChunkIDs(t *testing.T) {
+	ids := make([]int, 250)
+	chunks := chunkIDs(ids, 100)
+	if len(chunks) != 3 || len(chunks[0]) != 100 || len(chunks[2]) != 50 {
+		t.Errorf("chunks = %d", len(chunks))
+	}
+	if chunkIDs(nil, 100) != nil {
+		t.Error("expected no chunks for no ids")
+	}
+}
@@ -76,7 +76,7 @@ func (a *app) participantsCmd(use, short, filter string) *cobra.Command {
 				f = filterFlag
 			}
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -88,7 +88,7 @@ func (a *app) participantsCmd(use, short, filter string) *cobra.Command {
 				if err != nil {
 					return err
 				}
-				return a.printer.Emit(res)
+				return a.out(ctx).Emit(res)
 			})
 		},
 	}
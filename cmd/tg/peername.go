@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/telegram/peers"
+
+	"github.com/gotd/cli/internal/peercache"
+)
+
+// Name selectors: "name:Project Alpha" or "~project alpha" picks a cached peer
+// by its dialog title or contact name, for groups and people without a
+// username.
+const (
+	peerNamePrefix  = "name:"
+	peerFuzzyPrefix = "~"
+)
+
+// maxNameCandidates caps the candidates listed in an ambiguity error.
+const maxNameCandidates = 10
+
+// minPickMatch is the weakest match a name selector resolves by itself;
+// weaker ones are only offered as candidates.
+const minPickMatch = matchSubstring
+
+// isNameArg reports whether arg is a "name:" or "~" selector.
+func isNameArg(arg string) bool {
+	arg = strings.TrimSpace(arg)
+	return strings.HasPrefix(arg, peerNamePrefix) || strings.HasPrefix(arg, peerFuzzyPrefix)
+}
+
+// isCachedArg reports whether arg selects a peer from the local cache ("id:" or
+// a name selector) rather than by username, phone or link.
+func isCachedArg(arg string) bool {
+	return isIDArg(arg) || isNameArg(arg)
+}
+
+// resolveCached resolves an "id:" or name selector through the peer cache.
+func (m *peerManager) resolveCached(ctx context.Context, arg string) (peers.Peer, error) {
+	if isNameArg(arg) {
+		return m.resolveName(ctx, arg)
+	}
+	return m.resolveID(ctx, arg)
+}
+
+// nameMatch is how well a query matches a peer name; higher is better.
+type nameMatch int
+
+const (
+	noMatch         nameMatch = iota
+	matchSubseq               // query letters appear in order ("prjalpha")
+	matchSubstring            // query occurs anywhere in the name
+	matchWordPrefix           // every query word starts a name word ("pro alp")
+	matchPrefix               // name starts with the query
+	matchExact                // equal, ignoring case and spacing
+)
+
+// normalizeName lowercases s and collapses runs of spaces and punctuation, so
+// "Project  Alpha!" and "project alpha" compare equal.
+func normalizeName(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// matchName scores name against an already normalized query.
+func matchName(name, query string) nameMatch {
+	name = normalizeName(name)
+	switch {
+	case name == "" || query == "":
+		return noMatch
+	case name == query:
+		return matchExact
+	case strings.HasPrefix(name, query):
+		return matchPrefix
+	case wordPrefixes(strings.Fields(name), strings.Fields(query)):
+		return matchWordPrefix
+	case strings.Contains(name, query):
+		return matchSubstring
+	case subsequence(strings.ReplaceAll(name, " ", ""), strings.ReplaceAll(query, " ", "")):
+		return matchSubseq
+	default:
+		return noMatch
+	}
+}
+
+// wordPrefixes reports whether every query word is a prefix of a distinct name
+// word, in order.
+func wordPrefixes(words, query []string) bool {
+	i := 0
+	for _, w := range words {
+		if i < len(query) && strings.HasPrefix(w, query[i]) {
+			i++
+		}
+	}
+	return i == len(query)
+}
+
+// subsequence reports whether the runes of q appear in s in order.
+func subsequence(s, q string) bool {
+	rs := []rune(q)
+	i := 0
+	for _, r := range s {
+		if i < len(rs) && r == rs[i] {
+			i++
+		}
+	}
+	return i == len(rs)
+}
+
+// nameCandidate is a cached peer matching a name selector.
+type nameCandidate struct {
+	peercache.Info
+	match nameMatch
+}
+
+// rankNames returns the cached peers matching query, best match first, then
+// in cache order (most recently active first).
+func rankNames(infos []peercache.Info, query string) []nameCandidate {
+	query = normalizeName(query)
+	var out []nameCandidate
+	for _, info := range infos {
+		if mt := matchName(info.Name, query); mt != noMatch {
+			out = append(out, nameCandidate{Info: info, match: mt})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].match > out[j].match })
+	return out
+}
+
+// pickName returns the single best candidate, or false when none matches at
+// least minPickMatch or the best match is shared by several peers.
+func pickName(cands []nameCandidate) (nameCandidate, bool) {
+	if len(cands) == 0 || cands[0].match < minPickMatch || (len(cands) > 1 && cands[1].match == cands[0].match) {
+		return nameCandidate{}, false
+	}
+	return cands[0], true
+}
+
+// resolveName resolves a name selector to the one cached peer that matches it
+// best. It never guesses: no match, only loose (subsequence) matches, or a tie
+// for the best match, is a peer_not_found error listing the ranked candidates
+// with their ids.
+func (m *peerManager) resolveName(ctx context.Context, arg string) (peers.Peer, error) {
+	arg = strings.TrimSpace(arg)
+	query := strings.TrimPrefix(arg, peerNamePrefix)
+	if query == arg {
+		query = strings.TrimPrefix(arg, peerFuzzyPrefix)
+	}
+	query = strings.TrimSpace(query)
+	if normalizeName(query) == "" {
+		return nil, withClass(classUsage, errors.Errorf("empty peer name in %q", arg))
+	}
+
+	cands := rankNames(m.store.Infos(), query)
+	best, ok := pickName(cands)
+	if ok {
+		return m.resolveKindID(ctx, best.Kind, best.ID)
+	}
+	if len(cands) == 0 {
+		return nil, withClass(classPeer, errors.Errorf(
+			"no cached peer is named like %q; run `tg chats list` (or `tg contacts list`) first so names are cached", query))
+	}
+
+	var b strings.Builder
+	if cands[0].match < minPickMatch {
+		_, _ = fmt.Fprintf(&b, "peer name %q only loosely matches %d cached peer(s); use one of:", query, len(cands))
+	} else {
+		_, _ = fmt.Fprintf(&b, "peer name %q is ambiguous (%d matches); use one of:", query, len(cands))
+	}
+	for i, c := range cands {
+		if i == maxNameCandidates {
+			_, _ = fmt.Fprintf(&b, "\n  ... and %d more", len(cands)-i)
+			break
+		}
+		_, _ = fmt.Fprintf(&b, "\n  %s%s  %s (%s)", peerIDPrefix, strconv.FormatInt(c.ID, 10), c.Name, c.Kind)
+	}
+	return nil, withClass(classPeer, errors.New(b.String()))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gotd/td/telegram/peers"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/peercache"
+)
+
+func TestMatchName(t *testing.T) {
+	for _, c := range []struct {
+		name, query string
+		want        nameMatch
+	}{
+		{"Project Alpha", "project alpha", matchExact},
+		{"Project  Alpha!", "project alpha", matchExact},
+		{"Project Alpha", "proj", matchPrefix},
+		{"Project Alpha", "pro alp", matchWordPrefix},
+		{"Project Alpha", "ject", matchSubstring},
+		{"Project Alpha", "prjalpha", matchSubseq},
+		{"Project Alpha", "beta", noMatch},
+		{"", "alpha", noMatch},
+	} {
+		if got := matchName(c.name, normalizeName(c.query)); got != c.want {
+			t.Errorf("matchName(%q, %q) = %d, want %d", c.name, c.query, got, c.want)
+		}
+	}
+}
+
+func TestResolveName(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(t)
+	if err := m.store.Save(ctx, peers.Key{Prefix: "users_", ID: 42}, peers.Value{AccessHash: 999}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.store.SaveInfo(
+		peercache.Info{ID: 42, Kind: peerUser, Name: "Mom", LastSeen: 10},
+		peercache.Info{ID: 1, Kind: peerChat, Name: "Project Alpha", LastSeen: 30},
+		peercache.Info{ID: 2, Kind: peerChannel, Name: "Project Alpha News", LastSeen: 20},
+		peercache.Info{ID: 3, Kind: peerChat, Name: "Project Beta"},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// A unique best match resolves, even when weaker matches exist.
+	api, mock := newTestAPI(t)
+	mock.Expect().ThenResult(&tg.UserClassVector{Elems: []tg.UserClass{
+		&tg.User{ID: 42, AccessHash: 999, FirstName: "Mom"},
+	}})
+	m.Manager = peers.Options{Storage: m.store}.Build(api)
+	p, err := resolvePeer(ctx, m, "~mom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, ok := p.(*tg.InputPeerUser); !ok || u.UserID != 42 {
+		t.Fatalf("resolvePeer(~mom) = %+v", p)
+	}
+
+	// A tie is an error listing the candidates, most recent first.
+	_, err = resolvePeer(ctx, m, "name:project")
+	if classify(err) != classPeer {
+		t.Fatalf("ambiguous name: class %q, err %v", classify(err), err)
+	}
+	msg := err.Error()
+	for _, want := range []string{"ambiguous (3 matches)", "id:1  Project Alpha (chat)", "id:2  Project Alpha News (channel)"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q does not mention %q", msg, want)
+		}
+	}
+	if strings.Index(msg, "id:1 ") > strings.Index(msg, "id:2 ") {
+		t.Errorf("candidates not ranked by recency: %q", msg)
+	}
+
+	// The matched entry's kind picks the peer when a user shares its id.
+	if err := m.store.Save(ctx, peers.Key{Prefix: "channel_", ID: 42}, peers.Value{AccessHash: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.store.SaveInfo(peercache.Info{ID: 42, Kind: peerChannel, Name: "Ops Room"}); err != nil {
+		t.Fatal(err)
+	}
+	mock.Expect().ThenResult(&tg.MessagesChats{Chats: []tg.ChatClass{
+		&tg.Channel{ID: 42, AccessHash: 7, Title: "Ops Room", Photo: &tg.ChatPhotoEmpty{}},
+	}})
+	p, err = resolvePeer(ctx, m, "~ops room")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := p.(*tg.InputPeerChannel); !ok || c.ChannelID != 42 {
+		t.Fatalf("resolvePeer(~ops room) = %+v", p)
+	}
+
+	// A subsequence-only match is offered, never picked.
+	_, err = resolvePeer(ctx, m, "~mm")
+	if classify(err) != classPeer {
+		t.Fatalf("loose name: class %q, err %v", classify(err), err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "only loosely matches 1 cached peer(s)") || !strings.Contains(msg, "id:42  Mom (user)") {
+		t.Errorf("loose name: error %q", msg)
+	}
+
+	if _, err := resolvePeer(ctx, m, "name:nobody"); classify(err) != classPeer {
+		t.Fatalf("unknown name: class %q, err %v", classify(err), err)
+	}
+	if _, err := resolvePeer(ctx, m, "name:  "); classify(err) != classUsage {
+		t.Fatalf("empty name: class %q, err %v", classify(err), err)
+	}
+}
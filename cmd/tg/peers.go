@@ -5,9 +5,11 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-faster/errors"
 
+	"github.com/gotd/td/constant"
 	"github.com/gotd/td/telegram/message"
 	"github.com/gotd/td/telegram/peers"
 	"github.com/gotd/td/tg"
@@ -27,21 +29,128 @@ const peerIDPrefix = "id:"
 type peerManager struct {
 	*peers.Manager
 	store *peercache.Storage
+	// aliases are the account's peer aliases; see expandAlias.
+	aliases map[string]string
 }
 
-// resolveID resolves an "id:<n>" argument to a cached peer. The kind
-// (user/chat/channel) comes from the peer cache; an unseen id is an error that
-// points the user at `tg chats list`.
+// Apply caches the entities' access hashes, like peers.Manager.Apply, and also
+// their names and usernames for offline lookup and completion.
+func (m *peerManager) Apply(ctx context.Context, users []tg.UserClass, chats []tg.ChatClass) error {
+	if err := m.Manager.Apply(ctx, users, chats); err != nil {
+		return err
+	}
+	return m.remember(entityInfos(users, chats)...)
+}
+
+// Resolve is peers.Manager.Resolve, also recording the peer's metadata. It
+// additionally accepts aliases and the cache selectors "id:<n>" and
+// "name:<text>"/"~text".
+func (m *peerManager) Resolve(ctx context.Context, from string) (peers.Peer, error) {
+	from, err := m.peerSpec(from)
+	if err != nil {
+		return nil, err
+	}
+	if isCachedArg(from) {
+		return m.resolveCached(ctx, from)
+	}
+	p, err := m.Manager.Resolve(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	return p, m.remember(peerInfo(describeManagedPeer(p), 0))
+}
+
+// ResolveDomain is peers.Manager.ResolveDomain, also recording the peer's
+// metadata.
+func (m *peerManager) ResolveDomain(ctx context.Context, domain string) (peers.Peer, error) {
+	p, err := m.Manager.ResolveDomain(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	return p, m.remember(peerInfo(describeManagedPeer(p), 0))
+}
+
+// remember records peer metadata in the cache.
+func (m *peerManager) remember(infos ...peercache.Info) error {
+	if err := m.store.SaveInfo(infos...); err != nil {
+		return errors.Wrap(err, "cache peer info")
+	}
+	return nil
+}
+
+// peerInfo converts a peerRef to cache metadata; lastSeen is the unix time of
+// the peer's latest dialog activity, or zero.
+func peerInfo(ref peerRef, lastSeen int64) peercache.Info {
+	return peercache.Info{ID: ref.ID, Kind: ref.Type, Name: ref.Name, Username: ref.Username, LastSeen: lastSeen}
+}
+
+// entityInfos returns cache metadata for the users and chats of a response.
+func entityInfos(users []tg.UserClass, chats []tg.ChatClass) []peercache.Info {
+	ent := entitiesOf(users, chats)
+	var out []peercache.Info
+	for _, uc := range users {
+		if u, ok := uc.AsNotEmpty(); ok {
+			out = append(out, peerInfo(describePeer(&tg.PeerUser{UserID: u.ID}, ent), 0))
+		}
+	}
+	for _, c := range chats {
+		switch c := c.(type) {
+		case *tg.Chat:
+			out = append(out, peerInfo(describePeer(&tg.PeerChat{ChatID: c.ID}, ent), 0))
+		case *tg.Channel:
+			out = append(out, peerInfo(describePeer(&tg.PeerChannel{ChannelID: c.ID}, ent), 0))
+		}
+	}
+	return out
+}
+
+// resolveID resolves an "id:<n>" argument or a Bot API marked id to a cached
+// peer. For a plain id the kind (user/chat/channel) comes from the peer cache;
+// an unseen id is an error that points the user at `tg chats list`.
 func (m *peerManager) resolveID(ctx context.Context, arg string) (peers.Peer, error) {
-	raw := strings.TrimSpace(strings.TrimPrefix(arg, peerIDPrefix))
+	raw := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(arg), peerIDPrefix))
 	id, err := strconv.ParseInt(raw, 10, 64)
 	if err != nil {
 		return nil, errors.Wrapf(err, "invalid peer id %q", raw)
 	}
+	if id < 0 {
+		return m.resolveMarkedID(ctx, id)
+	}
+	return m.resolveCachedID(ctx, id)
+}
+
+// resolveMarkedID resolves a Bot API / TDLib marked id: "-100<n>" is channel
+// <n>, "-<n>" basic group <n>. The kind is in the id, so only channels, which
+// need an access hash, have to be cached.
+func (m *peerManager) resolveMarkedID(ctx context.Context, id int64) (peers.Peer, error) {
+	marked := constant.TDLibPeerID(id)
+	switch {
+	case marked.IsChannel():
+		plain := marked.ToPlain()
+		if !m.store.Has(peercache.KindChannel, plain) {
+			return nil, errPeerNotCached(plain)
+		}
+		return m.ResolveChannelID(ctx, plain)
+	case marked.IsChat():
+		return m.ResolveChatID(ctx, marked.ToPlain())
+	default:
+		return nil, withClass(classPeer, errors.Errorf("invalid marked peer id %d", id))
+	}
+}
+
+// resolveCachedID resolves a numeric peer id whose kind and access hash are in
+// the peer cache.
+func (m *peerManager) resolveCachedID(ctx context.Context, id int64) (peers.Peer, error) {
 	kind, ok := m.store.Kind(id)
 	if !ok {
-		return nil, errors.Errorf("peer id %d not in cache; run `tg chats list` (or `tg contacts list`) first so its access hash is stored", id)
+		return nil, errPeerNotCached(id)
 	}
+	return m.resolveKindID(ctx, kind, id)
+}
+
+// resolveKindID resolves the cached peer of the given kind and id. Users,
+// chats and channels have separate id spaces, so the kind picks the peer.
+func (m *peerManager) resolveKindID(ctx context.Context, kind string, id int64) (peers.Peer, error) {
 	switch kind {
 	case peercache.KindUser:
 		return m.ResolveUserID(ctx, id)
@@ -54,9 +163,35 @@ func (m *peerManager) resolveID(ctx context.Context, arg string) (peers.Peer, er
 	}
 }
 
-// isIDArg reports whether arg uses the "id:" prefix.
+// errPeerNotCached is the error for a peer id without a cached access hash.
+func errPeerNotCached(id int64) error {
+	return withClass(classPeer, errors.Errorf(
+		"peer id %d not in cache; run `tg chats list` (or `tg contacts list`) first so its access hash is stored, "+
+			"or `tg peers import` a cache exported elsewhere", id))
+}
+
+// isIDArg reports whether arg is a numeric id: "id:<n>", or a Bot API marked
+// id ("-100<n>" for channels, "-<n>" for basic groups).
 func isIDArg(arg string) bool {
-	return strings.HasPrefix(strings.TrimSpace(arg), peerIDPrefix)
+	arg = strings.TrimSpace(arg)
+	if strings.HasPrefix(arg, peerIDPrefix) {
+		return true
+	}
+	_, err := strconv.ParseInt(arg, 10, 64)
+	return err == nil && strings.HasPrefix(arg, "-")
+}
+
+// peerSpec returns the peer argument arg stands for: an alias is expanded and
+// a message link is reduced to its chat.
+func (m *peerManager) peerSpec(arg string) (string, error) {
+	arg, err := m.expandAlias(arg)
+	if err != nil {
+		return "", err
+	}
+	if ref, ok := parseMessageLink(arg); ok {
+		return ref.Peer, nil
+	}
+	return arg, nil
 }
 
 // isSelf reports whether a peer string targets the current account's Saved
@@ -71,14 +206,15 @@ func isSelf(peer string) bool {
 }
 
 // peerResolver adapts a peerManager (with its persistent access-hash cache) to
-// the message package's peer.Resolver interface, adding "id:" support.
+// the message package's peer.Resolver interface, adding the "id:" and
+// "name:" cache selectors.
 type peerResolver struct {
 	pm *peerManager
 }
 
 func (r peerResolver) ResolveDomain(ctx context.Context, domain string) (tg.InputPeerClass, error) {
-	if isIDArg(domain) {
-		p, err := r.pm.resolveID(ctx, domain)
+	if isCachedArg(domain) {
+		p, err := r.pm.resolveCached(ctx, domain)
 		if err != nil {
 			return nil, err
 		}
@@ -99,49 +235,115 @@ func (r peerResolver) ResolvePhone(ctx context.Context, phone string) (tg.InputP
 	return u.InputPeer(), nil
 }
 
-// manager builds a peerManager backed by the persistent access-hash cache for
-// the user account.
-//
-// TODO(phase7): take an account label / auth kind for multi-account.
-func (a *app) manager(api *tg.Client) (*peerManager, error) {
-	return a.managerFor(api, a.active)
+// manager builds a peerManager backed by the persistent access-hash cache of
+// the account ctx works on (see stateOf).
+func (a *app) manager(ctx context.Context, api *tg.Client) (*peerManager, error) {
+	return a.managerFor(api, a.stateOf(ctx))
 }
 
 // managerFor builds a peerManager for a specific account state.
 func (a *app) managerFor(api *tg.Client, st *accountState) (*peerManager, error) {
-	path := st.acc.peerCachePath(filepath.Dir(a.configPath), st.label, authUser.String())
-	store, err := peercache.Open(path)
+	store, err := a.peerCache(st)
 	if err != nil {
-		return nil, errors.Wrap(err, "open peer cache")
+		return nil, err
 	}
 	return &peerManager{
 		Manager: peers.Options{Storage: store}.Build(api),
 		store:   store,
+		aliases: st.acc.Aliases,
 	}, nil
 }
 
+// cachePeers stores the access hashes and metadata of users and chats returned
+// by a query in the active account's peer cache.
+func (a *app) cachePeers(ctx context.Context, api *tg.Client, users []tg.UserClass, chats []tg.ChatClass) error {
+	m, err := a.manager(ctx, api)
+	if err != nil {
+		return err
+	}
+	if err := m.Apply(ctx, users, chats); err != nil {
+		return errors.Wrap(err, "cache peers")
+	}
+	return nil
+}
+
+// peerCache returns the persistent peer cache of an account's user session,
+// opening it on first use. Saves are batched in memory until flushPeers.
+func (a *app) peerCache(st *accountState) (*peercache.Storage, error) {
+	st.peersMu.Lock()
+	defer st.peersMu.Unlock()
+	if st.peers != nil {
+		return st.peers, nil
+	}
+	path := st.acc.peerCachePath(filepath.Dir(a.configPath), st.label, authUser.String())
+	store, err := peercache.OpenCodec(path, cacheCodec(a.cfg, a.sessionKey))
+	if err != nil {
+		return nil, errors.Wrap(err, "open peer cache")
+	}
+	st.peers = store
+	return store, nil
+}
+
+// peerFlushInterval is how often a long-running command (watch, wait) writes
+// newly learned peers, so they survive a crash or kill.
+const peerFlushInterval = 30 * time.Second
+
+// flushPeers writes the peers batched in the account's cache, if it was opened.
+func (st *accountState) flushPeers() error {
+	st.peersMu.Lock()
+	store := st.peers
+	st.peersMu.Unlock()
+	if store == nil {
+		return nil
+	}
+	if err := store.Flush(); err != nil {
+		return errors.Wrap(err, "flush peer cache")
+	}
+	return nil
+}
+
+// flushPeersEvery calls flushPeers every interval until ctx is done. Errors are
+// left for the final flush to report.
+func (st *accountState) flushPeersEvery(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			_ = st.flushPeers()
+		}
+	}
+}
+
 // sender returns a message.Sender that resolves peers through the cached
 // manager, so access-hashes persist across invocations. It also returns the
 // peerManager so builderFor can resolve "id:" peers, which the sender's own
 // resolver cannot (the message package rejects the ":" as an invalid domain
 // before delegating to the resolver).
-func (a *app) sender(api *tg.Client) (*message.Sender, *peerManager, error) {
-	m, err := a.manager(api)
+func (a *app) sender(ctx context.Context, api *tg.Client) (*message.Sender, *peerManager, error) {
+	m, err := a.manager(ctx, api)
 	if err != nil {
 		return nil, nil, err
 	}
 	return message.NewSender(api).WithResolver(peerResolver{pm: m}), m, nil
 }
 
-// builderFor returns a request builder targeting peer; the empty string, "me"
-// and "self" target the current account's Saved Messages, and "id:<n>" resolves
-// a cached peer by numeric id.
+// builderFor returns a request builder targeting peer (or the peer it is an
+// alias for); the empty string, "me" and "self" target the current account's
+// Saved Messages, and "id:<n>" or "name:<text>" resolves a cached peer by
+// numeric id or name.
 func builderFor(ctx context.Context, m *peerManager, sender *message.Sender, peer string) (*message.RequestBuilder, error) {
+	peer, err := m.peerSpec(peer)
+	if err != nil {
+		return nil, err
+	}
 	if isSelf(peer) {
 		return sender.Self(), nil
 	}
-	if isIDArg(peer) {
-		p, err := m.resolveID(ctx, peer)
+	if isCachedArg(peer) {
+		p, err := m.resolveCached(ctx, peer)
 		if err != nil {
 			return nil, err
 		}
@@ -150,15 +352,20 @@ func builderFor(ctx context.Context, m *peerManager, sender *message.Sender, pee
 	return sender.Resolve(peer), nil
 }
 
-// resolvePeer turns a peer string into an InputPeer using the cached manager.
-// The empty string, "me" and "self" resolve to the current account; an "id:<n>"
-// argument resolves a cached peer by numeric id.
+// resolvePeer turns a peer string (or alias) into an InputPeer using the
+// cached manager. The empty string, "me" and "self" resolve to the current
+// account; an "id:<n>" or "name:<text>" argument resolves a cached peer by
+// numeric id or name.
 func resolvePeer(ctx context.Context, m *peerManager, from string) (tg.InputPeerClass, error) {
+	from, err := m.peerSpec(from)
+	if err != nil {
+		return nil, err
+	}
 	if isSelf(from) {
 		return &tg.InputPeerSelf{}, nil
 	}
-	if isIDArg(from) {
-		p, err := m.resolveID(ctx, from)
+	if isCachedArg(from) {
+		p, err := m.resolveCached(ctx, from)
 		if err != nil {
 			return nil, err
 		}
@@ -35,6 +35,10 @@ func TestIsIDArg(t *testing.T) {
 		{"@durov", false},
 		{"42", false},
 		{"identity", false},
+		{"-1001234567890", true},
+		{"-4567", true},
+		{"id:-1001234567890", true},
+		{"-abc", false},
 	} {
 		if got := isIDArg(c.in); got != c.want {
 			t.Errorf("isIDArg(%q) = %v, want %v", c.in, got, c.want)
@@ -139,3 +143,67 @@ func TestBuilderForSelf(t *testing.T) {
 		}
 	}
 }
+
+func TestApplyRecordsInfo(t *testing.T) {
+	m := newTestManager(t)
+	err := m.Apply(context.Background(),
+		[]tg.UserClass{&tg.User{ID: 42, AccessHash: 1, FirstName: "Pavel", LastName: "Durov", Username: "durov"}},
+		[]tg.ChatClass{
+			&tg.Chat{ID: 7, Title: "Family"},
+			&tg.Channel{ID: 9, AccessHash: 2, Title: "News", Username: "news"},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[int64]peercache.Info{}
+	for _, info := range m.store.Infos() {
+		got[info.ID] = info
+	}
+	for _, want := range []peercache.Info{
+		{ID: 42, Kind: peerUser, Name: "Pavel Durov", Username: "durov"},
+		{ID: 7, Kind: peerChat, Name: "Family"},
+		{ID: 9, Kind: peerChannel, Name: "News", Username: "news"},
+	} {
+		if got[want.ID] != want {
+			t.Errorf("info %d = %+v, want %+v", want.ID, got[want.ID], want)
+		}
+	}
+	if kind, ok := m.store.Kind(9); !ok || kind != peercache.KindChannel {
+		t.Errorf("Kind(9) = %q, %v; access hash not cached", kind, ok)
+	}
+}
+
+func TestPeerCacheSharedAndFlushed(t *testing.T) {
+	configPath, _ := newTestConfig(t)
+	a := &app{configPath: configPath}
+	st := &accountState{label: defaultAccount}
+
+	// Nothing opened: flushing is a no-op.
+	if err := st.flushPeers(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := a.peerCache(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := a.peerCache(st); again != store {
+		t.Fatal("peerCache opened a second storage for the same account")
+	}
+	if err := store.SaveInfo(peercache.Info{ID: 1, Kind: peerUser, Name: "Pavel"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.flushPeers(); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh, err := a.peerCache(&accountState{label: defaultAccount})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if infos := fresh.Infos(); len(infos) != 1 || infos[0].Name != "Pavel" {
+		t.Fatalf("Infos() after flush = %+v", infos)
+	}
+}
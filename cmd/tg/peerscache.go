@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/gotd/cli/internal/output"
+	"github.com/gotd/cli/internal/peercache"
+)
+
+// cachedPeer is one entry of the local peer cache.
+type cachedPeer struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"`
+	Name     string `json:"name,omitempty"`
+	Username string `json:"username,omitempty"`
+	// LastSeen is the unix time of the peer's latest dialog activity seen by
+	// `tg chats list`, if any.
+	LastSeen int64 `json:"last_seen,omitempty"`
+	// AccessHash reports whether the access hash is cached, i.e. whether
+	// "id:<n>" resolves without a lookup.
+	AccessHash bool `json:"access_hash"`
+}
+
+func newCachedPeer(e peercache.Entry) cachedPeer {
+	return cachedPeer{
+		ID:         e.ID,
+		Type:       e.Kind,
+		Name:       e.Name,
+		Username:   e.Username,
+		LastSeen:   e.LastSeen,
+		AccessHash: e.HasAccessHash,
+	}
+}
+
+// ref returns the peer as a peerRef, for labels.
+func (p cachedPeer) ref() peerRef {
+	return peerRef{ID: p.ID, Type: p.Type, Name: p.Name, Username: p.Username}
+}
+
+// cachedPeersResult is the result of `tg peers list|search`.
+type cachedPeersResult struct {
+	Peers []cachedPeer `json:"peers"`
+}
+
+// Items implements output.ItemLister.
+func (r cachedPeersResult) Items() []any { return output.Items(r.Peers) }
+
+// Table implements output.Tabler.
+func (r cachedPeersResult) Table() output.Table { return output.NewTable(cachedPeerColumns(), r.Peers) }
+
+// cachedPeerColumns are the tabular columns of the peer cache.
+func cachedPeerColumns() []output.Column[cachedPeer] {
+	return []output.Column[cachedPeer]{
+		{Name: "id", Value: func(p cachedPeer) string { return strconv.FormatInt(p.ID, 10) }},
+		{Name: "type", Value: func(p cachedPeer) string { return p.Type }},
+		{Name: "username", Value: func(p cachedPeer) string { return p.Username }},
+		{Name: "name", Width: 40, Value: func(p cachedPeer) string { return p.Name }},
+		{Name: "last_seen", Value: func(p cachedPeer) string { return dateCell(int(p.LastSeen)) }},
+		{Name: "access_hash", Value: func(p cachedPeer) string { return strconv.FormatBool(p.AccessHash) }},
+	}
+}
+
+// Table implements output.Tabler, rendering a single cached peer.
+func (p cachedPeer) Table() output.Table {
+	return output.NewTable(cachedPeerColumns(), []cachedPeer{p})
+}
+
+// peersRemovedResult is the result of `tg peers forget|prune`.
+type peersRemovedResult struct {
+	Removed int          `json:"removed"`
+	DryRun  bool         `json:"dry_run,omitempty"`
+	Peers   []cachedPeer `json:"peers"`
+}
+
+// MarshalText renders a summary plus the affected peers.
+func (r peersRemovedResult) MarshalText(w io.Writer) error {
+	verb := "removed"
+	if r.DryRun {
+		verb = "would remove"
+	}
+	if _, err := fmt.Fprintf(w, "%s %d peers\n", verb, r.Removed); err != nil {
+		return err
+	}
+	for _, p := range r.Peers {
+		if _, err := fmt.Fprintf(w, "  %s\t%s\tid=%d\n", p.Type, p.ref().label(), p.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// peersTransferResult is the result of `tg peers export <file>|import`.
+type peersTransferResult struct {
+	Path  string `json:"path"`
+	Peers int    `json:"peers"`
+}
+
+// MarshalText renders a short summary.
+func (r peersTransferResult) MarshalText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%d peers\t%s\n", r.Peers, r.Path)
+	return err
+}
+
+// matchCachedPeer reports whether a cache entry is the peer named by arg:
+// "id:<n>" or a bare id, or "@username" / "username" (case-insensitive).
+func matchCachedPeer(e peercache.Entry, arg string) bool {
+	arg = strings.TrimSpace(arg)
+	if id, err := strconv.ParseInt(strings.TrimPrefix(arg, peerIDPrefix), 10, 64); err == nil {
+		return e.ID == id
+	}
+	name := strings.TrimPrefix(arg, "@")
+	return e.Username != "" && strings.EqualFold(e.Username, name)
+}
+
+// searchCachedPeer reports whether q occurs in the entry's name or username,
+// ignoring case.
+func searchCachedPeer(e peercache.Entry, q string) bool {
+	q = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(q), "@"))
+	return strings.Contains(strings.ToLower(e.Name), q) || strings.Contains(strings.ToLower(e.Username), q)
+}
+
+// filterPeers returns the cache entries matching keep.
+func filterPeers(entries []peercache.Entry, keep func(peercache.Entry) bool) []cachedPeer {
+	out := []cachedPeer{}
+	for _, e := range entries {
+		if keep(e) {
+			out = append(out, newCachedPeer(e))
+		}
+	}
+	return out
+}
+
+// openPeerCache opens the selected account's peer cache. The peers commands
+// never touch the network.
+func (a *app) openPeerCache() (*peercache.Storage, error) {
+	if err := a.ensureActive(); err != nil {
+		return nil, err
+	}
+	return a.peerCache(a.active)
+}
+
+// cachedPeerCompletion completes every positional argument from the cache.
+func cachedPeerCompletion(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return peerCandidates(cmd, toComplete), peerCompDirective
+}
+
+func (a *app) newPeersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "peers",
+		Short:   "Inspect and maintain the local peer cache",
+		GroupID: groupChats,
+		Long: `Inspect and maintain the local peer cache: the access hashes, names and
+usernames tg stores per account so peers resolve without a lookup (and so
+"id:<n>" works at all). These commands read and write the cache only; they never
+contact Telegram.
+
+The cache fills as you use tg: "tg chats list" stores every listed dialog,
+"tg contacts list" every contact, and any command stores the peers it resolves.`,
+	}
+	cmd.AddCommand(
+		a.newPeersListCmd(),
+		a.newPeersSearchCmd(),
+		a.newPeersShowCmd(),
+		a.newPeersForgetCmd(),
+		a.newPeersPruneCmd(),
+		a.newPeersExportCmd(),
+		a.newPeersImportCmd(),
+	)
+	return cmd
+}
+
+func (a *app) newPeersListCmd() *cobra.Command {
+	var kind string
+	cmd := &cobra.Command{
+		Use:   cmdList,
+		Short: "List cached peers, most recently active first",
+		Example: `  tg peers list
+  tg peers list --type channel -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			store, err := a.openPeerCache()
+			if err != nil {
+				return err
+			}
+			return a.printer.Emit(cachedPeersResult{Peers: filterPeers(store.Entries(), func(e peercache.Entry) bool {
+				return kind == "" || e.Kind == kind
+			})})
+		},
+	}
+	cmd.Flags().StringVar(&kind, "type", "", "only list peers of this type: user, chat or channel")
+	registerEnumCompletion(cmd, "type", []string{peerUser, peerChat, peerChannel})
+	return cmd
+}
+
+func (a *app) newPeersSearchCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "search <query>",
+		Short:   "Search cached peers by name or username",
+		Example: `  tg peers search durov`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			store, err := a.openPeerCache()
+			if err != nil {
+				return err
+			}
+			return a.printer.Emit(cachedPeersResult{Peers: filterPeers(store.Entries(), func(e peercache.Entry) bool {
+				return searchCachedPeer(e, args[0])
+			})})
+		},
+		ValidArgsFunction: noFileComp,
+	}
+}
+
+func (a *app) newPeersShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <peer>",
+		Short: "Show what the cache knows about a peer",
+		Long: `Show a cached peer by "id:<n>" (or a bare id) or "@username". Exits with the
+peer_not_found code if the peer is not cached.`,
+		Example: `  tg peers show @durov
+  tg peers show id:2201861038`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			store, err := a.openPeerCache()
+			if err != nil {
+				return err
+			}
+			for _, e := range store.Entries() {
+				if matchCachedPeer(e, args[0]) {
+					return a.printer.Emit(newCachedPeer(e))
+				}
+			}
+			return withClass(classPeer, errors.Errorf("peer %q is not in the cache", args[0]))
+		},
+		ValidArgsFunction: peerArgCompletion,
+	}
+}
+
+func (a *app) newPeersForgetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "forget <peer>...",
+		Short: "Remove peers from the cache",
+		Long: `Remove peers, by "id:<n>" or "@username", from the cache: access hash, name
+and phone number. The next command that needs one resolves it again.`,
+		Example: `  tg peers forget @old_username id:2201861038`,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			store, err := a.openPeerCache()
+			if err != nil {
+				return err
+			}
+			for _, arg := range args {
+				found := false
+				for _, e := range store.Entries() {
+					if matchCachedPeer(e, arg) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					return withClass(classPeer, errors.Errorf("peer %q is not in the cache", arg))
+				}
+			}
+			removed, err := store.Remove(func(e peercache.Entry) bool {
+				for _, arg := range args {
+					if matchCachedPeer(e, arg) {
+						return true
+					}
+				}
+				return false
+			})
+			if err != nil {
+				return err
+			}
+			return a.printer.Emit(removedResult(removed, false))
+		},
+		ValidArgsFunction: cachedPeerCompletion,
+	}
+}
+
+// removedResult builds the result of a forget/prune.
+func removedResult(entries []peercache.Entry, dryRun bool) peersRemovedResult {
+	res := peersRemovedResult{Removed: len(entries), DryRun: dryRun, Peers: []cachedPeer{}}
+	for _, e := range entries {
+		res.Peers = append(res.Peers, newCachedPeer(e))
+	}
+	return res
+}
+
+func (a *app) newPeersPruneCmd() *cobra.Command {
+	var (
+		olderThan time.Duration
+		unseen    bool
+		noHash    bool
+		dryRun    bool
+	)
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Drop stale entries from the cache",
+		Long: `Drop cache entries matching any of the given criteria: peers whose last dialog
+activity is older than --older-than, peers never seen in "tg chats list"
+(--unseen), or peers whose access hash is not cached (--no-access-hash).`,
+		Example: `  tg peers prune --older-than 2160h --dry-run   # inactive for 90 days
+  tg peers prune --unseen`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if olderThan <= 0 && !unseen && !noHash {
+				return withClass(classUsage, errors.New("give --older-than, --unseen or --no-access-hash"))
+			}
+			store, err := a.openPeerCache()
+			if err != nil {
+				return err
+			}
+			cutoff := time.Now().Add(-olderThan).Unix()
+			stale := func(e peercache.Entry) bool {
+				switch {
+				case olderThan > 0 && e.LastSeen != 0 && e.LastSeen < cutoff:
+					return true
+				case unseen && e.LastSeen == 0:
+					return true
+				case noHash && !e.HasAccessHash:
+					return true
+				default:
+					return false
+				}
+			}
+			if dryRun {
+				var matched []peercache.Entry
+				for _, e := range store.Entries() {
+					if stale(e) {
+						matched = append(matched, e)
+					}
+				}
+				return a.printer.Emit(removedResult(matched, true))
+			}
+			removed, err := store.Remove(stale)
+			if err != nil {
+				return err
+			}
+			return a.printer.Emit(removedResult(removed, false))
+		},
+	}
+	fs := cmd.Flags()
+	fs.DurationVar(&olderThan, "older-than", 0, "drop peers whose last dialog activity is older than this")
+	fs.BoolVar(&unseen, "unseen", false, "drop peers with no recorded dialog activity")
+	fs.BoolVar(&noHash, "no-access-hash", false, "drop peers whose access hash is not cached")
+	fs.BoolVar(&dryRun, "dry-run", false, "only report what would be removed")
+	return cmd
+}
+
+func (a *app) newPeersExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [file]",
+		Short: "Export the peer cache as JSON",
+		Long: `Export the whole peer cache as JSON, to a file or (without one) to stdout, for
+"tg peers import" on another machine. Access hashes are tied to the account, so
+import the export into the same account there.`,
+		Example: `  tg peers export peers.json
+  tg peers export | ssh host tg peers import -`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := a.openPeerCache()
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				return store.Export(cmd.OutOrStdout())
+			}
+			f, err := os.OpenFile(args[0], os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600) // #nosec G304 // user-provided path
+			if err != nil {
+				return errors.Wrap(err, "create export file")
+			}
+			if err := store.Export(f); err != nil {
+				_ = f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return errors.Wrap(err, "write export file")
+			}
+			return a.printer.Emit(peersTransferResult{Path: args[0], Peers: len(store.Entries())})
+		},
+	}
+	return cmd
+}
+
+func (a *app) newPeersImportCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "import <file>",
+		Short: "Merge an exported peer cache into this account's cache",
+		Long: `Merge a "tg peers export" file ("-" for stdin) into the selected account's
+cache. Imported access hashes replace cached ones; names and usernames are merged.`,
+		Example: `  tg peers import peers.json
+  tg peers import --account work - < peers.json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := a.openPeerCache()
+			if err != nil {
+				return err
+			}
+			r := cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0]) // #nosec G304 // user-provided path
+				if err != nil {
+					return errors.Wrap(err, "open import file")
+				}
+				defer func() { _ = f.Close() }()
+				r = f
+			}
+			n, err := store.Import(r)
+			if err != nil {
+				return err
+			}
+			return a.printer.Emit(peersTransferResult{Path: args[0], Peers: n})
+		},
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/gotd/td/telegram/peers"
+
+	"github.com/gotd/cli/internal/peercache"
+)
+
+func TestPeersCommands(t *testing.T) {
+	configPath, store := newTestConfig(t)
+	if err := store.Save(context.Background(), peers.Key{Prefix: "users_", ID: 1}, peers.Value{AccessHash: 11}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveInfo(
+		peercache.Info{ID: 1, Kind: peerUser, Name: "Pavel Durov", Username: "durov", LastSeen: 100},
+		peercache.Info{ID: 2, Kind: peerChannel, Name: "News"},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) string {
+		t.Helper()
+		out, err := runRoot(t, append([]string{"--config", configPath, "-o", "json"}, args...)...)
+		if err != nil {
+			t.Fatalf("tg %v: %v", args, err)
+		}
+		return out
+	}
+	decode := func(raw string, v any) {
+		t.Helper()
+		env := struct{ Data any }{Data: v}
+		if err := json.Unmarshal([]byte(raw), &env); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var list cachedPeersResult
+	decode(run("peers", "list"), &list)
+	want := []cachedPeer{
+		{ID: 1, Type: peerUser, Name: "Pavel Durov", Username: "durov", LastSeen: 100, AccessHash: true},
+		{ID: 2, Type: peerChannel, Name: "News"},
+	}
+	if len(list.Peers) != 2 || list.Peers[0] != want[0] || list.Peers[1] != want[1] {
+		t.Errorf("peers list = %+v, want %+v", list.Peers, want)
+	}
+
+	var found cachedPeersResult
+	decode(run("peers", "search", "NEW"), &found)
+	if len(found.Peers) != 1 || found.Peers[0].ID != 2 {
+		t.Errorf("peers search = %+v", found.Peers)
+	}
+
+	var shown cachedPeer
+	decode(run("peers", "show", "@Durov"), &shown)
+	if shown != want[0] {
+		t.Errorf("peers show = %+v", shown)
+	}
+	if _, err := runRoot(t, "--config", configPath, "peers", "show", "id:99"); classify(err) != classPeer {
+		t.Errorf("peers show id:99: %v (class %q), want peer_not_found", err, classify(err))
+	}
+
+	var pruned peersRemovedResult
+	decode(run("peers", "prune", "--unseen", "--dry-run"), &pruned)
+	if pruned.Removed != 1 || !pruned.DryRun || pruned.Peers[0].ID != 2 {
+		t.Errorf("peers prune --dry-run = %+v", pruned)
+	}
+
+	export := filepath.Join(t.TempDir(), "peers.json")
+	run("peers", "export", export)
+
+	var forgot peersRemovedResult
+	decode(run("peers", "forget", "id:1"), &forgot)
+	if forgot.Removed != 1 {
+		t.Errorf("peers forget = %+v", forgot)
+	}
+
+	var imported peersTransferResult
+	decode(run("peers", "import", export), &imported)
+	if imported.Peers != 2 {
+		t.Errorf("peers import = %+v", imported)
+	}
+	decode(run("peers", "list"), &list)
+	if len(list.Peers) != 2 || !list.Peers[0].AccessHash {
+		t.Errorf("peers list after import = %+v", list.Peers)
+	}
+}
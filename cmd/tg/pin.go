@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"strconv"
 
 	"github.com/go-faster/errors"
 	"github.com/spf13/cobra"
@@ -46,29 +45,46 @@ func (a *app) pinCmd(use, short string, unpin bool) *cobra.Command {
 	)
 
 	cmd := &cobra.Command{
-		Use:               use + " <peer> <message-id>",
-		Short:             short,
-		GroupID:           groupMessaging,
-		Args:              cobra.ExactArgs(2),
+		Use:     use + " <peer> <message-id|selector>...",
+		Short:   short,
+		GroupID: groupMessaging,
+		Long: short + ` by id or t.me message link. Several messages may be given by id
+or by the selectors of "tg delete" (100-200, last:N, since:DATE, from:PEER, -
+for stdin); they are handled oldest first.`,
+		Example:           "  tg " + use + " @group 42\n  tg " + use + " https://t.me/durov/42\n  tg " + use + " @group last:3 from:me",
+		Args:              messageSelectArgs,
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			id, err := strconv.Atoi(args[1])
+			peerArg, selectors := splitMessageSelect(args)
+			sel, err := parseSelection(selectors, cmd.InOrStdin())
 			if err != nil {
-				return errors.Wrap(err, "message-id must be an integer")
+				return err
 			}
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
-				peer, err := resolvePeer(ctx, m, args[0])
+				peer, err := resolvePeer(ctx, m, peerArg)
 				if err != nil {
 					return err
 				}
-				if err := updatePin(ctx, api, peer, id, unpin, silent, oneside); err != nil {
+				ids, err := resolveSelection(ctx, api, m, peer, sel)
+				if err != nil {
 					return err
 				}
-				return a.printer.Emit(pinResult{OK: true})
+				if len(ids) == 0 {
+					return withClass(classUsage, errors.New("no messages match"))
+				}
+				if !sel.literal() {
+					ids = ascending(ids)
+				}
+				for _, id := range ids {
+					if err := updatePin(ctx, api, peer, id, unpin, silent, oneside); err != nil {
+						return err
+					}
+				}
+				return a.out(ctx).Emit(pinResult{OK: true})
 			})
 		},
 	}
@@ -104,7 +120,7 @@ func (a *app) newUnpinAllCmd() *cobra.Command {
 				return errors.New("refusing to unpin all without --yes")
 			}
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -115,7 +131,7 @@ func (a *app) newUnpinAllCmd() *cobra.Command {
 				if _, err := api.MessagesUnpinAllMessages(ctx, &tg.MessagesUnpinAllMessagesRequest{Peer: peer}); err != nil {
 					return errors.Wrap(err, "messages.unpinAllMessages")
 				}
-				return a.printer.Emit(pinResult{OK: true})
+				return a.out(ctx).Emit(pinResult{OK: true})
 			})
 		},
 	}
@@ -137,7 +153,7 @@ func (a *app) newPinnedCmd() *cobra.Command {
 		Example:           "  tg pinned @durov\n  tg pinned me --output json",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -149,7 +165,7 @@ func (a *app) newPinnedCmd() *cobra.Command {
 				if err != nil {
 					return err
 				}
-				return a.printer.Emit(res)
+				return a.out(ctx).Emit(res)
 			})
 		},
 	}
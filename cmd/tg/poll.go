@@ -48,7 +48,7 @@ poll is single-choice and anonymous.`,
 			}
 
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				sender, m, err := a.sender(api)
+				sender, m, err := a.sender(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -72,7 +72,7 @@ poll is single-choice and anonymous.`,
 				if err != nil {
 					return errors.Wrap(err, "create poll")
 				}
-				return a.printer.Emit(sentResult{Peer: peer, MessageID: id})
+				return a.out(ctx).Emit(sentResult{Peer: peer, MessageID: id})
 			})
 		},
 	}
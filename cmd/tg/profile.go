@@ -52,7 +52,7 @@ func (a *app) newProfileUpdateCmd() *cobra.Command {
 				if _, err := api.AccountUpdateProfile(ctx, req); err != nil {
 					return errors.Wrap(err, "account.updateProfile")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -79,7 +79,7 @@ func (a *app) newProfileSetPhotoCmd() *cobra.Command {
 				if _, err := api.PhotosUploadProfilePhoto(ctx, &tg.PhotosUploadProfilePhotoRequest{File: file}); err != nil {
 					return errors.Wrap(err, "photos.uploadProfilePhoto")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -115,7 +115,7 @@ func (a *app) newProfileDeletePhotoCmd() *cobra.Command {
 				}}); err != nil {
 					return errors.Wrap(err, "photos.deletePhotos")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -135,7 +135,7 @@ func (a *app) newProfileStatusCmd() *cobra.Command {
 				if _, err := api.AccountUpdateStatus(ctx, offline); err != nil {
 					return errors.Wrap(err, "account.updateStatus")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
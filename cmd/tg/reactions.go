@@ -11,6 +11,8 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/output"
 )
 
 // reactionItem is one reaction with its count.
@@ -25,6 +27,9 @@ type reactionsResult struct {
 	Reactions []reactionItem `json:"reactions"`
 }
 
+// Items implements output.ItemLister.
+func (r reactionsResult) Items() []any { return output.Items(r.Reactions) }
+
 // MarshalText renders reactions on one line.
 func (r reactionsResult) MarshalText(w io.Writer) error {
 	if len(r.Reactions) == 0 {
@@ -72,40 +77,40 @@ func sendReaction(ctx context.Context, api *tg.Client, peer tg.InputPeerClass, i
 }
 
 func (a *app) reactCmd(use, short string, remove bool) *cobra.Command {
-	minArgs := 3
+	extraArgs := 1
 	if remove {
-		minArgs = 2
+		extraArgs = 0
 	}
 
 	cmd := &cobra.Command{
 		Use:               use,
 		Short:             short,
 		GroupID:           groupMessaging,
-		Args:              cobra.ExactArgs(minArgs),
+		Args:              messageRefArgs(extraArgs),
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			id, err := strconv.Atoi(args[1])
+			ref, rest, err := splitMessageRef(args)
 			if err != nil {
-				return errors.Wrap(err, "message-id must be an integer")
+				return err
 			}
 			emoji := ""
 			if !remove {
-				emoji = args[2]
+				emoji = rest[0]
 			}
 
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
-				peer, err := resolvePeer(ctx, m, args[0])
+				peer, err := resolvePeer(ctx, m, ref.Peer)
 				if err != nil {
 					return err
 				}
-				if err := sendReaction(ctx, api, peer, id, emoji); err != nil {
+				if err := sendReaction(ctx, api, peer, ref.ID, emoji); err != nil {
 					return err
 				}
-				return a.printer.Emit(pinResult{OK: true})
+				return a.out(ctx).Emit(pinResult{OK: true})
 			})
 		},
 	}
@@ -125,23 +130,23 @@ func (a *app) newReactionsCmd() *cobra.Command {
 		Use:               "reactions <peer> <message-id>",
 		Short:             "Show reactions on a message",
 		GroupID:           groupMessaging,
-		Args:              cobra.ExactArgs(2),
+		Args:              messageRefArgs(0),
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			id, err := strconv.Atoi(args[1])
+			ref, _, err := splitMessageRef(args)
 			if err != nil {
-				return errors.Wrap(err, "message-id must be an integer")
+				return err
 			}
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
-				peer, err := resolvePeer(ctx, m, args[0])
+				peer, err := resolvePeer(ctx, m, ref.Peer)
 				if err != nil {
 					return err
 				}
-				msg, err := getMessage(ctx, api, peer, id)
+				msg, err := getMessage(ctx, api, peer, ref.ID)
 				if err != nil {
 					return err
 				}
@@ -157,7 +162,7 @@ func (a *app) newReactionsCmd() *cobra.Command {
 						})
 					}
 				}
-				return a.printer.Emit(res)
+				return a.out(ctx).Emit(res)
 			})
 		},
 	}
@@ -55,7 +55,7 @@ me/self, @username, phone, or a t.me link.`,
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -66,7 +66,7 @@ me/self, @username, phone, or a t.me link.`,
 				if err := markRead(ctx, api, peer); err != nil {
 					return err
 				}
-				return a.printer.Emit(readResult{OK: true})
+				return a.out(ctx).Emit(readResult{OK: true})
 			})
 		},
 	}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"strconv"
 
 	"github.com/go-faster/errors"
 	"github.com/spf13/cobra"
@@ -21,19 +20,19 @@ func (a *app) newReplyCmd() *cobra.Command {
 		Long: `Send a reply to a specific message in a peer's history. The peer is
 me/self, @username, phone, or a t.me link.`,
 		Example: `  tg reply @durov 12345 "great post"
-  tg reply me 1000 "note to self"`,
-		Args:              cobra.ExactArgs(3),
+  tg reply me 1000 "note to self"
+  tg reply https://t.me/somegroup/42 "agreed"`,
+		Args:              messageRefArgs(1),
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			peer := args[0]
-			replyTo, err := strconv.Atoi(args[1])
+			ref, rest, err := splitMessageRef(args)
 			if err != nil {
-				return errors.Wrap(err, "message-id must be an integer")
+				return err
 			}
-			text := args[2]
+			peer, replyTo, text := ref.Peer, ref.ID, rest[0]
 
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				sender, m, err := a.sender(api)
+				sender, m, err := a.sender(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -47,7 +46,7 @@ me/self, @username, phone, or a t.me link.`,
 				if err != nil {
 					return errors.Wrap(err, "reply")
 				}
-				return a.printer.Emit(sentResult{Peer: peer, MessageID: id})
+				return a.out(ctx).Emit(sentResult{Peer: peer, MessageID: id})
 			})
 		},
 	}
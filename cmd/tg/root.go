@@ -66,22 +66,32 @@ below apply to every command.`,
 	}
 
 	root.SetGlobalNormalizationFunc(normalizeFlag)
+	root.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return withClass(classUsage, err)
+	})
 
 	pf := root.PersistentFlags()
 	pf.StringVarP(&a.configPath, "config", "c", defaultConfigPath(), "config file to use")
-	pf.StringVarP(&a.outputFormat, "output", "o", string(output.Text), "output format: text or json")
+	pf.StringVarP(&a.outputFormat, "output", "o", string(output.Text), "output format: text, json, jsonl, csv or tsv")
+	pf.StringVar(&a.templateText, "template", "",
+		"render the result through a Go text/template, e.g. '{{range .Chats}}{{peer .Peer}}{{\"\\n\"}}{{end}}'")
+	pf.StringVar(&a.templateFile, "template-file", "", "read the --template from a file")
+	_ = root.MarkPersistentFlagFilename("template-file")
+	root.MarkFlagsMutuallyExclusive("template", "template-file")
 	pf.StringVar(&a.proxyURL, "proxy", os.Getenv("TG_PROXY"),
 		"proxy URL: socks5:// or tg://proxy?... (overrides config)")
 	pf.StringVarP(&a.accountFlag, "account", "a", os.Getenv("TG_ACCOUNT"),
 		"account label to use, or 'all' to fan out (default: the default account)")
 	_ = root.RegisterFlagCompletionFunc("account",
 		func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
-			cfg, err := loadConfig(a.configPath)
+			cfg, err := peekConfig(a.configPath)
 			if err != nil {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
 			return append(cfg.labels(), "all"), cobra.ShellCompDirectiveNoFileComp
 		})
+	pf.DurationVar(&a.maxFloodWait, "max-flood-wait", defaultMaxFloodWait,
+		"longest FLOOD_WAIT to wait out per request before failing with exit code 5 (0: never wait; overrides config)")
 	pf.BoolVar(&a.debugInvoker, "debug-invoker", false, "use pretty-printing debug invoker")
 	_ = root.RegisterFlagCompletionFunc("output",
 		cobra.FixedCompletions(output.Formats(), cobra.ShellCompDirectiveNoFileComp))
@@ -99,6 +109,9 @@ below apply to every command.`,
 		a.newLogoutCmd(),
 		a.newAccountsCmd(),
 		a.newWhoamiCmd(),
+		a.newSessionCmd(),
+		a.newDoctorCmd(),
+		a.newConfigCmd(),
 		a.newDevicesCmd(),
 		a.newChatsCmd(),
 		a.newHistoryCmd(),
@@ -135,6 +148,8 @@ below apply to every command.`,
 		a.newUnarchiveCmd(),
 		a.newChatCmd(),
 		a.newResolveCmd(),
+		a.newPeersCmd(),
+		a.newAliasCmd(),
 		a.newSearchPublicCmd(),
 		a.newSubscribeCmd(),
 		a.newContactsCmd(),
@@ -159,6 +174,7 @@ below apply to every command.`,
 		a.newRecentActionsCmd(),
 		a.newProfileCmd(),
 		a.newFoldersCmd(),
+		a.newSchemaCmd(),
 	)
 	root.AddCommand(newDocsCmd(root))
 
@@ -43,7 +43,7 @@ func (a *app) newScheduleSendCmd() *cobra.Command {
 				return err
 			}
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				sender, m, err := a.sender(api)
+				sender, m, err := a.sender(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -57,7 +57,7 @@ func (a *app) newScheduleSendCmd() *cobra.Command {
 				if err != nil {
 					return errors.Wrap(err, "schedule send")
 				}
-				return a.printer.Emit(sentResult{Peer: args[0], MessageID: id})
+				return a.out(ctx).Emit(sentResult{Peer: args[0], MessageID: id})
 			})
 		},
 	}
@@ -93,7 +93,7 @@ func (a *app) newScheduleListCmd() *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -109,26 +109,78 @@ func (a *app) newScheduleListCmd() *cobra.Command {
 				if err != nil {
 					return err
 				}
-				return a.printer.Emit(messagesToHistory(msgs, ent))
+				return a.out(ctx).Emit(messagesToHistory(msgs, ent))
 			})
 		},
 	}
 	return cmd
 }
 
+// deleteScheduled deletes the scheduled messages of peer that sel picks.
+// Without yes it deletes nothing and reports how many match; with dryRun it
+// lists them.
+func deleteScheduled(ctx context.Context, api *tg.Client, m *peerManager, peer tg.InputPeerClass, sel msgSelection, yes, dryRun bool) (deletedResult, error) {
+	if err := sel.checkChats(ctx, m, peer); err != nil {
+		return deletedResult{}, err
+	}
+	ids := sel.ids
+	if !sel.literal() {
+		res, err := api.MessagesGetScheduledHistory(ctx, &tg.MessagesGetScheduledHistoryRequest{Peer: peer})
+		if err != nil {
+			return deletedResult{}, errors.Wrap(err, "messages.getScheduledHistory")
+		}
+		msgs, _, err := messagesFrom(res)
+		if err != nil {
+			return deletedResult{}, err
+		}
+		if ids, err = selectMessages(ctx, m, msgs, sel); err != nil {
+			return deletedResult{}, err
+		}
+	}
+	switch {
+	case dryRun:
+		return deletedResult{Count: len(ids), DryRun: true, IDs: ascending(ids)}, nil
+	case !yes:
+		return deletedResult{}, errNeedYes("delete", len(ids))
+	case len(ids) == 0:
+		return deletedResult{}, nil
+	}
+	if _, err := api.MessagesDeleteScheduledMessages(ctx, &tg.MessagesDeleteScheduledMessagesRequest{
+		Peer: peer,
+		ID:   ids,
+	}); err != nil {
+		return deletedResult{}, errors.Wrap(err, "messages.deleteScheduledMessages")
+	}
+	return deletedResult{Count: len(ids)}, nil
+}
+
 func (a *app) newScheduleDeleteCmd() *cobra.Command {
+	var (
+		yes    bool
+		dryRun bool
+	)
+
 	cmd := &cobra.Command{
-		Use:               "delete <peer> <message-id>...",
-		Short:             "Delete scheduled messages",
+		Use:   "delete <peer> <message-id|selector>...",
+		Short: "Delete scheduled messages",
+		Long: `Delete scheduled messages by id or by the selectors of "tg delete", matched
+against the chat's scheduled messages (since: compares the scheduled time).
+Destructive: requires --yes; without it the number of matching messages is
+reported, and --dry-run lists them.`,
+		Example: `  tg schedule delete me 5 6 --yes
+  tg schedule delete @channel since:2027-01-01 --dry-run`,
 		Args:              cobra.MinimumNArgs(2),
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ids, err := parseIDs(args[1:])
+			sel, err := parseSelection(args[1:], cmd.InOrStdin())
 			if err != nil {
 				return err
 			}
+			if !yes && !dryRun && sel.literal() {
+				return errNeedYes("delete", len(sel.ids))
+			}
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -136,15 +188,18 @@ func (a *app) newScheduleDeleteCmd() *cobra.Command {
 				if err != nil {
 					return err
 				}
-				if _, err := api.MessagesDeleteScheduledMessages(ctx, &tg.MessagesDeleteScheduledMessagesRequest{
-					Peer: peer,
-					ID:   ids,
-				}); err != nil {
-					return errors.Wrap(err, "messages.deleteScheduledMessages")
+				res, err := deleteScheduled(ctx, api, m, peer, sel, yes, dryRun)
+				if err != nil {
+					return err
 				}
-				return a.printer.Emit(deletedResult{Count: len(ids)})
+				return a.out(ctx).Emit(res)
 			})
 		},
 	}
+
+	fs := cmd.Flags()
+	fs.BoolVar(&yes, "yes", false, "confirm deletion")
+	fs.BoolVar(&dryRun, "dry-run", false, "list the selected messages without deleting them")
+
 	return cmd
 }
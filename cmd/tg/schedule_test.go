@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+)
+
+func TestDeleteScheduled(t *testing.T) {
+	var deleted []int
+	api := newFuncAPI(t, func(req bin.Encoder) (bin.Encoder, error) {
+		switch r := req.(type) {
+		case *tg.MessagesGetScheduledHistoryRequest:
+			return &tg.MessagesMessages{Messages: []tg.MessageClass{
+				&tg.Message{ID: 1, Date: 1 * 86400, PeerID: &tg.PeerUser{UserID: 1}},
+				&tg.Message{ID: 2, Date: 9 * 86400, PeerID: &tg.PeerUser{UserID: 1}},
+			}}, nil
+		case *tg.MessagesDeleteScheduledMessagesRequest:
+			deleted = append(deleted, r.ID...)
+			return &tg.Updates{}, nil
+		}
+		return nil, errors.Errorf("unexpected request %T", req)
+	})
+	ctx := context.Background()
+	peer := &tg.InputPeerSelf{}
+	sel := msgSelection{since: time.Unix(5*86400, 0)}
+
+	// A selector without --yes only counts.
+	if _, err := deleteScheduled(ctx, api, nil, peer, sel, false, false); err == nil || classify(err) != classUsage {
+		t.Errorf("without --yes: err = %v", err)
+	}
+	res, err := deleteScheduled(ctx, api, nil, peer, sel, false, true)
+	if err != nil || !res.DryRun || !slices.Equal(res.IDs, []int{2}) {
+		t.Errorf("--dry-run = %+v, %v", res, err)
+	}
+	if deleted != nil {
+		t.Fatalf("deleted %v without --yes", deleted)
+	}
+
+	if res, err = deleteScheduled(ctx, api, nil, peer, sel, true, false); err != nil || res.Count != 1 {
+		t.Errorf("--yes = %+v, %v", res, err)
+	}
+	if !slices.Equal(deleted, []int{2}) {
+		t.Errorf("deleted %v, want [2]", deleted)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/gotd/cli/internal/jsonschema"
+	"github.com/gotd/cli/internal/output"
+)
+
+// resultTypes maps each command path (without the leading "tg") to the result
+// type(s) it emits as envelope data. Commands that can emit more than one shape
+// list every alternative, and streaming commands that write bare lines mark
+// their line type with bareLine. Keep in sync with the commands' Emit calls;
+// the schema golden test fails when a command is missing.
+func resultTypes() map[string][]any {
+	ok := []any{okResult{}}
+	peers := []any{peerListResult{}}
+	sent := []any{sentResult{}}
+	history := []any{historyResult{}}
+	return map[string][]any{
+		"accounts":             {accountsResult{}},
+		"admins":               peers,
+		"album":                sent,
+		"alias add":            ok,
+		"alias list":           {aliasesResult{}},
+		"alias rm":             ok,
+		"archive":              ok,
+		"ban":                  ok,
+		"banned":               peers,
+		"chat full":            {chatFullResult{}},
+		"chat get":             {chatInfo{}},
+		"chats list":           {chatList{}},
+		"config edit":          ok,
+		"config get":           {configValueResult{}},
+		"config migrate":       {configMigrateResult{}},
+		"config path":          {configPathResult{}},
+		"config set":           ok,
+		"config unset":         ok,
+		"config validate":      ok,
+		"contacts add":         peers,
+		"contacts block":       ok,
+		"contacts blocked":     peers,
+		"contacts delete":      ok,
+		"contacts import":      peers,
+		"contacts list":        peers,
+		"contacts search":      peers,
+		"contacts unblock":     ok,
+		"context":              history,
+		"create-channel":       {peerRef{}, okResult{}},
+		"create-group":         {peerRef{}, okResult{}},
+		"delete":               {deletedResult{}},
+		"delete-history":       {deletedResult{}},
+		"demote":               ok,
+		"devices":              {devicesResult{}},
+		"devices terminate":    ok,
+		"doctor":               {doctorResult{}},
+		"download":             {downloadResult{}},
+		"draft clear":          {pinResult{}},
+		"draft set":            {pinResult{}},
+		"drafts":               {draftsResult{}},
+		"edit":                 sent,
+		"folders add-chat":     ok,
+		"folders create":       {folderItem{}},
+		"folders delete":       ok,
+		"folders list":         {foldersResult{}},
+		"folders remove-chat":  ok,
+		"folders reorder":      ok,
+		"forward":              sent,
+		"history":              history,
+		"invite":               ok,
+		"invite-link new":      {linkResult{}},
+		"invite-link show":     {linkResult{}, noLinkResult{}},
+		"join-link":            ok,
+		"leave":                ok,
+		"link":                 {linkResult{}},
+		"login":                {whoamiResult{}},
+		"logout":               ok,
+		"mute":                 ok,
+		"participants":         peers,
+		"peers export":         {peersTransferResult{}},
+		"peers forget":         {peersRemovedResult{}},
+		"peers import":         {peersTransferResult{}},
+		"peers list":           {cachedPeersResult{}},
+		"peers prune":          {peersRemovedResult{}},
+		"peers search":         {cachedPeersResult{}},
+		"peers show":           {cachedPeer{}},
+		"pin":                  {pinResult{}},
+		"pinned":               history,
+		"poll create":          sent,
+		"profile delete-photo": ok,
+		"profile set-photo":    ok,
+		"profile status":       ok,
+		"profile update":       ok,
+		"promote":              ok,
+		"react":                {pinResult{}},
+		"reactions":            {reactionsResult{}},
+		"read":                 {readResult{}},
+		"recent-actions":       {recentActionsResult{}},
+		"reply":                sent,
+		"resolve":              {peerRef{}},
+		"schedule delete":      {deletedResult{}},
+		"schedule list":        history,
+		"schedule send":        sent,
+		"schema":               {schemaResult{}},
+		"search":               history,
+		"search-public":        peers,
+		"send":                 sent,
+		"session export":       {sessionExportResult{}},
+		"session import":       {sessionImportResult{}},
+		"set-about":            ok,
+		"set-photo":            ok,
+		"set-title":            ok,
+		"slow-mode":            ok,
+		"stickers":             {stickerSetsResult{}},
+		"subscribe":            ok,
+		"topics create":        ok,
+		"topics enable":        ok,
+		"topics list":          {topicsResult{}},
+		"unarchive":            ok,
+		"unban":                ok,
+		"unmute":               ok,
+		"unpin":                {pinResult{}},
+		"unpin-all":            {pinResult{}},
+		"unreact":              {pinResult{}},
+		"upload":               {uploadResult{}},
+		"wait":                 {watchEvent{}},
+		"watch":                {bareLine{watchLine{}}},
+		"whoami":               {whoamiResult{}},
+	}
+}
+
+// schemaResult is the result of `tg schema`: one JSON Schema per command,
+// describing the full {"schema":N,"data":...} envelope it writes.
+type schemaResult struct {
+	Version  int                           `json:"version"`
+	Commands map[string]*jsonschema.Schema `json:"commands"`
+}
+
+// MarshalText renders the schemas as indented JSON; there is no more useful
+// human form of a schema.
+func (r schemaResult) MarshalText(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// bareLine marks a command whose JSON output is a stream of bare objects of
+// the wrapped type, one per line, instead of envelopes.
+type bareLine struct{ line any }
+
+// commandSchema builds the envelope schema for one command path, or the line
+// schema for a bareLine command.
+func commandSchema(path string, types []any) *jsonschema.Schema {
+	id := "https://github.com/gotd/cli/schema/v" + strconv.Itoa(output.SchemaVersion) + "/" + strings.ReplaceAll(path, " ", "-") + ".json"
+	if l, ok := types[0].(bareLine); ok {
+		s := jsonschema.For(l.line)
+		s.Schema, s.ID, s.Title = jsonschema.Draft, id, "tg "+path
+		s.Description = "one JSON line per event, not wrapped in an envelope"
+		if p := s.Properties["account"]; p != nil {
+			p.Description = "account label, set with --account all"
+		}
+		return s
+	}
+	data := jsonschema.For(types[0])
+	if len(types) > 1 {
+		data = &jsonschema.Schema{}
+		for _, t := range types {
+			data.OneOf = append(data.OneOf, jsonschema.For(t))
+		}
+	}
+	return &jsonschema.Schema{
+		Schema: jsonschema.Draft,
+		ID:     id,
+		Title:  "tg " + path,
+		Type:   "object",
+		Properties: map[string]*jsonschema.Schema{
+			"schema":  {Type: "integer", Const: output.SchemaVersion},
+			"account": {Type: "string", Description: "account label, set with --account all"},
+			"data":    data,
+		},
+		Required: []string{"schema", "data"},
+	}
+}
+
+// buildSchemas returns the schemas for the given command paths, or for every
+// command when paths is empty.
+func buildSchemas(paths []string) (schemaResult, error) {
+	types := resultTypes()
+	if len(paths) == 0 {
+		for p := range types {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+	}
+	res := schemaResult{Version: output.SchemaVersion, Commands: map[string]*jsonschema.Schema{}}
+	for _, p := range paths {
+		t, ok := types[p]
+		if !ok {
+			return schemaResult{}, errors.Errorf("no result schema for command %q", p)
+		}
+		res.Commands[p] = commandSchema(p, t)
+	}
+	return res, nil
+}
+
+func (a *app) newSchemaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema [command]",
+		Short: "Print JSON Schemas for command output",
+		Long: `Print JSON Schema documents describing the {"schema":N,"data":...} envelope
+each command writes with --output json, generated from the result types. With a
+command path (e.g. "chats list"), print only that command's schema. watch
+writes one bare event object per line instead, and its schema describes a line.`,
+		Example: `  tg schema
+  tg schema history
+  tg schema chats list`,
+		GroupID: groupAuth,
+		RunE: func(_ *cobra.Command, args []string) error {
+			var paths []string
+			if len(args) > 0 {
+				paths = []string{strings.Join(args, " ")}
+			}
+			res, err := buildSchemas(paths)
+			if err != nil {
+				return err
+			}
+			return a.printer.Emit(res)
+		},
+		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			var paths []string
+			for p := range resultTypes() {
+				paths = append(paths, p)
+			}
+			sort.Strings(paths)
+			return paths, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoglobals // test flag
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// TestSchemaGolden pins the published output contract: changing a result
+// struct's JSON shape fails here until the golden file is regenerated with
+// `go test ./cmd/tg -run TestSchemaGolden -update`.
+func TestSchemaGolden(t *testing.T) {
+	res, err := buildSchemas(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := res.MarshalText(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "schema.golden.json")
+	if *updateGolden {
+		if err := os.WriteFile(golden, buf.Bytes(), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("schemas differ from %s; rerun with -update if the change is intended", golden)
+	}
+	if !json.Valid(buf.Bytes()) {
+		t.Error("schema output is not valid JSON")
+	}
+}
+
+// TestSchemaCoversCommands checks that every command writing a result has a
+// schema, and that no schema is registered for a command that does not exist.
+func TestSchemaCoversCommands(t *testing.T) {
+	exempt := map[string]bool{
+		"init":             true,
+		"accounts add":     true,
+		"accounts remove":  true,
+		"accounts default": true,
+		"docs":             true,
+	}
+	types := resultTypes()
+	seen := map[string]bool{}
+
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		for _, sub := range c.Commands() {
+			if sub.Name() == "help" || sub.Name() == "completion" {
+				continue
+			}
+			walk(sub)
+		}
+		if !c.Runnable() || !c.HasParent() {
+			return
+		}
+		path := strings.TrimPrefix(c.CommandPath(), c.Root().Name()+" ")
+		seen[path] = true
+		if _, ok := types[path]; !ok && !exempt[path] {
+			t.Errorf("command %q has no result schema", path)
+		}
+	}
+	walk(newRootCmd())
+
+	for path := range types {
+		if !seen[path] {
+			t.Errorf("schema registered for unknown command %q", path)
+		}
+	}
+}
+
+func TestSchemaUnknownCommand(t *testing.T) {
+	if _, err := buildSchemas([]string{"nope"}); err == nil {
+		t.Fatal("expected error for unknown command")
+	}
+}
+
+// TestSchemaWatchLine checks that watch publishes the shape of the bare lines
+// emitLine writes, not an envelope.
+func TestSchemaWatchLine(t *testing.T) {
+	res, err := buildSchemas([]string{"watch"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := res.Commands["watch"].Properties
+	for _, key := range []string{"account", "message", "peer"} {
+		if props[key] == nil {
+			t.Errorf("watch schema lacks %q", key)
+		}
+	}
+	if props["data"] != nil || props["schema"] != nil {
+		t.Error("watch schema describes an envelope")
+	}
+}
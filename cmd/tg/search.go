@@ -94,12 +94,12 @@ the peer is omitted and the query is run across all chats.`,
 					if err != nil {
 						return err
 					}
-					return a.printer.Emit(res)
+					return a.out(ctx).Emit(res)
 				}
 				if len(args) != 2 {
 					return errors.New("usage: tg search <peer> <query> (or --global <query>)")
 				}
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -111,7 +111,7 @@ the peer is omitted and the query is run across all chats.`,
 				if err != nil {
 					return err
 				}
-				return a.printer.Emit(res)
+				return a.out(ctx).Emit(res)
 			})
 		},
 	}
@@ -59,7 +59,7 @@ it has been cached first by running ` + "`tg chats list`" + `.`,
 			}
 
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				sender, m, err := a.sender(api)
+				sender, m, err := a.sender(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -73,7 +73,7 @@ it has been cached first by running ` + "`tg chats list`" + `.`,
 				if err != nil {
 					return errors.Wrap(err, "send")
 				}
-				return a.printer.Emit(sentResult{Peer: peer, MessageID: id})
+				return a.out(ctx).Emit(sentResult{Peer: peer, MessageID: id})
 			})
 		},
 	}
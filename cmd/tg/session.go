@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/gotd/td/session"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/lockedfile"
+	"github.com/gotd/cli/internal/sessionfmt"
+)
+
+// Session string formats of `tg session export|import`.
+const (
+	sessionFormatAuto     = "auto"
+	sessionFormatTg       = "tg"
+	sessionFormatJSON     = "json"
+	sessionFormatTelethon = "telethon"
+	sessionFormatPyrogram = "pyrogram"
+)
+
+// sessionExportResult is the result of `tg session export`.
+type sessionExportResult struct {
+	Format    string `json:"format"`
+	Encrypted bool   `json:"encrypted,omitempty"`
+	Session   string `json:"session"`
+}
+
+// MarshalText prints the bare session string, for piping.
+func (r sessionExportResult) MarshalText(w io.Writer) error {
+	_, err := fmt.Fprintln(w, r.Session)
+	return err
+}
+
+// sessionImportResult is the result of `tg session import`.
+type sessionImportResult struct {
+	Account  string `json:"account"`
+	Kind     string `json:"kind"`
+	Format   string `json:"format"`
+	DC       int    `json:"dc"`
+	Replaced bool   `json:"replaced,omitempty"`
+}
+
+// MarshalText renders a short summary.
+func (r sessionImportResult) MarshalText(w io.Writer) error {
+	verb := "imported"
+	if r.Replaced {
+		verb = "replaced"
+	}
+	_, err := fmt.Fprintf(w, "%s %s session for %s (%s, DC %d)\n", verb, r.Kind, r.Account, r.Format, r.DC)
+	return err
+}
+
+// portableKey returns the key for sealed portable strings: the key file when
+// given, else TG_SESSION_KEY or a passphrase typed on the terminal, or nil
+// when there is none of these. It is independent of the at-rest encryption
+// config, so a string sealed on one machine opens on another with the same
+// secret alone.
+func portableKey(keyFile string) *sessionKey {
+	if keyFile != "" {
+		return &sessionKey{keyFile: keyFile}
+	}
+	k := newSessionKey(&Encryption{}, "", true)
+	if k.env == "" && k.prompt == nil {
+		return nil
+	}
+	return k
+}
+
+// exportedSession is what an export needs besides the gotd session document.
+type exportedSession struct {
+	kind   string
+	test   bool
+	appID  int
+	userID int64 // Pyrogram only
+}
+
+// encodeSession renders a gotd session document in format. key seals the tg
+// format when non-nil.
+func encodeSession(raw []byte, format string, meta exportedSession, key *sessionKey) (string, error) {
+	switch format {
+	case sessionFormatJSON:
+		return string(bytes.TrimSpace(raw)), nil
+	case sessionFormatTg:
+		p, err := sessionfmt.Portable{Kind: meta.kind, Test: meta.test, AppID: meta.appID, Session: raw}.Marshal()
+		if err != nil {
+			return "", err
+		}
+		if key == nil {
+			return sessionfmt.Armor(sessionfmt.PortablePrefix, p), nil
+		}
+		sealed, err := key.seal(p)
+		if err != nil {
+			return "", errors.Wrap(err, "encrypt session")
+		}
+		return sessionfmt.Armor(sessionfmt.SealedPortablePrefix, sealed), nil
+	}
+
+	d, err := sessionfmt.Decode(raw)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case sessionFormatTelethon:
+		return sessionfmt.Telethon(d)
+	case sessionFormatPyrogram:
+		return sessionfmt.Pyrogram{
+			DC:      d.DC,
+			APIID:   meta.appID,
+			Test:    meta.test,
+			AuthKey: d.AuthKey,
+			UserID:  meta.userID,
+			Bot:     meta.kind == kindBot,
+		}.String(), nil
+	default:
+		return "", errors.Errorf("unknown session format %q", format)
+	}
+}
+
+// importedSession is a session string decoded for import. kind and test are
+// set when the format records them.
+type importedSession struct {
+	format    string
+	data      *session.Data
+	kind      string
+	test      bool
+	knownTest bool
+}
+
+// detectSessionFormat guesses the format of a session string. Telethon strings
+// start with their version "1"; Pyrogram ones with the base64 of a small DC id.
+func detectSessionFormat(s string) string {
+	switch {
+	case strings.HasPrefix(s, sessionfmt.PortablePrefix), strings.HasPrefix(s, sessionfmt.SealedPortablePrefix):
+		return sessionFormatTg
+	case strings.HasPrefix(s, "{"):
+		return sessionFormatJSON
+	case strings.HasPrefix(s, "1"):
+		return sessionFormatTelethon
+	default:
+		return sessionFormatPyrogram
+	}
+}
+
+// decodeSession parses a session string in format (or detects it). key opens
+// sealed portable strings and is only used for them.
+func decodeSession(s, format string, key func() *sessionKey) (importedSession, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return importedSession{}, withClass(classUsage, errors.New("empty session string"))
+	}
+	if format == sessionFormatAuto || format == "" {
+		format = detectSessionFormat(s)
+	}
+	r := importedSession{format: format}
+	switch format {
+	case sessionFormatTg:
+		var p sessionfmt.Portable
+		var err error
+		switch {
+		case strings.HasPrefix(s, sessionfmt.SealedPortablePrefix):
+			p, err = openPortable(s, key())
+		case strings.HasPrefix(s, sessionfmt.PortablePrefix):
+			var raw []byte
+			if raw, err = sessionfmt.Dearmor(sessionfmt.PortablePrefix, s); err == nil {
+				p, err = sessionfmt.UnmarshalPortable(raw)
+			}
+		default:
+			err = errors.Errorf("not a tg session string (want a %s or %s prefix)",
+				sessionfmt.PortablePrefix, sessionfmt.SealedPortablePrefix)
+		}
+		if err != nil {
+			return r, err
+		}
+		if r.data, err = sessionfmt.Decode(p.Session); err != nil {
+			return r, err
+		}
+		r.kind, r.test, r.knownTest = p.Kind, p.Test, true
+	case sessionFormatJSON:
+		// Either a gotd session document or a portable session as JSON.
+		if p, err := sessionfmt.UnmarshalPortable([]byte(s)); err == nil && p.Kind != "" {
+			if r.data, err = sessionfmt.Decode(p.Session); err != nil {
+				return r, err
+			}
+			r.kind, r.test, r.knownTest = p.Kind, p.Test, true
+			break
+		}
+		d, err := sessionfmt.Decode([]byte(s))
+		if err != nil {
+			return r, err
+		}
+		r.data = d
+	case sessionFormatTelethon:
+		d, err := sessionfmt.ParseTelethon(s)
+		if err != nil {
+			return r, err
+		}
+		r.data = d
+	case sessionFormatPyrogram:
+		p, err := sessionfmt.ParsePyrogram(s)
+		if err != nil {
+			return r, err
+		}
+		if r.data, err = p.Data(); err != nil {
+			return r, err
+		}
+		r.kind, r.test, r.knownTest = kindUser, p.Test, true
+		if p.Bot {
+			r.kind = kindBot
+		}
+	default:
+		return r, errors.Errorf("unknown session format %q", format)
+	}
+	if len(r.data.AuthKey) == 0 {
+		return r, errors.New("session has no auth key")
+	}
+	return r, nil
+}
+
+// openPortable decrypts a sealed portable string.
+func openPortable(s string, key *sessionKey) (sessionfmt.Portable, error) {
+	sealed, err := sessionfmt.Dearmor(sessionfmt.SealedPortablePrefix, s)
+	if err != nil {
+		return sessionfmt.Portable{}, err
+	}
+	if key == nil {
+		return sessionfmt.Portable{}, withClass(classAuth, errors.Errorf(
+			"session string is encrypted: pass --key-file or set %s", envSessionKey))
+	}
+	raw, err := key.open(sealed)
+	if err != nil {
+		return sessionfmt.Portable{}, errors.Wrap(err, "decrypt session string")
+	}
+	return sessionfmt.UnmarshalPortable(raw)
+}
+
+// readSessionArg returns the session string from args, or from stdin when it
+// is "-" or absent.
+func readSessionArg(cmd *cobra.Command, args []string) (string, error) {
+	if len(args) == 1 && args[0] != "-" {
+		return args[0], nil
+	}
+	raw, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return "", errors.Wrap(err, "read session from stdin")
+	}
+	return string(raw), nil
+}
+
+func (a *app) newSessionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "session",
+		Short:   "Move a session between machines",
+		GroupID: groupAuth,
+		Long: `Export the selected account's session as a single string and import it on
+another machine (a fresh CI runner, a container) without logging in again, or
+convert it to and from Telethon and Pyrogram string sessions.
+
+A session string grants full access to the account: treat it like a password,
+pass it through a secret store, and prefer --encrypt.`,
+	}
+	cmd.AddCommand(a.newSessionExportCmd(), a.newSessionImportCmd())
+	return cmd
+}
+
+func (a *app) newSessionExportCmd() *cobra.Command {
+	var (
+		asBot   bool
+		encrypt bool
+		keyFile string
+	)
+	format := newEnumValue(sessionFormatTg, sessionFormatTg, sessionFormatJSON, sessionFormatTelethon, sessionFormatPyrogram)
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Print the session as a portable string",
+		Long: `Print the selected account's session as a string, in one of these formats:
+
+  tg        gotd1:... with the auth kind and test flag, for "tg session import" (default)
+  json      gotd's session document, as stored in the session file
+  telethon  a Telethon StringSession
+  pyrogram  a Pyrogram string session (connects once to learn the user id)
+
+--encrypt seals the tg string (gotd1s:...) with a passphrase typed on the
+terminal, the --key-file contents, or ` + envSessionKey + `; import it with the
+same secret.`,
+		Example: `  tg session export > session.txt
+  tg session export --encrypt --key-file ci.key
+  tg session export --format telethon
+  tg session export --account work | ssh runner tg session import`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if encrypt && format.value != sessionFormatTg {
+				return withClass(classUsage, errors.New("--encrypt needs --format tg"))
+			}
+			kind := authUser
+			if asBot {
+				kind = authBot
+			}
+			if err := a.ensureActive(); err != nil {
+				return err
+			}
+			st := a.active
+			raw, err := a.sessionStore(st.label, st.acc, kind.String()).LoadSession(cmd.Context())
+			if errors.Is(err, session.ErrNotFound) {
+				return withClass(classAuth, errors.Errorf("no %s session for %s: run tg login", kind, st.label))
+			}
+			if err != nil {
+				return errors.Wrap(err, "load session")
+			}
+
+			meta := exportedSession{kind: kind.String(), test: st.acc.Test, appID: st.acc.AppID}
+			if appID, _, err := effectiveCreds(st.acc); err == nil {
+				meta.appID = appID
+			}
+			if format.value == sessionFormatPyrogram {
+				if err := a.connectWith(cmd.Context(), st, runParams{auth: kind},
+					func(ctx context.Context, client *telegram.Client, _ tg.UpdateDispatcher) error {
+						self, err := fetchSelf(ctx, client.API())
+						if err != nil {
+							return err
+						}
+						meta.userID = self.ID
+						return nil
+					}); err != nil {
+					return err
+				}
+			}
+
+			var key *sessionKey
+			if encrypt {
+				if key = portableKey(keyFile); key == nil {
+					return withClass(classUsage, errors.Errorf("--encrypt needs --key-file, %s or a terminal", envSessionKey))
+				}
+			}
+			s, err := encodeSession(raw, format.value, meta, key)
+			if err != nil {
+				return err
+			}
+			return a.printer.Emit(sessionExportResult{Format: format.value, Encrypted: encrypt, Session: s})
+		},
+	}
+
+	f := cmd.Flags()
+	f.Var(format, "format", "session string format: tg, json, telethon or pyrogram")
+	f.BoolVar(&encrypt, "encrypt", false, "encrypt the session string (tg format)")
+	f.StringVar(&keyFile, "key-file", "", "file holding the encryption secret (default: "+envSessionKey+" or a prompt)")
+	f.BoolVar(&asBot, kindBot, false, "export the bot session instead of the user session")
+	_ = cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(format.allowed, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.MarkFlagFilename("key-file")
+
+	return cmd
+}
+
+func (a *app) newSessionImportCmd() *cobra.Command {
+	var (
+		asBot   bool
+		force   bool
+		keyFile string
+	)
+	format := newEnumValue(sessionFormatAuto,
+		sessionFormatAuto, sessionFormatTg, sessionFormatJSON, sessionFormatTelethon, sessionFormatPyrogram)
+
+	cmd := &cobra.Command{
+		Use:   "import [string|-]",
+		Short: "Store a session string for the selected account",
+		Long: `Store a session string as the selected account's session. The string is read
+from the argument, or from stdin when it is "-" or omitted, so it stays out of
+the shell history. The format (tg, json, telethon or pyrogram) is detected.
+
+The session is stored like one from "tg login": in the Keychain, the session
+helper or the (encrypted) session file. The auth kind comes from the string
+when it records one (tg, pyrogram), else from --bot. An existing session is
+kept unless --force, which also clears the account's peer cache.
+
+Encrypted strings are opened with --key-file, ` + envSessionKey + ` or a
+passphrase typed on the terminal.`,
+		Example: `  tg session import < session.txt
+  TG_SESSION_KEY=... tg session import --account ci "$TG_SESSION"
+  tg session import --format telethon 1BVts...`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := readSessionArg(cmd, args)
+			if err != nil {
+				return err
+			}
+			if err := a.ensureActive(); err != nil {
+				return err
+			}
+			st := a.active
+			in, err := decodeSession(s, format.value, func() *sessionKey { return portableKey(keyFile) })
+			if err != nil {
+				return err
+			}
+
+			kind := in.kind
+			switch {
+			case kind == "":
+				kind = authUser.String()
+				if asBot {
+					kind = authBot.String()
+				}
+			case asBot && kind != kindBot:
+				return withClass(classUsage, errors.Errorf("--bot given but the session string is a %s session", kind))
+			}
+			if in.knownTest && in.test != st.acc.Test {
+				return withClass(classUsage, errors.Errorf(
+					"session is for the %s servers but account %s uses the %s servers",
+					serversName(in.test), st.label, serversName(st.acc.Test)))
+			}
+
+			ctx := cmd.Context()
+			store := a.sessionStore(st.label, st.acc, kind)
+			exists, err := store.Exists(ctx)
+			if err != nil {
+				return errors.Wrap(err, "check session")
+			}
+			if exists && !force {
+				return withClass(classUsage, errors.Errorf(
+					"account %s already has a %s session; pass --force to replace it", st.label, kind))
+			}
+			raw, err := sessionfmt.Encode(in.data)
+			if err != nil {
+				return err
+			}
+			if err := store.StoreSession(ctx, raw); err != nil {
+				return errors.Wrap(err, "store session")
+			}
+			if exists {
+				// Access hashes belong to the replaced session's account.
+				cachePath := st.acc.peerCachePath(filepath.Dir(a.configPath), st.label, kind)
+				if err := lockedfile.Remove(cachePath); err != nil {
+					return errors.Wrapf(err, "remove %s", cachePath)
+				}
+			}
+			return a.out(ctx).Emit(sessionImportResult{
+				Account:  st.label,
+				Kind:     kind,
+				Format:   in.format,
+				DC:       in.data.DC,
+				Replaced: exists,
+			})
+		},
+	}
+
+	f := cmd.Flags()
+	f.Var(format, "format", "session string format: auto, tg, json, telethon or pyrogram")
+	f.StringVar(&keyFile, "key-file", "", "file holding the secret of an encrypted string (default: "+envSessionKey+" or a prompt)")
+	f.BoolVar(&force, "force", false, "replace an existing session")
+	f.BoolVar(&asBot, kindBot, false, "store as the bot session when the string does not say")
+	_ = cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(format.allowed, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.MarkFlagFilename("key-file")
+
+	return cmd
+}
+
+// serversName names the production or test servers.
+func serversName(test bool) string {
+	if test {
+		return "test"
+	}
+	return "production"
+}
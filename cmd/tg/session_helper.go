@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/go-faster/errors"
+)
+
+// Session helper operations, passed as the helper's only argument.
+const (
+	helperGet   = "get"
+	helperStore = "store"
+	helperErase = "erase"
+)
+
+// helperAttr is one "key=value" attribute line of the helper protocol.
+type helperAttr struct{ key, value string }
+
+// helperSessionStore delegates sessions to an external credential helper, the
+// session_helper command, in the manner of git credential helpers. The command
+// is run through the shell (cmd /C on Windows) in the config directory, with
+// the operation appended as its last argument:
+//
+//	get    print the session on stdout, or nothing if there is none
+//	store  save the session read from stdin
+//	erase  delete the session; succeed if there is none
+//
+// Stdin starts with attribute lines ended by an empty line; for store, the
+// session (a JSON document) follows until EOF:
+//
+//	service=gotd.session
+//	account=work.user.5d41402abc4b2a76
+//	label=work
+//	kind=user
+//
+// The account attribute is unique per account and auth kind; use it as the
+// key. A non-zero exit status fails the operation. The helper's stderr is
+// passed through; since stdin carries the request, a helper that needs to
+// prompt must open /dev/tty itself. Existence is answered by get. A file session left from before the helper was configured is moved into
+// it on first use.
+type helperSessionStore struct {
+	command string
+	dir     string
+	attrs   []helperAttr
+	legacy  sessionStore
+	// readOnly serves a file session without moving it into the helper.
+	readOnly bool
+}
+
+// run invokes the helper for op with the attributes and payload on stdin.
+func (s *helperSessionStore) run(ctx context.Context, op string, payload []byte) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", s.command+" "+op) // #nosec G204 // helper command from the user's config
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", s.command+` "$@"`, s.command, op) // #nosec G204 // helper command from the user's config
+	}
+	cmd.Dir = s.dir
+
+	var in bytes.Buffer
+	for _, a := range s.attrs {
+		_, _ = fmt.Fprintf(&in, "%s=%s\n", a.key, a.value)
+	}
+	in.WriteByte('\n')
+	in.Write(payload)
+	cmd.Stdin = &in
+
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.Wrapf(err, "session helper %s: %s", op, lastLine(msg))
+		}
+		return nil, errors.Wrapf(err, "session helper %s", op)
+	}
+	return out.Bytes(), nil
+}
+
+// lastLine returns the last line of s.
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// get returns the helper's session, or nil if it has none.
+func (s *helperSessionStore) get(ctx context.Context) ([]byte, error) {
+	out, err := s.run(ctx, helperGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(out), nil
+}
+
+func (s *helperSessionStore) LoadSession(ctx context.Context) ([]byte, error) {
+	data, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		return data, nil
+	}
+	// Nothing in the helper: migrate a file session if present.
+	data, err = s.legacy.LoadSession(ctx)
+	if err != nil || s.readOnly {
+		return data, err
+	}
+	if err := s.StoreSession(ctx, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *helperSessionStore) StoreSession(ctx context.Context, data []byte) error {
+	if _, err := s.run(ctx, helperStore, data); err != nil {
+		return err
+	}
+	// Any write supersedes a file session.
+	if err := s.legacy.Delete(ctx); err != nil {
+		return errors.Wrap(err, "remove file session")
+	}
+	return nil
+}
+
+func (s *helperSessionStore) Exists(ctx context.Context) (bool, error) {
+	data, err := s.get(ctx)
+	if err != nil {
+		return false, err
+	}
+	if len(data) > 0 {
+		return true, nil
+	}
+	// Not yet migrated: a leftover file session still counts.
+	return s.legacy.Exists(ctx)
+}
+
+func (s *helperSessionStore) Delete(ctx context.Context) error {
+	if _, err := s.run(ctx, helperErase, nil); err != nil {
+		return err
+	}
+	return s.legacy.Delete(ctx)
+}
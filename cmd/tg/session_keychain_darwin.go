@@ -5,12 +5,9 @@ package main
 import (
 	"bytes"
 	"context"
-	"os"
 	"os/exec"
 
 	"github.com/go-faster/errors"
-
-	"github.com/gotd/td/session"
 )
 
 // keychainNotFound is the exit code `security` returns when an item is absent
@@ -20,18 +17,21 @@ const keychainNotFound = 44
 // keychainSessionStore returns a Keychain-backed store. The bool is always true
 // on darwin; the signature mirrors the non-darwin stub so newSessionStore can
 // fall back to a file when Keychain is unavailable. legacy is the pre-Keychain
-// file session path, migrated into the Keychain (and removed) on first use.
-func keychainSessionStore(service, account, legacy string) (sessionStore, bool) {
-	return &keychainStore{service: service, account: account, legacy: legacy}, true
+// file session, migrated into the Keychain (and removed) on first use; it is
+// read through its own store, so an encrypted file is decrypted first. With
+// readOnly the file session is served without being migrated.
+func keychainSessionStore(service, account string, legacy sessionStore, readOnly bool) (sessionStore, bool) {
+	return &keychainStore{service: service, account: account, legacy: legacy, readOnly: readOnly}, true
 }
 
 // keychainStore stores a gotd string session as a generic password in the macOS
 // login Keychain, driven through the `security` CLI (no cgo, keeps the binary
 // static).
 type keychainStore struct {
-	service string
-	account string
-	legacy  string // pre-Keychain file session, migrated then deleted.
+	service  string
+	account  string
+	legacy   sessionStore // pre-Keychain file session, migrated then deleted.
+	readOnly bool
 }
 
 func keychainAddArgs(service, account, secret string) []string {
@@ -64,12 +64,9 @@ func (s *keychainStore) LoadSession(ctx context.Context) ([]byte, error) {
 		return nil, errors.Wrap(err, "keychain find")
 	}
 	// Nothing in the Keychain: migrate a pre-Keychain file session if present.
-	data, rerr := os.ReadFile(s.legacy)
-	if errors.Is(rerr, os.ErrNotExist) {
-		return nil, session.ErrNotFound
-	}
-	if rerr != nil {
-		return nil, errors.Wrap(rerr, "read legacy session")
+	data, err := s.legacy.LoadSession(ctx)
+	if err != nil || s.readOnly {
+		return data, err
 	}
 	if err := s.StoreSession(ctx, data); err != nil {
 		return nil, err
@@ -82,7 +79,7 @@ func (s *keychainStore) StoreSession(ctx context.Context, data []byte) error {
 		return errors.Wrap(err, "keychain add")
 	}
 	// Any write supersedes a pre-Keychain file session; remove it best-effort.
-	if err := os.Remove(s.legacy); err != nil && !os.IsNotExist(err) {
+	if err := s.legacy.Delete(ctx); err != nil {
 		return errors.Wrap(err, "remove legacy session")
 	}
 	return nil
@@ -97,10 +94,7 @@ func (s *keychainStore) Exists(ctx context.Context) (bool, error) {
 		return false, errors.Wrap(err, "keychain find")
 	}
 	// Not yet migrated: a leftover file session still counts.
-	if _, serr := os.Stat(s.legacy); serr == nil {
-		return true, nil
-	}
-	return false, nil
+	return s.legacy.Exists(ctx)
 }
 
 func (s *keychainStore) Delete(ctx context.Context) error {
@@ -109,8 +103,8 @@ func (s *keychainStore) Delete(ctx context.Context) error {
 		return errors.Wrap(err, "keychain delete")
 	}
 	// Also drop any pre-Keychain file session.
-	if rerr := os.Remove(s.legacy); rerr != nil && !os.IsNotExist(rerr) {
-		return errors.Wrap(rerr, "remove legacy session")
+	if err := s.legacy.Delete(ctx); err != nil {
+		return errors.Wrap(err, "remove legacy session")
 	}
 	return nil
 }
@@ -60,7 +60,7 @@ func TestKeychainMigration(t *testing.T) {
 	service := "gotd.session-test-" + t.Name()
 	account := "migrate"
 	legacy := filepath.Join(t.TempDir(), "legacy.json")
-	store, _ := keychainSessionStore(service, account, legacy)
+	store, _ := keychainSessionStore(service, account, &fileSessionStore{FileStorage: &session.FileStorage{Path: legacy}}, false)
 	t.Cleanup(func() { _ = store.Delete(ctx) })
 
 	// A leftover file session should migrate on first load and be removed.
@@ -105,3 +105,28 @@ func TestIsNotFound(t *testing.T) {
 		t.Fatal("non-exit error should not be not-found")
 	}
 }
+
+// TestKeychainMigrationSealed migrates an encrypted file session: the Keychain
+// gets the decrypted session, not the sealed file.
+func TestKeychainMigrationSealed(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	t.Setenv(envSessionKey, "secret")
+	legacy := &sealedSessionStore{
+		fileSessionStore: fileSessionStore{FileStorage: &session.FileStorage{Path: filepath.Join(dir, "legacy.json")}},
+		key:              newSessionKey(nil, dir, false),
+	}
+	want := []byte(`{"v":1,"data":"abc=="}`)
+	if err := legacy.StoreSession(ctx, want); err != nil {
+		t.Fatal(err)
+	}
+	store, _ := keychainSessionStore("gotd.session-test-"+t.Name(), "migrate", legacy, false)
+	t.Cleanup(func() { _ = store.Delete(ctx) })
+
+	if got, err := store.LoadSession(ctx); err != nil || string(got) != string(want) {
+		t.Fatalf("LoadSession() = %q, %v; want %q, nil", got, err, want)
+	}
+	if ok, err := legacy.Exists(ctx); err != nil || ok {
+		t.Fatalf("legacy file should be removed after migration: %v, %v", ok, err)
+	}
+}
@@ -4,6 +4,6 @@ package main
 
 // keychainSessionStore reports that no Keychain backend exists off macOS, so
 // newSessionStore falls back to file storage.
-func keychainSessionStore(_, _, _ string) (sessionStore, bool) {
+func keychainSessionStore(_, _ string, _ sessionStore, _ bool) (sessionStore, bool) {
 	return nil, false
 }
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-faster/errors"
+	"golang.org/x/term"
+
+	"github.com/gotd/td/session"
+
+	"github.com/gotd/cli/internal/peercache"
+	"github.com/gotd/cli/internal/sealed"
+)
+
+// envSessionKey holds the at-rest encryption secret; setting it enables
+// encrypted sessions without any config.
+const envSessionKey = "TG_SESSION_KEY"
+
+// sessionKey obtains the at-rest encryption secret on first use: from
+// TG_SESSION_KEY, the configured key file, or a passphrase typed on the
+// terminal. It is shared by every store of a process, so the passphrase is
+// asked for at most once.
+type sessionKey struct {
+	env     string
+	keyFile string
+	// prompt reads a passphrase, twice when confirm is set; nil when there is
+	// no terminal to ask on.
+	prompt func(confirm bool) ([]byte, error)
+
+	mu  sync.Mutex
+	key *sealed.Key
+}
+
+// newSessionKey returns the key source for the config in dir, or nil when
+// encryption is off. interactive allows asking for a passphrase.
+func newSessionKey(enc *Encryption, dir string, interactive bool) *sessionKey {
+	env := os.Getenv(envSessionKey)
+	if enc == nil && env == "" {
+		return nil
+	}
+	k := &sessionKey{env: env}
+	if enc != nil && enc.KeyFile != "" {
+		k.keyFile = enc.KeyFile
+		if !filepath.IsAbs(k.keyFile) {
+			k.keyFile = filepath.Join(dir, k.keyFile)
+		}
+	}
+	if interactive && term.IsTerminal(int(os.Stdin.Fd())) {
+		k.prompt = func(confirm bool) ([]byte, error) {
+			return promptPassphrase(os.Stderr, int(os.Stdin.Fd()), confirm)
+		}
+	}
+	return k
+}
+
+// get returns the key, obtaining the secret on first use. confirm asks for a
+// typed passphrase twice, for when it is about to encrypt something new.
+func (k *sessionKey) get(confirm bool) (*sealed.Key, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.key != nil {
+		return k.key, nil
+	}
+	var secret []byte
+	switch {
+	case k.env != "":
+		secret = []byte(k.env)
+	case k.keyFile != "":
+		raw, err := os.ReadFile(k.keyFile) // #nosec G304 // path from config
+		if err != nil {
+			return nil, errors.Wrap(err, "read encryption key file")
+		}
+		if secret = bytes.TrimSpace(raw); len(secret) == 0 {
+			return nil, errors.Errorf("encryption key file %s is empty", k.keyFile)
+		}
+	case k.prompt != nil:
+		pass, err := k.prompt(confirm)
+		if err != nil {
+			return nil, err
+		}
+		secret = pass
+	default:
+		return nil, withClass(classAuth, errors.Errorf(
+			"sessions are encrypted: set %s or encryption.key_file, or run in a terminal to enter the passphrase", envSessionKey))
+	}
+	k.key = sealed.NewKey(secret)
+	return k.key, nil
+}
+
+// promptPassphrase reads a passphrase from the terminal fd without echo.
+func promptPassphrase(out io.Writer, fd int, confirm bool) ([]byte, error) {
+	read := func(label string) ([]byte, error) {
+		if _, err := fmt.Fprint(out, label); err != nil {
+			return nil, err
+		}
+		pass, err := term.ReadPassword(fd)
+		_, _ = fmt.Fprintln(out)
+		if err != nil {
+			return nil, errors.Wrap(err, "read passphrase")
+		}
+		return pass, nil
+	}
+	pass, err := read("Session passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(pass) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	if confirm {
+		again, err := read("Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(pass, again) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+	return pass, nil
+}
+
+// open decrypts a sealed file, or returns a plain one unchanged.
+func (k *sessionKey) open(raw []byte) ([]byte, error) {
+	if !sealed.IsSealed(raw) {
+		return raw, nil
+	}
+	key, err := k.get(false)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := key.Open(raw)
+	if errors.Is(err, sealed.ErrKey) {
+		return nil, withClass(classAuth, errors.Wrapf(err, "decrypt (check %s, the key file or the passphrase)", envSessionKey))
+	}
+	return plain, err
+}
+
+// seal encrypts plain.
+func (k *sessionKey) seal(plain []byte) ([]byte, error) {
+	key, err := k.get(true)
+	if err != nil {
+		return nil, err
+	}
+	return key.Seal(plain)
+}
+
+// sealedSessionStore is the file backend with the session encrypted at rest.
+// A plaintext session file left from before encryption was enabled is
+// encrypted in place on first load.
+type sealedSessionStore struct {
+	fileSessionStore
+	key *sessionKey
+	// readOnly keeps a plaintext session plain.
+	readOnly bool
+}
+
+func (s *sealedSessionStore) LoadSession(ctx context.Context) ([]byte, error) {
+	raw, err := os.ReadFile(s.Path) // #nosec G304 // path derived from config dir
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, session.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read session")
+	}
+	if !sealed.IsSealed(raw) {
+		if s.readOnly {
+			return raw, nil
+		}
+		if err := s.StoreSession(ctx, raw); err != nil {
+			return nil, errors.Wrap(err, "encrypt plaintext session")
+		}
+		return raw, nil
+	}
+	data, err := s.key.open(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "open session")
+	}
+	return data, nil
+}
+
+func (s *sealedSessionStore) StoreSession(ctx context.Context, data []byte) error {
+	raw, err := s.key.seal(data)
+	if err != nil {
+		return errors.Wrap(err, "encrypt session")
+	}
+	return s.fileSessionStore.StoreSession(ctx, raw)
+}
+
+// peerCacheCodec reads encrypted peer caches with the session key, and writes
+// them encrypted when encrypt is set (encryption.peer_cache) or plain
+// otherwise, so toggling the option converts the cache on its next write.
+type peerCacheCodec struct {
+	key     *sessionKey
+	encrypt bool
+}
+
+var _ peercache.Codec = peerCacheCodec{}
+
+func (c peerCacheCodec) Encode(plain []byte) ([]byte, error) {
+	if !c.encrypt {
+		return plain, nil
+	}
+	return c.key.seal(plain)
+}
+
+func (c peerCacheCodec) Decode(raw []byte) ([]byte, error) { return c.key.open(raw) }
+
+// cacheCodec returns the peer cache codec for a config, or nil (plain JSON)
+// when encryption is off.
+func cacheCodec(cfg Config, key *sessionKey) peercache.Codec {
+	if key == nil {
+		return nil
+	}
+	return peerCacheCodec{key: key, encrypt: cfg.Encryption != nil && cfg.Encryption.PeerCache}
+}
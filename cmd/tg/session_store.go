@@ -5,7 +5,11 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/go-faster/errors"
+
 	"github.com/gotd/td/session"
+
+	"github.com/gotd/cli/internal/lockedfile"
 )
 
 // sessionService is the Keychain service name under which sessions are stored.
@@ -14,8 +18,9 @@ const sessionService = "gotd.session"
 
 // sessionStore persists a gotd string session. It is the single seam through
 // which the session is read (by the gotd client), checked for existence (by
-// `accounts`), and removed (by `logout`). Backends: a plain file, or the macOS
-// Keychain (default on darwin).
+// `accounts`), and removed (by `logout`). Backends: a plain file, an encrypted
+// file (see sealedSessionStore), the macOS Keychain (default on darwin), or an
+// external credential helper (see helperSessionStore).
 type sessionStore interface {
 	session.Storage // LoadSession / StoreSession, consumed by telegram.Options.
 	Exists(ctx context.Context) (bool, error)
@@ -23,11 +28,26 @@ type sessionStore interface {
 }
 
 // fileSessionStore is the cross-platform backend: gotd's JSON FileStorage plus
-// existence/deletion over the same path.
+// existence/deletion over the same path. Writes and deletes hold an
+// inter-process lock and sessions are replaced atomically, so concurrent tg
+// processes on one account never read a torn session file.
 type fileSessionStore struct {
 	*session.FileStorage
 }
 
+// StoreSession implements session.Storage with a locked atomic replace.
+func (s *fileSessionStore) StoreSession(_ context.Context, data []byte) error {
+	unlock, err := lockedfile.Lock(s.Path)
+	if err != nil {
+		return errors.Wrap(err, "lock session")
+	}
+	defer func() { _ = unlock() }()
+	if err := lockedfile.WriteFile(s.Path, data, 0o600); err != nil {
+		return errors.Wrap(err, "write session")
+	}
+	return nil
+}
+
 func (s *fileSessionStore) Exists(context.Context) (bool, error) {
 	switch _, err := os.Stat(s.Path); {
 	case err == nil:
@@ -40,8 +60,8 @@ func (s *fileSessionStore) Exists(context.Context) (bool, error) {
 }
 
 func (s *fileSessionStore) Delete(context.Context) error {
-	if err := os.Remove(s.Path); err != nil && !os.IsNotExist(err) {
-		return err
+	if err := lockedfile.Remove(s.Path); err != nil {
+		return errors.Wrap(err, "remove session")
 	}
 	return nil
 }
@@ -53,19 +73,63 @@ func keychainAccount(label string, acc Account, kind string) string {
 	return label + "." + kind + "." + acc.seed(label, kind)
 }
 
-// newSessionStore selects the backend. On macOS with Keychain enabled (the
-// default) it returns the Keychain backend; otherwise it falls back to a file in
-// the config directory.
-func newSessionStore(dir, label string, acc Account, kind string, useKeychain bool) sessionStore {
+// sessionBackend selects where sessions are kept.
+type sessionBackend struct {
+	// helper is the session_helper command; it takes precedence over the
+	// other backends.
+	helper string
+	// keychain enables the macOS Keychain.
+	keychain bool
+	// key encrypts file sessions; nil keeps them plain.
+	key *sessionKey
+	// readOnly loads sessions without writing anything: no migration
+	// between backends or to encryption, and stores are dropped.
+	readOnly bool
+}
+
+// newSessionStore selects the backend. A configured credential helper wins;
+// then, on macOS with Keychain enabled (the default), the Keychain backend;
+// otherwise a file in the config directory, encrypted when b.key is set. The
+// helper and the Keychain migrate a file session left from before they were
+// enabled, unless b.readOnly is set.
+func newSessionStore(dir, label string, acc Account, kind string, b sessionBackend) sessionStore {
+	store := selectSessionStore(dir, label, acc, kind, b)
+	if b.readOnly {
+		return readOnlySession{sessionStore: store}
+	}
+	return store
+}
+
+// selectSessionStore is newSessionStore without the read-only wrapper.
+func selectSessionStore(dir, label string, acc Account, kind string, b sessionBackend) sessionStore {
 	path := acc.sessionPath(dir, label, kind)
-	if useKeychain {
-		// path is handed to the Keychain backend so it can migrate (and clean
-		// up) a pre-Keychain file session on first use.
-		if ks, ok := keychainSessionStore(sessionService, keychainAccount(label, acc, kind), path); ok {
+	file := fileSessionStore{FileStorage: &session.FileStorage{Path: path}}
+	var fileStore sessionStore = &file
+	if b.key != nil {
+		fileStore = &sealedSessionStore{fileSessionStore: file, key: b.key, readOnly: b.readOnly}
+	}
+	if b.helper != "" {
+		return &helperSessionStore{
+			command: b.helper,
+			dir:     dir,
+			attrs: []helperAttr{
+				{"service", sessionService},
+				{"account", keychainAccount(label, acc, kind)},
+				{"label", label},
+				{"kind", kind},
+			},
+			legacy:   fileStore,
+			readOnly: b.readOnly,
+		}
+	}
+	if b.keychain {
+		// The file store is handed to the Keychain backend so it can migrate
+		// (and clean up) a pre-Keychain file session on first use.
+		if ks, ok := keychainSessionStore(sessionService, keychainAccount(label, acc, kind), fileStore, b.readOnly); ok {
 			return ks
 		}
 	}
-	return &fileSessionStore{FileStorage: &session.FileStorage{Path: path}}
+	return fileStore
 }
 
 // useKeychain reports whether the Keychain backend is enabled. It defaults to
@@ -76,5 +140,23 @@ func (a *app) useKeychain() bool {
 
 // sessionStore builds the session store for an account label + auth kind.
 func (a *app) sessionStore(label string, acc Account, kind string) sessionStore {
-	return newSessionStore(filepath.Dir(a.configPath), label, acc, kind, a.useKeychain())
+	return newSessionStore(filepath.Dir(a.configPath), label, acc, kind, a.sessionBackend())
 }
+
+// sessionBackend returns the session backend the config selects.
+func (a *app) sessionBackend() sessionBackend {
+	return sessionBackend{
+		helper:   a.cfg.SessionHelper,
+		keychain: a.useKeychain(),
+		key:      a.sessionKey,
+	}
+}
+
+// readOnlySession drops the writes to a session store.
+type readOnlySession struct {
+	sessionStore
+}
+
+func (readOnlySession) StoreSession(context.Context, []byte) error { return nil }
+
+func (readOnlySession) Delete(context.Context) error { return nil }
@@ -4,7 +4,16 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/session"
+
+	"github.com/gotd/cli/internal/sealed"
 )
 
 func TestUseKeychain(t *testing.T) {
@@ -46,7 +55,7 @@ func TestFileSessionStore(t *testing.T) {
 	dir := t.TempDir()
 	acc := Account{AppID: 42}
 	// keychain off → file backend regardless of platform.
-	store := newSessionStore(dir, defaultAccount, acc, kindUser, false)
+	store := newSessionStore(dir, defaultAccount, acc, kindUser, sessionBackend{})
 
 	if ok, err := store.Exists(ctx); err != nil || ok {
 		t.Fatalf("Exists() = %v, %v; want false, nil", ok, err)
@@ -76,3 +85,206 @@ func TestFileSessionStore(t *testing.T) {
 		t.Fatal("session file should be gone after delete")
 	}
 }
+
+func TestFileSessionStoreConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	store := newSessionStore(t.TempDir(), defaultAccount, Account{AppID: 42}, kindUser, sessionBackend{})
+
+	// Every load sees one complete session, never a torn or empty file.
+	blobs := []string{strings.Repeat("a", 1<<16), strings.Repeat("b", 1<<16)}
+	var wg sync.WaitGroup
+	for _, blob := range blobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				if err := store.StoreSession(ctx, []byte(blob)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for range 50 {
+		got, err := store.LoadSession(ctx)
+		if errors.Is(err, session.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := string(got); s != blobs[0] && s != blobs[1] {
+			t.Fatalf("LoadSession() returned a torn session of %d bytes", len(got))
+		}
+	}
+	wg.Wait()
+}
+
+func TestSealedSessionStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	acc := Account{AppID: 42}
+	path := acc.sessionPath(dir, defaultAccount, kindUser)
+
+	// A plaintext session from before encryption is migrated on first load.
+	plain := []byte(`{"Version":1,"Data":{"DC":2}}`)
+	if err := os.WriteFile(path, plain, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(envSessionKey, "secret")
+	store := newSessionStore(dir, defaultAccount, acc, kindUser, sessionBackend{key: newSessionKey(nil, dir, false)})
+	got, err := store.LoadSession(ctx)
+	if err != nil || string(got) != string(plain) {
+		t.Fatalf("LoadSession() = %q, %v", got, err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "Version") || !sealed.IsSealed(raw) {
+		t.Fatalf("session not encrypted after load: %q", raw)
+	}
+
+	// Reloading with the same secret in a new process works.
+	again := newSessionStore(dir, defaultAccount, acc, kindUser, sessionBackend{key: newSessionKey(nil, dir, false)})
+	if got, err := again.LoadSession(ctx); err != nil || string(got) != string(plain) {
+		t.Fatalf("reload = %q, %v", got, err)
+	}
+
+	// A wrong secret is an auth error.
+	t.Setenv(envSessionKey, "wrong")
+	wrong := newSessionStore(dir, defaultAccount, acc, kindUser, sessionBackend{key: newSessionKey(nil, dir, false)})
+	if _, err := wrong.LoadSession(ctx); classify(err) != classAuth {
+		t.Fatalf("wrong key: err = %v", err)
+	}
+
+	// A key file works too, relative to the config directory.
+	t.Setenv(envSessionKey, "")
+	if err := os.WriteFile(filepath.Join(dir, "session.key"), []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fromFile := newSessionStore(dir, defaultAccount, acc, kindUser,
+		sessionBackend{key: newSessionKey(&Encryption{KeyFile: "session.key"}, dir, false)})
+	if got, err := fromFile.LoadSession(ctx); err != nil || string(got) != string(plain) {
+		t.Fatalf("key file = %q, %v", got, err)
+	}
+
+	// Without any key source the session cannot be opened.
+	noKey := newSessionStore(dir, defaultAccount, acc, kindUser, sessionBackend{key: newSessionKey(&Encryption{}, dir, false)})
+	if _, err := noKey.LoadSession(ctx); err == nil {
+		t.Fatal("expected error without a key")
+	}
+}
+
+// testSessionHelper is a shell-script credential helper keeping sessions as
+// files named after the account attribute in its working directory.
+const testSessionHelper = `#!/bin/sh
+while IFS= read -r line; do
+	[ -z "$line" ] && break
+	case "$line" in account=*) account=${line#account=} ;; esac
+done
+[ -n "$account" ] || { echo "no account" >&2; exit 2; }
+case "$1" in
+get) [ -f "store/$account" ] && cat "store/$account" ;;
+store) mkdir -p store && cat > "store/$account" ;;
+erase) rm -f "store/$account" ;;
+*) echo "unknown op $1" >&2; exit 2 ;;
+esac
+exit 0
+`
+
+func TestHelperSessionStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell-script helper")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "helper.sh"), []byte(testSessionHelper), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	acc := Account{AppID: 42}
+
+	// A file session is migrated into the helper on first load.
+	path := acc.sessionPath(dir, "work", kindUser)
+	if err := os.WriteFile(path, []byte(`{"Version":1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := newSessionStore(dir, "work", acc, kindUser, sessionBackend{helper: "./helper.sh"})
+	if ok, err := store.Exists(ctx); err != nil || !ok {
+		t.Fatalf("Exists() before migration = %v, %v", ok, err)
+	}
+	got, err := store.LoadSession(ctx)
+	if err != nil || string(got) != `{"Version":1}` {
+		t.Fatalf("LoadSession() = %q, %v", got, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("file session not removed after migration: %v", err)
+	}
+	stored, err := os.ReadFile(filepath.Join(dir, "store", keychainAccount("work", acc, kindUser)))
+	if err != nil || string(stored) != `{"Version":1}` {
+		t.Fatalf("helper store = %q, %v", stored, err)
+	}
+
+	if err := store.StoreSession(ctx, []byte(`{"Version":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.LoadSession(ctx); err != nil || string(got) != `{"Version":2}` {
+		t.Fatalf("LoadSession() after store = %q, %v", got, err)
+	}
+
+	if err := store.Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := store.Exists(ctx); err != nil || ok {
+		t.Fatalf("Exists() after delete = %v, %v", ok, err)
+	}
+	if _, err := store.LoadSession(ctx); !errors.Is(err, session.ErrNotFound) {
+		t.Fatalf("LoadSession() after delete: err = %v, want ErrNotFound", err)
+	}
+
+	// A failing helper surfaces its stderr.
+	broken := newSessionStore(dir, "work", acc, kindUser, sessionBackend{helper: "echo boom >&2; exit 3;"})
+	if _, err := broken.LoadSession(ctx); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("broken helper: err = %v", err)
+	}
+}
+
+func TestReadOnlySessionStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell-script helper")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "helper.sh"), []byte(testSessionHelper), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	acc := Account{AppID: 42}
+	path := acc.sessionPath(dir, defaultAccount, kindUser)
+	plain := []byte(`{"Version":1}`)
+	if err := os.WriteFile(path, plain, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(envSessionKey, "secret")
+	key := newSessionKey(nil, dir, false)
+
+	// Neither encryption nor a helper migrates the plaintext file session,
+	// and writes are dropped.
+	for name, b := range map[string]sessionBackend{
+		"sealed": {key: key, readOnly: true},
+		"helper": {helper: "./helper.sh", key: key, readOnly: true},
+	} {
+		store := newSessionStore(dir, defaultAccount, acc, kindUser, b)
+		if got, err := store.LoadSession(ctx); err != nil || string(got) != string(plain) {
+			t.Fatalf("%s: LoadSession() = %q, %v", name, got, err)
+		}
+		if err := store.StoreSession(ctx, []byte(`{"Version":2}`)); err != nil {
+			t.Fatal(err)
+		}
+		if raw, _ := os.ReadFile(path); string(raw) != string(plain) {
+			t.Fatalf("%s: session file rewritten: %q", name, raw)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "store")); !os.IsNotExist(err) {
+		t.Errorf("helper was written to: %v", err)
+	}
+}
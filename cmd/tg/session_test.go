@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gotd/cli/internal/sessionfmt"
+)
+
+func testSessionDoc(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 256)
+	for i := range key {
+		key[i] = byte(i * 7)
+	}
+	d, err := sessionfmt.FromKey(2, false, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := sessionfmt.Encode(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestSessionRoundTrip(t *testing.T) {
+	raw := testSessionDoc(t)
+	keyFile := writeKey(t, "secret\n")
+	meta := exportedSession{kind: kindBot, appID: 1, userID: 42}
+	want, err := sessionfmt.Decode(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name, format, detected string
+		key                    *sessionKey
+		kind                   string
+	}{
+		{"tg", sessionFormatTg, sessionFormatTg, nil, kindBot},
+		{"sealed", sessionFormatTg, sessionFormatTg, portableKey(keyFile), kindBot},
+		{"json", sessionFormatJSON, sessionFormatJSON, nil, ""},
+		{"telethon", sessionFormatTelethon, sessionFormatTelethon, nil, ""},
+		{"pyrogram", sessionFormatPyrogram, sessionFormatPyrogram, nil, kindBot},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := encodeSession(raw, c.format, meta, c.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.key != nil && !strings.HasPrefix(s, sessionfmt.SealedPortablePrefix) {
+				t.Fatalf("sealed string %q", s)
+			}
+			got, err := decodeSession(s+"\n", sessionFormatAuto, func() *sessionKey { return portableKey(keyFile) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.format != c.detected || got.kind != c.kind {
+				t.Errorf("format, kind = %s, %q; want %s, %q", got.format, got.kind, c.detected, c.kind)
+			}
+			if got.data.DC != 2 || !bytes.Equal(got.data.AuthKey, want.AuthKey) {
+				t.Errorf("decoded DC %d, auth key mismatch", got.data.DC)
+			}
+		})
+	}
+}
+
+func TestDecodeSessionErrors(t *testing.T) {
+	raw := testSessionDoc(t)
+	s, err := encodeSession(raw, sessionFormatTg, exportedSession{kind: kindUser}, portableKey(writeKey(t, "a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeSession(s, sessionFormatAuto, func() *sessionKey { return portableKey(writeKey(t, "b")) }); err == nil {
+		t.Error("expected error for the wrong key")
+	}
+	// Without a key file, TG_SESSION_KEY or a terminal there is no key, and
+	// the error names --key-file rather than the at-rest encryption config.
+	t.Setenv(envSessionKey, "")
+	if k := portableKey(""); k != nil {
+		t.Errorf("portableKey without a secret = %+v, want nil", k)
+	}
+	_, err = decodeSession(s, sessionFormatAuto, func() *sessionKey { return portableKey("") })
+	if classify(err) != classAuth || !strings.Contains(err.Error(), "--key-file") {
+		t.Errorf("no key: %v, want auth error naming --key-file", err)
+	}
+	t.Setenv(envSessionKey, "a")
+	if _, err := decodeSession(s, sessionFormatAuto, func() *sessionKey { return portableKey("") }); err != nil {
+		t.Errorf("key from %s: %v", envSessionKey, err)
+	}
+	if _, err := decodeSession("  ", sessionFormatAuto, nil); classify(err) != classUsage {
+		t.Errorf("empty: class = %v, want usage", classify(err))
+	}
+	if _, err := decodeSession("1not-base64!", sessionFormatAuto, nil); err == nil {
+		t.Error("expected error for a malformed telethon string")
+	}
+}
+
+func writeKey(t *testing.T, secret string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte(secret), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/output"
 )
 
 // stickerSetItem describes an installed sticker set.
@@ -24,6 +26,9 @@ type stickerSetsResult struct {
 	Sets []stickerSetItem `json:"sets"`
 }
 
+// Items implements output.ItemLister.
+func (r stickerSetsResult) Items() []any { return output.Items(r.Sets) }
+
 // MarshalText renders one sticker set per line.
 func (r stickerSetsResult) MarshalText(w io.Writer) error {
 	for _, s := range r.Sets {
@@ -50,7 +55,7 @@ func (a *app) newStickersCmd() *cobra.Command {
 				all, ok := res.(*tg.MessagesAllStickers)
 				if !ok {
 					// MessagesAllStickersNotModified: nothing to show.
-					return a.printer.Emit(stickerSetsResult{})
+					return a.out(ctx).Emit(stickerSetsResult{})
 				}
 
 				var out stickerSetsResult
@@ -62,7 +67,7 @@ func (a *app) newStickersCmd() *cobra.Command {
 						Count:     s.Count,
 					})
 				}
-				return a.printer.Emit(out)
+				return a.out(ctx).Emit(out)
 			})
 		},
 	}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"golang.org/x/term"
+
+	"github.com/gotd/td/session"
+	"github.com/gotd/td/session/tdesktop"
+
+	"github.com/gotd/cli/internal/sessionfmt"
+)
+
+// envTDataPasscode holds the Telegram Desktop local passcode for
+// `tg login --from-tdata`.
+const envTDataPasscode = "TG_TDATA_PASSCODE"
+
+// readTData reads the accounts of a Telegram Desktop tdata directory. When the
+// profile is locked with a local passcode and none was given, the passcode is
+// asked for on the terminal if there is one.
+func readTData(dir string, passcode []byte, interactive bool) ([]tdesktop.Account, error) {
+	accs, err := tdesktop.Read(dir, passcode)
+	if errors.Is(err, tdesktop.ErrKeyInfoDecrypt) && passcode == nil && interactive {
+		fd := int(os.Stdin.Fd())
+		_, _ = fmt.Fprint(os.Stderr, "Telegram Desktop passcode: ")
+		pass, rErr := term.ReadPassword(fd)
+		_, _ = fmt.Fprintln(os.Stderr)
+		if rErr != nil {
+			return nil, errors.Wrap(rErr, "read passcode")
+		}
+		accs, err = tdesktop.Read(dir, pass)
+	}
+	switch {
+	case errors.Is(err, tdesktop.ErrKeyInfoDecrypt):
+		if passcode == nil && !interactive {
+			return nil, withClass(classAuth, errors.Errorf(
+				"tdata is locked with a local passcode: pass --passcode or set %s", envTDataPasscode))
+		}
+		return nil, withClass(classAuth, errors.New("wrong Telegram Desktop passcode"))
+	case errors.Is(err, tdesktop.ErrNoAccounts):
+		return nil, withClass(classAuth, errors.Errorf("no logged-in accounts in %s", dir))
+	case err != nil:
+		return nil, errors.Wrapf(err, "read tdata %s", dir)
+	}
+	return accs, nil
+}
+
+// pickTDataAccount selects one of the accounts of a Desktop profile by sel: a
+// 1-based position or a user id. An empty sel picks the only account; with
+// several, choose asks for one (nil when there is no terminal).
+func pickTDataAccount(accs []tdesktop.Account, sel string, choose func([]tdesktop.Account) (string, error)) (tdesktop.Account, error) {
+	if sel == "" {
+		if len(accs) == 1 {
+			return accs[0], nil
+		}
+		if choose == nil {
+			return tdesktop.Account{}, withClass(classUsage, errors.Errorf(
+				"tdata holds %d accounts (%s): pick one with --tdata-account", len(accs), tdataAccountList(accs)))
+		}
+		var err error
+		if sel, err = choose(accs); err != nil {
+			return tdesktop.Account{}, err
+		}
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(sel), 10, 64)
+	if err != nil {
+		return tdesktop.Account{}, withClass(classUsage, errors.Errorf("--tdata-account %q: want a number or a user id", sel))
+	}
+	if n >= 1 && n <= uint64(len(accs)) {
+		return accs[n-1], nil
+	}
+	for _, acc := range accs {
+		if acc.Authorization.UserID == n {
+			return acc, nil
+		}
+	}
+	return tdesktop.Account{}, withClass(classUsage, errors.Errorf(
+		"no tdata account %s (have %s)", sel, tdataAccountList(accs)))
+}
+
+// tdataAccountList renders accounts as "1=id:123, 2=id:456".
+func tdataAccountList(accs []tdesktop.Account) string {
+	parts := make([]string, len(accs))
+	for i, acc := range accs {
+		parts[i] = fmt.Sprintf("%d=id:%d", i+1, acc.Authorization.UserID)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// promptTDataAccount lists accounts on out and reads the choice from in.
+func promptTDataAccount(in io.Reader, out io.Writer) func([]tdesktop.Account) (string, error) {
+	return func(accs []tdesktop.Account) (string, error) {
+		_, _ = fmt.Fprintln(out, "Telegram Desktop accounts:")
+		for i, acc := range accs {
+			_, _ = fmt.Fprintf(out, "  %d) user id %d, DC %d\n", i+1, acc.Authorization.UserID, acc.Authorization.MainDC)
+		}
+		t := termAuth{in: bufio.NewReader(in), out: out}
+		return t.prompt("Account to import: ")
+	}
+}
+
+// tdataSession converts a Desktop account into a gotd session document for an
+// account on the test servers when test is set.
+func tdataSession(acc tdesktop.Account, test bool) ([]byte, error) {
+	if acc.Config.Environment.Test() != test {
+		return nil, withClass(classUsage, errors.Errorf("tdata account is on the %s servers but the tg account uses the %s servers",
+			serversName(acc.Config.Environment.Test()), serversName(test)))
+	}
+	d, err := session.TDesktopSession(acc)
+	if err != nil {
+		return nil, errors.Wrap(err, "convert tdata session")
+	}
+	return sessionfmt.Encode(d)
+}
+
+// loginTData stores the user session of a Desktop profile account for the
+// active account. It refuses to replace an existing session.
+func (a *app) loginTData(ctx context.Context, dir string, passcode []byte, sel string) error {
+	if err := a.ensureActive(); err != nil {
+		return err
+	}
+	st := a.active
+	store := a.sessionStore(st.label, st.acc, kindUser)
+	exists, err := store.Exists(ctx)
+	if err != nil {
+		return errors.Wrap(err, "check session")
+	}
+	if exists {
+		return withClass(classUsage, errors.Errorf(
+			"account %s already has a user session: run tg logout first, or use another --account", st.label))
+	}
+
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	accs, err := readTData(dir, passcode, interactive)
+	if err != nil {
+		return err
+	}
+	var choose func([]tdesktop.Account) (string, error)
+	if interactive {
+		choose = promptTDataAccount(os.Stdin, os.Stderr)
+	}
+	acc, err := pickTDataAccount(accs, sel, choose)
+	if err != nil {
+		return err
+	}
+	raw, err := tdataSession(acc, st.acc.Test)
+	if err != nil {
+		return err
+	}
+	if err := store.StoreSession(ctx, raw); err != nil {
+		return errors.Wrap(err, "store session")
+	}
+	return nil
+}
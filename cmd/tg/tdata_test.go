@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gotd/td/crypto"
+	"github.com/gotd/td/session/tdesktop"
+
+	"github.com/gotd/cli/internal/sessionfmt"
+)
+
+func testTDataAccounts() []tdesktop.Account {
+	return []tdesktop.Account{
+		{Authorization: tdesktop.MTPAuthorization{UserID: 100, MainDC: 2}},
+		{Authorization: tdesktop.MTPAuthorization{UserID: 200, MainDC: 4}},
+	}
+}
+
+func TestPickTDataAccount(t *testing.T) {
+	accs := testTDataAccounts()
+	for sel, want := range map[string]uint64{"1": 100, "2": 200, "200": 200, " 100 ": 100} {
+		got, err := pickTDataAccount(accs, sel, nil)
+		if err != nil {
+			t.Errorf("%q: %v", sel, err)
+			continue
+		}
+		if got.Authorization.UserID != want {
+			t.Errorf("%q: user %d, want %d", sel, got.Authorization.UserID, want)
+		}
+	}
+	for _, sel := range []string{"3", "x"} {
+		if _, err := pickTDataAccount(accs, sel, nil); classify(err) != classUsage {
+			t.Errorf("%q: class = %v, want usage", sel, classify(err))
+		}
+	}
+
+	// Several accounts and no terminal: the error lists them.
+	_, err := pickTDataAccount(accs, "", nil)
+	if err == nil || !strings.Contains(err.Error(), "2=id:200") {
+		t.Errorf("no selection: err = %v", err)
+	}
+	// A single account needs no selection.
+	if got, err := pickTDataAccount(accs[:1], "", nil); err != nil || got.Authorization.UserID != 100 {
+		t.Errorf("single account = %d, %v", got.Authorization.UserID, err)
+	}
+
+	var out bytes.Buffer
+	got, err := pickTDataAccount(accs, "", promptTDataAccount(strings.NewReader("2\n"), &out))
+	if err != nil || got.Authorization.UserID != 200 {
+		t.Errorf("prompted = %d, %v", got.Authorization.UserID, err)
+	}
+	if !strings.Contains(out.String(), "2) user id 200, DC 4") {
+		t.Errorf("prompt listed %q", out.String())
+	}
+}
+
+func TestTDataSession(t *testing.T) {
+	var key crypto.Key
+	key[0] = 1
+	acc := tdesktop.Account{Authorization: tdesktop.MTPAuthorization{
+		UserID: 100,
+		MainDC: 2,
+		Keys:   map[int]crypto.Key{2: key},
+	}}
+	raw, err := tdataSession(acc, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := sessionfmt.Decode(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.DC != 2 || d.Addr == "" || !bytes.Equal(d.AuthKey, key[:]) {
+		t.Errorf("session = DC %d addr %q", d.DC, d.Addr)
+	}
+	if _, err := tdataSession(acc, true); classify(err) != classUsage {
+		t.Errorf("test mismatch: class = %v, want usage", classify(err))
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/gotd/td/tg"
+
+	"github.com/gotd/cli/internal/output"
 )
 
 // randomID returns a random int64 suitable for MTProto random_id fields.
@@ -33,6 +35,9 @@ type topicsResult struct {
 	Topics []topicItem `json:"topics"`
 }
 
+// Items implements output.ItemLister.
+func (r topicsResult) Items() []any { return output.Items(r.Topics) }
+
 // MarshalText renders one topic per line.
 func (r topicsResult) MarshalText(w io.Writer) error {
 	for _, t := range r.Topics {
@@ -62,7 +67,7 @@ func (a *app) newTopicsListCmd() *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -80,7 +85,7 @@ func (a *app) newTopicsListCmd() *cobra.Command {
 						out.Topics = append(out.Topics, topicItem{ID: t.ID, Title: t.Title})
 					}
 				}
-				return a.printer.Emit(out)
+				return a.out(ctx).Emit(out)
 			})
 		},
 	}
@@ -98,7 +103,7 @@ func (a *app) newTopicsCreateCmd() *cobra.Command {
 				return err
 			}
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -113,7 +118,7 @@ func (a *app) newTopicsCreateCmd() *cobra.Command {
 				}); err != nil {
 					return errors.Wrap(err, "messages.createForumTopic")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
@@ -127,7 +132,7 @@ func (a *app) newTopicsEnableCmd() *cobra.Command {
 		ValidArgsFunction: peerArgCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.run(cmd.Context(), runParams{auth: authUser}, func(ctx context.Context, api *tg.Client) error {
-				m, err := a.manager(api)
+				m, err := a.manager(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -141,7 +146,7 @@ func (a *app) newTopicsEnableCmd() *cobra.Command {
 				}); err != nil {
 					return errors.Wrap(err, "channels.toggleForum")
 				}
-				return a.printer.Emit(okResult{OK: true})
+				return a.out(ctx).Emit(okResult{OK: true})
 			})
 		},
 	}
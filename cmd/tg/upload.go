@@ -252,7 +252,7 @@ The document type is detected from the file's MIME type unless --type is set.`,
 				upld := uploader.NewUploader(api).
 					WithThreads(uf.threads).
 					WithPartSize(uploader.MaximumPartSize)
-				sender, m, err := a.sender(api)
+				sender, m, err := a.sender(ctx, api)
 				if err != nil {
 					return err
 				}
@@ -262,7 +262,7 @@ The document type is detected from the file's MIME type unless --type is set.`,
 				if err != nil {
 					return err
 				}
-				quiet := a.printer.Format() == output.JSON
+				quiet := a.out(ctx).Format() == output.JSON
 
 				var result uploadResult
 				if err := filepath.Walk(arg, func(path string, info fs.FileInfo, err error) error {
@@ -279,7 +279,7 @@ The document type is detected from the file's MIME type unless --type is set.`,
 				}); err != nil {
 					return err
 				}
-				return a.printer.Emit(result)
+				return a.out(ctx).Emit(result)
 			})
 		},
 	}
@@ -59,7 +59,7 @@ func (a *app) newWaitCmd() *cobra.Command {
 					case <-timer:
 						return errors.New("timeout waiting for message")
 					case ev := <-events:
-						return a.printer.Emit(ev)
+						return a.out(ctx).Emit(ev)
 					}
 				})
 		},
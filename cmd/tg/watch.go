@@ -39,6 +39,7 @@ func (s *messageStream) handle(msg tg.MessageClass, e tg.Entities) {
 	}
 	ent := peer.EntitiesFromUpdate(e)
 	ev := watchEvent{Peer: describePeer(m.PeerID, ent), Message: buildMessageItem(m, ent)}
+	ev.Message.Peer = nil // already the event's
 	if s.filterID != 0 && ev.Peer.ID != s.filterID {
 		return
 	}
@@ -76,15 +77,18 @@ func (a *app) resolveFilterFor(ctx context.Context, api *tg.Client, st *accountS
 	return p.ID(), nil
 }
 
+// watchLine is one JSON line of `tg watch`: the event itself, not wrapped in
+// an envelope, labeled with its account in a multi-account watch.
+type watchLine struct {
+	Account string `json:"account,omitempty"`
+	watchEvent
+}
+
 // emitLine writes one streamed event (JSON line or text line) to stdout. When
 // account is non-empty (multi-account watch) it is included.
 func emitLine(format output.Format, account string, ev watchEvent) {
-	if format == output.JSON {
-		payload := struct {
-			Account string `json:"account,omitempty"`
-			watchEvent
-		}{Account: account, watchEvent: ev}
-		b, err := json.Marshal(payload)
+	if format == output.JSON || format == output.JSONL {
+		b, err := json.Marshal(watchLine{Account: account, watchEvent: ev})
 		if err != nil {
 			return
 		}
@@ -177,6 +181,10 @@ func (a *app) watchWith(ctx context.Context, st *accountState, header string, ar
 						mu.Lock()
 						defer mu.Unlock()
 					}
+					if a.printer.Templated() {
+						_ = a.printer.Emit(ev)
+						return
+					}
 					emitLine(format, header, ev)
 				},
 			}
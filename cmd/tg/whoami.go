@@ -100,7 +100,7 @@ Useful to confirm authentication and as a minimal end-to-end check.`,
 				auth = authBot
 			}
 			return a.run(cmd.Context(), runParams{auth: auth}, func(ctx context.Context, api *tg.Client) error {
-				return runWhoami(ctx, api, a.printer)
+				return runWhoami(ctx, api, a.out(ctx))
 			})
 		},
 	}
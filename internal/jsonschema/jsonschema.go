@@ -0,0 +1,141 @@
+// Package jsonschema derives JSON Schema documents from Go result structs.
+//
+// The CLI's JSON contract is defined by the struct tags of its result types,
+// so the schema is generated from those same types by reflection rather than
+// written by hand: editing a struct tag changes the published schema, which a
+// golden test can catch. Only what encoding/json produces is modeled — field
+// names from json tags, omitempty as "not required", nil slices/pointers as
+// null — which is all the result types use.
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect of generated documents.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a (subset of a) JSON Schema document.
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	ID          string `json:"$id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// Type is a type name, or a list of names for nullable values.
+	Type   any    `json:"type,omitempty"`
+	Format string `json:"format,omitempty"`
+	Const  any    `json:"const,omitempty"`
+
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// For returns the schema of the JSON encoding of v's type.
+func For(v any) *Schema {
+	return reflectType(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+//nolint:gochecknoglobals // immutable type handles
+var (
+	timeType    = reflect.TypeFor[time.Time]()
+	rawType     = reflect.TypeFor[json.RawMessage]()
+	marshalType = reflect.TypeFor[json.Marshaler]()
+)
+
+func reflectType(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawType, t.Implements(marshalType):
+		// Custom encodings can be anything.
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(reflectType(t.Elem(), seen))
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is base64 text.
+			return &Schema{Type: []string{"string", "null"}, Format: "base64"}
+		}
+		return &Schema{Type: []string{"array", "null"}, Items: reflectType(t.Elem(), seen)}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: reflectType(t.Elem(), seen)}
+	case reflect.Map:
+		return &Schema{Type: []string{"object", "null"}, AdditionalProperties: reflectType(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			// Recursive type: leave the inner occurrence unconstrained.
+			return &Schema{}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		addFields(s, t, seen)
+		return s
+	default:
+		// Interfaces and anything else: any JSON value.
+		return &Schema{}
+	}
+}
+
+// addFields adds the exported fields of struct type t to s, flattening
+// embedded structs the way encoding/json does.
+func addFields(s *Schema, t reflect.Type, seen map[reflect.Type]bool) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(s, ft, seen)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = reflectType(ft, seen)
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// nullable widens s to also accept null.
+func nullable(s *Schema) *Schema {
+	if v, ok := s.Type.(string); ok {
+		s.Type = []string{v, "null"}
+	}
+	return s
+}
@@ -0,0 +1,73 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	ID int64 `json:"id"`
+}
+
+type Embedded struct {
+	Kind string `json:"kind"`
+}
+
+type sample struct {
+	Embedded
+	Name    string            `json:"name"`
+	Note    string            `json:"note,omitempty"`
+	Tags    []string          `json:"tags"`
+	Meta    map[string]int    `json:"meta,omitempty"`
+	Peer    *inner            `json:"peer"`
+	When    time.Time         `json:"when"`
+	Raw     json.RawMessage   `json:"raw,omitempty"`
+	Skipped string            `json:"-"`
+	hidden  string            //nolint:unused // must not appear
+	Nested  map[string]*inner `json:"nested,omitempty"`
+}
+
+func TestFor(t *testing.T) {
+	s := For(sample{})
+	if s.Type != "object" {
+		t.Fatalf("type = %v, want object", s.Type)
+	}
+	wantRequired := []string{"kind", "name", "tags", "peer", "when"}
+	if !reflect.DeepEqual(s.Required, wantRequired) {
+		t.Errorf("required = %v, want %v", s.Required, wantRequired)
+	}
+	for _, name := range []string{"Skipped", "-", "hidden", "Embedded"} {
+		if _, ok := s.Properties[name]; ok {
+			t.Errorf("unexpected property %q", name)
+		}
+	}
+
+	for _, tc := range []struct {
+		prop string
+		want any
+	}{
+		{"kind", "string"},
+		{"tags", []string{"array", "null"}},
+		{"meta", []string{"object", "null"}},
+		{"peer", []string{"object", "null"}},
+		{"when", "string"},
+		{"raw", nil},
+	} {
+		got := s.Properties[tc.prop]
+		if got == nil {
+			t.Errorf("missing property %q", tc.prop)
+			continue
+		}
+		if !reflect.DeepEqual(got.Type, tc.want) {
+			t.Errorf("%s: type = %v, want %v", tc.prop, got.Type, tc.want)
+		}
+	}
+	if f := s.Properties["when"].Format; f != "date-time" {
+		t.Errorf("when: format = %q, want date-time", f)
+	}
+	if id := s.Properties["peer"].Properties["id"]; id == nil || id.Type != "integer" {
+		t.Errorf("peer.id = %+v, want integer", id)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix && !windows
+
+package lockedfile
+
+import "os"
+
+// Platforms without file locking (e.g. wasm) run a single process; WriteFile
+// still keeps writes atomic.
+
+func lockFile(*os.File) error { return nil }
+
+func unlockFile(*os.File) error { return nil }
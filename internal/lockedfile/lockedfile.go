@@ -0,0 +1,112 @@
+// Package lockedfile coordinates writes to small state files (sessions, the
+// peer cache) shared by concurrent tg processes.
+//
+// Agents often run several tg invocations against the same account at once.
+// Writers take an exclusive inter-process lock (Lock) around their
+// read-modify-write cycle, and replace files atomically (WriteFile), so a
+// reader never observes a truncated file and writers never interleave.
+package lockedfile
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/go-faster/errors"
+)
+
+// Lock takes an exclusive inter-process lock guarding path, blocking until it
+// is available, and returns the function that releases it. The lock is held on
+// a sidecar "<path>.lock" file rather than path itself, so path can be
+// replaced by WriteFile while the lock is held.
+func Lock(path string) (unlock func() error, err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, errors.Wrap(err, "create lock dir")
+	}
+	for {
+		f, err := os.OpenFile(lockPath(path), os.O_CREATE|os.O_RDWR, 0o600) // #nosec G304 // derived from a state file path
+		if err != nil {
+			return nil, errors.Wrap(err, "open lock file")
+		}
+		if err := lockFile(f); err != nil {
+			_ = f.Close()
+			return nil, errors.Wrap(err, "lock")
+		}
+		unlock := func() error {
+			if err := unlockFile(f); err != nil {
+				_ = f.Close()
+				return errors.Wrap(err, "unlock")
+			}
+			return f.Close()
+		}
+		// Remove may have deleted the sidecar while we waited for it: the lock
+		// only counts on the file currently at the sidecar path.
+		held, err := f.Stat()
+		if err != nil {
+			_ = unlock()
+			return nil, errors.Wrap(err, "stat lock file")
+		}
+		if cur, err := os.Stat(lockPath(path)); err == nil && os.SameFile(held, cur) {
+			return unlock, nil
+		}
+		if err := unlock(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Remove deletes path under its lock, and the lock's sidecar file with it, so
+// deleting state leaves nothing behind. A missing path is not an error.
+func Remove(path string) error {
+	unlock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = unlock() }()
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// Best effort: where an open file cannot be removed (Windows), the
+	// sidecar stays and is reused.
+	_ = os.Remove(lockPath(path))
+	return nil
+}
+
+func lockPath(path string) string { return path + ".lock" }
+
+// WriteFile atomically replaces path with data: the data is written to a
+// temporary file in the same directory, synced, and renamed over path.
+// Readers see either the old or the new contents, never a partial write.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return errors.Wrap(err, "create temp file")
+	}
+	tmp := f.Name()
+	cleanup := func() { _ = os.Remove(tmp) }
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		cleanup()
+		return errors.Wrap(err, "write temp file")
+	}
+	if err := f.Chmod(perm); err != nil {
+		_ = f.Close()
+		cleanup()
+		return errors.Wrap(err, "chmod temp file")
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		cleanup()
+		return errors.Wrap(err, "sync temp file")
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return errors.Wrap(err, "close temp file")
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		cleanup()
+		return errors.Wrap(err, "replace file")
+	}
+	return nil
+}
@@ -2,43 +2,51 @@
 //
 // Commands produce a typed result and hand it to a Printer. In JSON mode the
 // result is wrapped in a stable envelope ({"schema":N,"data":...}) so agents can
-// parse it; in text mode a human-friendly rendering is used. All machine output
-// goes to stdout; logs and progress must go to stderr.
+// parse it; in JSONL mode list results are written one compact envelope per
+// item, as they are fetched; CSV and TSV render tabular results (see Tabler)
+// as a header plus rows; in text mode a human-friendly rendering is used.
+// All machine output goes to stdout; logs and progress must go to stderr.
 package output
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
+	"text/template"
 
 	"github.com/go-faster/errors"
 )
 
-// schemaVersion is the JSON envelope version. Bump on breaking output changes.
-const schemaVersion = 1
+// SchemaVersion is the JSON envelope version. Bump on breaking output changes.
+const SchemaVersion = 1
 
 // Format is the output format selected by the global --output flag.
 type Format string
 
 // Supported formats.
 const (
-	Text Format = "text"
-	JSON Format = "json"
+	Text  Format = "text"
+	JSON  Format = "json"
+	JSONL Format = "jsonl"
+	CSV   Format = "csv"
+	TSV   Format = "tsv"
 )
 
 // Formats lists the valid output formats (for completion and validation).
-func Formats() []string { return []string{string(Text), string(JSON)} }
+func Formats() []string {
+	return []string{string(Text), string(JSON), string(JSONL), string(CSV), string(TSV)}
+}
 
 // ParseFormat validates and returns the format for s.
 func ParseFormat(s string) (Format, error) {
-	switch Format(s) {
-	case Text:
-		return Text, nil
-	case JSON:
-		return JSON, nil
+	switch f := Format(s); f {
+	case Text, JSON, JSONL, CSV, TSV:
+		return f, nil
 	default:
-		return "", errors.Errorf("unknown output format %q (want %s)", s, strings.Join(Formats(), " or "))
+		return "", errors.Errorf("unknown output format %q (want %s)", s, strings.Join(Formats(), ", "))
 	}
 }
 
@@ -48,11 +56,37 @@ type TextMarshaler interface {
 	MarshalText(w io.Writer) error
 }
 
+// ItemLister is implemented by list results. In JSONL mode a result that was
+// built in one go (rather than streamed with Item) is still written one item
+// per line.
+type ItemLister interface {
+	Items() []any
+}
+
+// Items converts a typed slice for ItemLister implementations.
+func Items[T any](s []T) []any {
+	out := make([]any, len(s))
+	for i, v := range s {
+		out[i] = v
+	}
+	return out
+}
+
 // Printer writes command results in the configured format.
 type Printer struct {
 	format  Format
 	w       io.Writer
 	account string // optional account label, included in JSON / text headers
+	open    bool   // between Begin and End
+
+	tmpl *template.Template // optional --template, overrides the format
+
+	// Forks (see Fork) write through mu; a JSON fork keeps its results in
+	// captured, others hold their output in held until Flush.
+	mu       sync.Mutex
+	capture  bool
+	captured []any
+	held     *bytes.Buffer
 }
 
 // New returns a Printer writing to w in the given format.
@@ -75,13 +109,33 @@ type envelope struct {
 
 // Emit writes a single result value.
 func (p *Printer) Emit(v any) error {
-	if p.format == JSON {
+	if p.capture {
+		p.captured = append(p.captured, v)
+		return nil
+	}
+	if p.tmpl != nil {
+		return p.execTemplate(v)
+	}
+	switch p.format {
+	case JSONL:
+		if l, ok := v.(ItemLister); ok {
+			for _, item := range l.Items() {
+				if err := p.line(item); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return p.line(v)
+	case JSON:
 		enc := json.NewEncoder(p.w)
 		enc.SetIndent("", "  ")
-		if err := enc.Encode(envelope{Schema: schemaVersion, Account: p.account, Data: v}); err != nil {
+		if err := enc.Encode(envelope{Schema: SchemaVersion, Account: p.account, Data: v}); err != nil {
 			return errors.Wrap(err, "encode json")
 		}
 		return nil
+	case CSV, TSV:
+		return p.table(v)
 	}
 
 	if p.account != "" {
@@ -93,8 +147,201 @@ func (p *Printer) Emit(v any) error {
 	if tm, ok := v.(TextMarshaler); ok {
 		return tm.MarshalText(p.w)
 	}
+	if t, ok := v.(Tabler); ok {
+		return t.Table().writeText(p.w)
+	}
 	if _, err := fmt.Fprintln(p.w, v); err != nil {
 		return errors.Wrap(err, "write text")
 	}
 	return nil
 }
+
+// table writes v as CSV/TSV. Results that are not Tablers become a single row
+// of their top-level fields.
+func (p *Printer) table(v any) error {
+	var t Table
+	if tb, ok := v.(Tabler); ok {
+		t = tb.Table()
+	} else {
+		var err error
+		if t, err = objectTable(v); err != nil {
+			return err
+		}
+	}
+	if p.account != "" {
+		t = t.withAccount(p.account)
+	}
+	return t.writeCSV(p.w, p.format == TSV)
+}
+
+// line writes v as one compact JSONL envelope.
+func (p *Printer) line(v any) error {
+	if err := json.NewEncoder(p.w).Encode(envelope{Schema: SchemaVersion, Account: p.account, Data: v}); err != nil {
+		return errors.Wrap(err, "encode json line")
+	}
+	return nil
+}
+
+// Streaming reports whether list items are written as they arrive (JSONL), in
+// which case callers need not retain them. A template always sees the full
+// result, so it disables streaming.
+func (p *Printer) Streaming() bool { return p.format == JSONL && p.tmpl == nil }
+
+// Templated reports whether results are rendered through a --template.
+func (p *Printer) Templated() bool { return p.tmpl != nil }
+
+// Begin starts a streamed list result. Items pushed with Item are written
+// immediately in JSONL mode; other formats render the complete result passed
+// to End.
+func (p *Printer) Begin() { p.open = true }
+
+// Item pushes one list item between Begin and End. It is a no-op unless the
+// printer is Streaming.
+func (p *Printer) Item(v any) error {
+	if !p.open {
+		return errors.New("output: Item called outside Begin/End")
+	}
+	if !p.Streaming() {
+		return nil
+	}
+	return p.line(v)
+}
+
+// End finishes a streamed list result. In JSONL mode the items were already
+// written by Item; otherwise v (the full result) is emitted like Emit.
+func (p *Printer) End(v any) error {
+	p.open = false
+	if p.Streaming() {
+		return nil
+	}
+	return p.Emit(v)
+}
+
+// Collect returns an item callback for list builders: each item is streamed
+// through p, and retained in *dst only when p renders the complete result.
+func Collect[T any](p *Printer, dst *[]T) func(T) error {
+	return func(v T) error {
+		if p.Streaming() {
+			return p.Item(v)
+		}
+		*dst = append(*dst, v)
+		return nil
+	}
+}
+
+// ErrorInfo describes a failed command in the error envelope.
+type ErrorInfo struct {
+	// Code is the stable error class, e.g. "not_authorized" or "flood_wait".
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// RPCError is the Telegram RPC error type (e.g. "PEER_ID_INVALID"), if
+	// the failure came from the server.
+	RPCError string `json:"rpc_error,omitempty"`
+	// RetryAfter is the number of seconds to wait before retrying, for
+	// flood-wait errors.
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+type errorEnvelope struct {
+	Schema  int       `json:"schema"`
+	Account string    `json:"account,omitempty"`
+	Error   ErrorInfo `json:"error"`
+}
+
+// EmitError writes a failure as {"schema":N,"error":{...}} in JSON and JSONL
+// modes, so agents can branch on it without parsing stderr. Other formats
+// write nothing: the human-readable message goes to stderr.
+func (p *Printer) EmitError(e ErrorInfo) error {
+	if p.capture {
+		return nil // reported by EmitAccounts
+	}
+	env := errorEnvelope{Schema: SchemaVersion, Account: p.account, Error: e}
+	switch p.format {
+	case JSON:
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(env); err != nil {
+			return errors.Wrap(err, "encode json error")
+		}
+	case JSONL:
+		if err := json.NewEncoder(p.w).Encode(env); err != nil {
+			return errors.Wrap(err, "encode json error")
+		}
+	}
+	return nil
+}
+
+// lockedWriter serializes writes of concurrent forks.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l lockedWriter) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(b)
+}
+
+// Fork returns a printer for one account of a concurrent multi-account run,
+// labeled with account. Forks of one printer may be used concurrently: JSONL
+// lines are written as they come, JSON results are kept for EmitAccounts, and
+// other output is held until Flush, so accounts do not interleave.
+func (p *Printer) Fork(account string) *Printer {
+	f := &Printer{format: p.format, account: account, tmpl: p.tmpl}
+	switch {
+	case p.Streaming():
+		f.w = lockedWriter{mu: &p.mu, w: p.w}
+	case p.format == JSON && p.tmpl == nil:
+		f.capture = true
+	default:
+		f.held = new(bytes.Buffer)
+		f.w = f.held
+	}
+	return f
+}
+
+// Captured returns the results a JSON fork kept: nil, the single result, or
+// all of them in order.
+func (p *Printer) Captured() any {
+	switch len(p.captured) {
+	case 0:
+		return nil
+	case 1:
+		return p.captured[0]
+	default:
+		return p.captured
+	}
+}
+
+// Flush writes the held output of forks, in order.
+func (p *Printer) Flush(forks ...*Printer) error {
+	for _, f := range forks {
+		if f.held == nil {
+			continue
+		}
+		if _, err := f.held.WriteTo(p.w); err != nil {
+			return errors.Wrap(err, "write")
+		}
+	}
+	return nil
+}
+
+// AccountResult is the outcome of one account in a multi-account document:
+// its result, or why it failed.
+type AccountResult struct {
+	Data  any        `json:"data,omitempty"`
+	Error *ErrorInfo `json:"error,omitempty"`
+}
+
+// EmitAccounts writes the results of a multi-account run as one document,
+// {"schema":N,"data":{"<account>":{"data":...}|{"error":{...}}}}. It is for
+// JSON mode; other formats write through forks.
+func (p *Printer) EmitAccounts(results map[string]AccountResult) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(envelope{Schema: SchemaVersion, Data: results}); err != nil {
+		return errors.Wrap(err, "encode json")
+	}
+	return nil
+}
@@ -24,6 +24,9 @@ func TestParseFormat(t *testing.T) {
 	}{
 		{"text", Text, false},
 		{"json", JSON, false},
+		{"jsonl", JSONL, false},
+		{"csv", CSV, false},
+		{"tsv", TSV, false},
 		{"yaml", "", true},
 		{"", "", true},
 	} {
@@ -66,3 +69,132 @@ func TestPrinterTextUsesMarshaler(t *testing.T) {
 		t.Errorf("text = %q, want %q", got, "name=durov")
 	}
 }
+
+func TestPrinterEmitError(t *testing.T) {
+	info := ErrorInfo{Code: "flood_wait", Message: "wait", RPCError: "FLOOD_WAIT", RetryAfter: 30}
+
+	var buf bytes.Buffer
+	if err := New(JSONL, &buf).EmitError(info); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"schema":1,"error":{"code":"flood_wait","message":"wait","rpc_error":"FLOOD_WAIT","retry_after":30}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("jsonl = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := New(Text, &buf).EmitError(info); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("text mode wrote %q, want nothing", buf.String())
+	}
+}
+
+type sampleList struct {
+	Samples []sample `json:"samples"`
+}
+
+func (l sampleList) Items() []any { return Items(l.Samples) }
+
+func TestPrinterJSONLItemLister(t *testing.T) {
+	var buf bytes.Buffer
+	list := sampleList{Samples: []sample{{Name: "a"}, {Name: "b"}}}
+	if err := New(JSONL, &buf).Emit(list); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"schema":1,"data":{"name":"a"}}` + "\n" + `{"schema":1,"data":{"name":"b"}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("jsonl = %q, want %q", got, want)
+	}
+}
+
+func TestPrinterStream(t *testing.T) {
+	for _, tc := range []struct {
+		format Format
+		want   string
+	}{
+		{JSONL, `{"schema":1,"data":{"name":"a"}}` + "\n" + `{"schema":1,"data":{"name":"b"}}` + "\n"},
+		{Text, "{[{a} {b}]}\n"},
+	} {
+		var (
+			buf  bytes.Buffer
+			list sampleList
+		)
+		p := New(tc.format, &buf)
+		p.Begin()
+		each := Collect(p, &list.Samples)
+		for _, name := range []string{"a", "b"} {
+			if err := each(sample{Name: name}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if p.Streaming() && len(list.Samples) != 0 {
+			t.Errorf("%s: streamed items were retained", tc.format)
+		}
+		if err := p.End(list); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%s: output = %q, want %q", tc.format, got, tc.want)
+		}
+	}
+}
+
+func TestPrinterItemOutsideStream(t *testing.T) {
+	if err := New(JSONL, io.Discard).Item(sample{}); err == nil {
+		t.Fatal("expected error for Item without Begin")
+	}
+}
+
+func TestPrinterFork(t *testing.T) {
+	var buf bytes.Buffer
+	p := New(Text, &buf)
+	a, b := p.Fork("a"), p.Fork("b")
+	if err := b.Emit(sample{Name: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Emit(sample{Name: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("text fork wrote before Flush: %q", buf.String())
+	}
+	if err := p.Flush(a, b); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "== a ==\nname=1== b ==\nname=2"; got != want {
+		t.Errorf("flushed = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	p = New(JSONL, &buf)
+	if err := p.Fork("a").Emit(sample{Name: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `{"schema":1,"account":"a","data":{"name":"1"}}`+"\n"; got != want {
+		t.Errorf("jsonl fork = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	p = New(JSON, &buf)
+	a = p.Fork("a")
+	if err := a.Emit(sample{Name: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("json fork wrote: %q", buf.String())
+	}
+	err := p.EmitAccounts(map[string]AccountResult{
+		"a": {Data: a.Captured()},
+		"b": {Error: &ErrorInfo{Code: "not_authorized", Message: "revoked"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"a": {`, `"name": "1"`, `"b": {`, `"code": "not_authorized"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("document lacks %q:\n%s", want, buf.String())
+		}
+	}
+}
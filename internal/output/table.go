@@ -0,0 +1,208 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-faster/errors"
+)
+
+// Column is one column of a tabular result: a header name and how to render a
+// row's cell. Width, if positive, truncates the cell in text mode only; CSV and
+// TSV always carry the full value.
+type Column[T any] struct {
+	Name  string
+	Width int
+	Value func(T) string
+}
+
+// Table is a rendered tabular result: a header row plus one row per item.
+type Table struct {
+	Header []string
+	Widths []int
+	Rows   [][]string
+}
+
+// Tabler is implemented by results that render as a table. Declaring the
+// columns once lets text, CSV and TSV output derive from the same definition.
+// A result that also implements TextMarshaler keeps its custom text form.
+type Tabler interface {
+	Table() Table
+}
+
+// NewTable renders items through the column definitions.
+func NewTable[T any](cols []Column[T], items []T) Table {
+	t := Table{
+		Header: make([]string, len(cols)),
+		Widths: make([]int, len(cols)),
+		Rows:   make([][]string, 0, len(items)),
+	}
+	for i, c := range cols {
+		t.Header[i] = c.Name
+		t.Widths[i] = c.Width
+	}
+	for _, item := range items {
+		row := make([]string, len(cols))
+		for i, c := range cols {
+			row[i] = c.Value(item)
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+// withAccount prepends an account column, so multi-account CSV stays a single
+// well-formed table per account instead of gaining a text header line.
+func (t Table) withAccount(label string) Table {
+	out := Table{
+		Header: append([]string{"account"}, t.Header...),
+		Widths: append([]int{0}, t.Widths...),
+		Rows:   make([][]string, len(t.Rows)),
+	}
+	for i, r := range t.Rows {
+		out.Rows[i] = append([]string{label}, r...)
+	}
+	return out
+}
+
+// writeCSV writes t as RFC 4180 CSV, or as TSV when tsv is set.
+func (t Table) writeCSV(w io.Writer, tsv bool) error {
+	if tsv {
+		return t.writeTSV(w)
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Header); err != nil {
+		return errors.Wrap(err, "write csv header")
+	}
+	if err := cw.WriteAll(t.Rows); err != nil {
+		return errors.Wrap(err, "write csv")
+	}
+	return nil
+}
+
+// tsvEscaper escapes the characters TSV cannot carry, using the common
+// backslash convention (as in PostgreSQL's text COPY format).
+var tsvEscaper = strings.NewReplacer( //nolint:gochecknoglobals // immutable, stateless replacer
+	`\`, `\\`,
+	"\t", `\t`,
+	"\n", `\n`,
+	"\r", `\r`,
+)
+
+func (t Table) writeTSV(w io.Writer) error {
+	write := func(cells []string) error {
+		escaped := make([]string, len(cells))
+		for i, c := range cells {
+			escaped[i] = tsvEscaper.Replace(c)
+		}
+		_, err := io.WriteString(w, strings.Join(escaped, "\t")+"\n")
+		return err
+	}
+	if err := write(t.Header); err != nil {
+		return errors.Wrap(err, "write tsv header")
+	}
+	for _, r := range t.Rows {
+		if err := write(r); err != nil {
+			return errors.Wrap(err, "write tsv")
+		}
+	}
+	return nil
+}
+
+// writeText writes t as aligned columns for humans. Cells are collapsed onto
+// one line so embedded tabs or newlines cannot break the alignment.
+func (t Table) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	line := func(cells []string, upper bool) error {
+		out := make([]string, len(cells))
+		for i, c := range cells {
+			c = strings.Join(strings.Fields(c), " ")
+			if upper {
+				c = strings.ToUpper(c)
+			} else if i < len(t.Widths) && t.Widths[i] > 0 {
+				c = truncate(c, t.Widths[i])
+			}
+			out[i] = c
+		}
+		_, err := fmt.Fprintln(tw, strings.Join(out, "\t"))
+		return err
+	}
+	if err := line(t.Header, true); err != nil {
+		return errors.Wrap(err, "write table header")
+	}
+	for _, r := range t.Rows {
+		if err := line(r, false); err != nil {
+			return errors.Wrap(err, "write table")
+		}
+	}
+	return tw.Flush()
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+// n <= 0 leaves nothing.
+func truncate(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// objectTable renders a non-tabular result as a single row with one column per
+// top-level JSON field (in declaration order). Nested values are kept as
+// compact JSON.
+func objectTable(v any) (Table, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return Table{}, errors.Wrap(err, "encode json")
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return Table{}, errors.Wrap(err, "decode json")
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		// Scalars and arrays: a single "value" column.
+		return Table{Header: []string{"value"}, Rows: [][]string{{strings.TrimSpace(string(raw))}}}, nil
+	}
+
+	var (
+		header []string
+		row    []string
+	)
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return Table{}, errors.Wrap(err, "decode json")
+		}
+		var val json.RawMessage
+		if err := dec.Decode(&val); err != nil {
+			return Table{}, errors.Wrap(err, "decode json")
+		}
+		header = append(header, fmt.Sprint(key))
+		row = append(row, cellString(val))
+	}
+	return Table{Header: header, Rows: [][]string{row}}, nil
+}
+
+// cellString renders a JSON value as a cell: strings unquoted, null empty,
+// everything else as compact JSON.
+func cellString(val json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(val, &s); err == nil {
+		return s
+	}
+	if string(val) == "null" {
+		return ""
+	}
+	return string(val)
+}
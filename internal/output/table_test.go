@@ -0,0 +1,74 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+type row struct {
+	Name string `json:"name"`
+	Note string `json:"note"`
+}
+
+type rows []row
+
+func (r rows) Table() Table {
+	return NewTable([]Column[row]{
+		{Name: "name", Value: func(v row) string { return v.Name }},
+		{Name: "note", Width: 6, Value: func(v row) string { return v.Note }},
+	}, r)
+}
+
+var tricky = rows{{Name: "a,b", Note: "line1\nline2\tx"}, {Name: `q"uote`, Note: "short"}}
+
+func TestPrinterCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := New(CSV, &buf).Emit(tricky); err != nil {
+		t.Fatal(err)
+	}
+	want := "name,note\n\"a,b\",\"line1\nline2\tx\"\n\"q\"\"uote\",short\n"
+	if got := buf.String(); got != want {
+		t.Errorf("csv = %q, want %q", got, want)
+	}
+}
+
+func TestPrinterTSV(t *testing.T) {
+	var buf bytes.Buffer
+	p := New(TSV, &buf)
+	p.SetAccount("work")
+	if err := p.Emit(tricky); err != nil {
+		t.Fatal(err)
+	}
+	want := "account\tname\tnote\nwork\ta,b\tline1\\nline2\\tx\nwork\tq\"uote\tshort\n"
+	if got := buf.String(); got != want {
+		t.Errorf("tsv = %q, want %q", got, want)
+	}
+}
+
+func TestPrinterTextTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := New(Text, &buf).Emit(tricky); err != nil {
+		t.Fatal(err)
+	}
+	// Whitespace is collapsed and the note column truncated to its width.
+	want := "NAME    NOTE\na,b     line1…\nq\"uote  short\n"
+	if got := buf.String(); got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+}
+
+func TestPrinterCSVObject(t *testing.T) {
+	var buf bytes.Buffer
+	v := struct {
+		ID   int      `json:"id"`
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}{ID: 1, Name: "x", Tags: []string{"a"}}
+	if err := New(CSV, &buf).Emit(v); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,name,tags\n1,x,\"[\"\"a\"\"]\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("csv = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-faster/errors"
+)
+
+// TemplateFuncs returns the helper functions available to --template, on top
+// of text/template's builtins. Callers may add their own (e.g. peer labels)
+// before parsing.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// truncate shortens a string to n runes: {{.Text | truncate 40}}.
+		"truncate": func(n int, s string) string { return truncate(s, n) },
+		// date renders a unix timestamp as RFC 3339: {{date .Date}}.
+		"date": func(unix int) string {
+			if unix == 0 {
+				return ""
+			}
+			return time.Unix(int64(unix), 0).Format(time.RFC3339)
+		},
+		// datefmt renders a unix timestamp with a Go layout:
+		// {{datefmt "2006-01-02" .Date}}.
+		"datefmt": func(layout string, unix int) string {
+			if unix == 0 {
+				return ""
+			}
+			return time.Unix(int64(unix), 0).Format(layout)
+		},
+		// json renders a value as compact JSON: {{json .Peer}}.
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return "", err
+			}
+			return string(b), nil
+		},
+		// oneline collapses whitespace (newlines, tabs) into single spaces.
+		"oneline": func(s string) string { return strings.Join(strings.Fields(s), " ") },
+		// pad left-justifies s to n runes: {{pad 24 .Title}}.
+		"pad": func(n int, s string) string {
+			if l := len([]rune(s)); l < n {
+				return s + strings.Repeat(" ", n-l)
+			}
+			return s
+		},
+		"join":  strings.Join,
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+	}
+}
+
+// ParseTemplate parses a --template text with TemplateFuncs plus extra.
+func ParseTemplate(text string, extra template.FuncMap) (*template.Template, error) {
+	funcs := TemplateFuncs()
+	for name, fn := range extra {
+		funcs[name] = fn
+	}
+	t, err := template.New("output").Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse template")
+	}
+	return t, nil
+}
+
+// SetTemplate makes the printer render every result through t instead of the
+// selected format. Results are passed as-is, so templates address Go field
+// names ({{.Peer.Username}}); a trailing newline is added when missing.
+func (p *Printer) SetTemplate(t *template.Template) { p.tmpl = t }
+
+// execTemplate renders v through the printer's template.
+func (p *Printer) execTemplate(v any) error {
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, v); err != nil {
+		return errors.Wrap(err, "execute template")
+	}
+	if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	if _, err := p.w.Write(buf.Bytes()); err != nil {
+		return errors.Wrap(err, "write template output")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrinterTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate(`{{range .}}{{.Name | upper}}|{{.Note | oneline | truncate 8}}{{"\n"}}{{end}}`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	p := New(JSONL, &buf)
+	p.SetTemplate(tmpl)
+	if p.Streaming() {
+		t.Error("templated printer must not stream")
+	}
+	if err := p.Emit(tricky); err != nil {
+		t.Fatal(err)
+	}
+	want := "A,B|line1 l…\nQ\"UOTE|short\n"
+	if got := buf.String(); got != want {
+		t.Errorf("template = %q, want %q", got, want)
+	}
+}
+
+func TestTemplateTrailingNewline(t *testing.T) {
+	tmpl, err := ParseTemplate(`{{date 0}}{{datefmt "2006" 86400}}`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	p := New(Text, &buf)
+	p.SetTemplate(tmpl)
+	if err := p.Emit(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "1970\n" {
+		t.Errorf("template = %q, want %q", got, "1970\n")
+	}
+}
+
+func TestTemplateTruncateNonPositive(t *testing.T) {
+	tmpl, err := ParseTemplate(`[{{"abc" | truncate 0}}{{"abc" | truncate -2}}{{"abc" | truncate 1}}]`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	p := New(Text, &buf)
+	p.SetTemplate(tmpl)
+	if err := p.Emit(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "[…]\n" {
+		t.Errorf("template = %q, want %q", got, "[…]\n")
+	}
+}
+
+func TestParseTemplateError(t *testing.T) {
+	if _, err := ParseTemplate(`{{nosuchfunc .}}`, nil); err == nil {
+		t.Fatal("expected error for unknown function")
+	}
+}
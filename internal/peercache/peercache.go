@@ -4,26 +4,58 @@
 // access-hashes would be lost between CLI invocations. This storage persists
 // them as JSON in the session directory, keyed per account, so peer resolution
 // is cheap and survives restarts.
+//
+// Saves are batched in memory and written by Flush (or Close), so applying the
+// entities of thousands of dialogs costs one file write rather than one per
+// peer. Several tg processes may share one cache. Writes take an inter-process
+// lock, re-read the file and apply only this process's changes on top of it,
+// so entries learned concurrently by another process are kept.
+//
+// A Codec (see OpenCodec) can transform the file at rest, e.g. encrypt it.
 package peercache
 
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"os"
+	"sort"
 	"strconv"
 	"sync"
 
 	"github.com/go-faster/errors"
 
 	"github.com/gotd/td/telegram/peers"
+
+	"github.com/gotd/cli/internal/lockedfile"
 )
 
 // Storage is a JSON-file-backed peers.Storage.
 type Storage struct {
-	path string
+	path  string
+	codec Codec
 
 	mu   sync.Mutex
 	data data
+	// dirty tracks the keys changed by this process since the last flush.
+	dirty changes
+}
+
+// changes is the set of keys modified in memory. A dirty key present in
+// memory is written to disk on flush; one absent from memory is deleted.
+type changes struct {
+	peers    map[string]bool
+	phones   map[string]bool
+	info     map[string]bool
+	contacts bool
+}
+
+func newChanges() changes {
+	return changes{peers: map[string]bool{}, phones: map[string]bool{}, info: map[string]bool{}}
+}
+
+func (c changes) empty() bool {
+	return len(c.peers) == 0 && len(c.phones) == 0 && len(c.info) == 0 && !c.contacts
 }
 
 type data struct {
@@ -33,63 +65,186 @@ type data struct {
 	Phones map[string]string `json:"phones"`
 	// ContactsHash is the cached contacts hash.
 	ContactsHash int64 `json:"contacts_hash"`
+	// Info maps "<kind>:<id>" to the peer's descriptive metadata.
+	Info map[string]Info `json:"info,omitempty"`
 }
 
+// Info is what the cache knows about a peer beyond its access hash: enough to
+// list, search and complete peers without a network round trip.
+type Info struct {
+	ID       int64  `json:"id"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name,omitempty"`
+	Username string `json:"username,omitempty"`
+	// LastSeen is the unix time of the peer's latest dialog activity seen by
+	// `tg chats list`, or zero if it was only resolved or listed elsewhere.
+	LastSeen int64 `json:"last_seen,omitempty"`
+}
+
+func (i Info) key() string { return i.Kind + ":" + strconv.FormatInt(i.ID, 10) }
+
 var _ peers.Storage = (*Storage)(nil)
 
+// Codec transforms the cache file at rest. Decode must also accept a file
+// written without the codec; it is rewritten encoded on the next flush.
+type Codec interface {
+	Encode(plain []byte) ([]byte, error)
+	Decode(raw []byte) ([]byte, error)
+}
+
 // Open loads the cache at path, creating an empty one if it does not exist.
 func Open(path string) (*Storage, error) {
-	s := &Storage{
-		path: path,
-		data: data{
-			Peers:  map[string]int64{},
-			Phones: map[string]string{},
-		},
+	return OpenCodec(path, nil)
+}
+
+// OpenCodec is Open for a cache file stored through codec; nil is plain JSON.
+func OpenCodec(path string, codec Codec) (*Storage, error) {
+	d, err := readData(path, codec)
+	if err != nil {
+		return nil, err
 	}
+	return &Storage{path: path, codec: codec, data: d, dirty: newChanges()}, nil
+}
 
+// readData reads the cache file at path; a missing file is an empty cache.
+func readData(path string, codec Codec) (data, error) {
+	d := data{
+		Peers:  map[string]int64{},
+		Phones: map[string]string{},
+		Info:   map[string]Info{},
+	}
 	raw, err := os.ReadFile(path) // #nosec G304 // path derived from config dir
 	if err != nil {
 		if os.IsNotExist(err) {
-			return s, nil
+			return d, nil
+		}
+		return data{}, errors.Wrap(err, "read peer cache")
+	}
+	if codec != nil && len(raw) > 0 {
+		if raw, err = codec.Decode(raw); err != nil {
+			return data{}, errors.Wrap(err, "decode peer cache")
 		}
-		return nil, errors.Wrap(err, "read peer cache")
 	}
 	if len(raw) > 0 {
-		if err := json.Unmarshal(raw, &s.data); err != nil {
-			return nil, errors.Wrap(err, "parse peer cache")
+		if err := json.Unmarshal(raw, &d); err != nil {
+			return data{}, errors.Wrap(err, "parse peer cache")
 		}
 	}
-	if s.data.Peers == nil {
-		s.data.Peers = map[string]int64{}
+	if d.Peers == nil {
+		d.Peers = map[string]int64{}
+	}
+	if d.Phones == nil {
+		d.Phones = map[string]string{}
 	}
-	if s.data.Phones == nil {
-		s.data.Phones = map[string]string{}
+	if d.Info == nil {
+		d.Info = map[string]Info{}
 	}
-	return s, nil
+	return d, nil
 }
 
 func keyString(k peers.Key) string {
 	return k.Prefix + ":" + strconv.FormatInt(k.ID, 10)
 }
 
-// flush persists the cache; caller must hold mu.
+// Flush writes the changes saved since the last flush, if any.
+func (s *Storage) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dirty.empty() {
+		return nil
+	}
+	return s.flush()
+}
+
+// Close flushes pending changes. The storage remains usable afterwards.
+func (s *Storage) Close() error { return s.Flush() }
+
+// flush persists the cache; caller must hold mu. Under the file lock it
+// re-reads the file, applies this process's changes on top and atomically
+// replaces it, then adopts the merged result.
 func (s *Storage) flush() error {
-	raw, err := json.Marshal(s.data)
+	unlock, err := lockedfile.Lock(s.path)
+	if err != nil {
+		return errors.Wrap(err, "lock peer cache")
+	}
+	defer func() { _ = unlock() }()
+
+	disk, err := readData(s.path, s.codec)
+	if err != nil {
+		return err
+	}
+	s.applyChanges(&disk)
+	raw, err := json.Marshal(disk)
 	if err != nil {
 		return errors.Wrap(err, "marshal peer cache")
 	}
-	if err := os.WriteFile(s.path, raw, 0o600); err != nil {
+	if s.codec != nil {
+		if raw, err = s.codec.Encode(raw); err != nil {
+			return errors.Wrap(err, "encode peer cache")
+		}
+	}
+	if err := lockedfile.WriteFile(s.path, raw, 0o600); err != nil {
 		return errors.Wrap(err, "write peer cache")
 	}
+	s.data = disk
+	s.dirty = newChanges()
 	return nil
 }
 
-// Save implements peers.Storage.
+// applyChanges replays the dirty keys of s onto d; caller must hold mu.
+func (s *Storage) applyChanges(d *data) {
+	for k := range s.dirty.peers {
+		if v, ok := s.data.Peers[k]; ok {
+			d.Peers[k] = v
+		} else {
+			delete(d.Peers, k)
+		}
+	}
+	for k := range s.dirty.phones {
+		if v, ok := s.data.Phones[k]; ok {
+			d.Phones[k] = v
+		} else {
+			delete(d.Phones, k)
+		}
+	}
+	for k := range s.dirty.info {
+		v, ok := s.data.Info[k]
+		if !ok {
+			delete(d.Info, k)
+			continue
+		}
+		if old, ok := d.Info[k]; ok {
+			v = mergeInfo(old, v)
+		}
+		d.Info[k] = v
+	}
+	if s.dirty.contacts {
+		d.ContactsHash = s.data.ContactsHash
+	}
+}
+
+// mergeInfo merges info into old: empty names and usernames do not erase known
+// ones, and LastSeen only moves forward.
+func mergeInfo(old, info Info) Info {
+	if info.Name == "" {
+		info.Name = old.Name
+	}
+	if info.Username == "" {
+		info.Username = old.Username
+	}
+	info.LastSeen = max(info.LastSeen, old.LastSeen)
+	return info
+}
+
+// Save implements peers.Storage. Like the other Save methods it only updates
+// memory; the change is written by the next Flush.
 func (s *Storage) Save(_ context.Context, key peers.Key, value peers.Value) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.data.Peers[keyString(key)] = value.AccessHash
-	return s.flush()
+	k := keyString(key)
+	s.data.Peers[k] = value.AccessHash
+	s.dirty.peers[k] = true
+	return nil
 }
 
 // Find implements peers.Storage.
@@ -117,11 +272,7 @@ func (s *Storage) Kind(id int64) (string, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	idStr := strconv.FormatInt(id, 10)
-	for _, kp := range []struct{ kind, prefix string }{
-		{KindUser, "users_"},
-		{KindChannel, "channel_"},
-		{KindChat, "chats_"},
-	} {
+	for _, kp := range kindPrefixes {
 		if _, ok := s.data.Peers[kp.prefix+":"+idStr]; ok {
 			return kp.kind, true
 		}
@@ -129,12 +280,41 @@ func (s *Storage) Kind(id int64) (string, bool) {
 	return "", false
 }
 
+// Has reports whether the access hash of the peer of the given kind is cached.
+func (s *Storage) Has(kind string, id int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.data.Peers[s.peerKey(kind, id)]
+	return ok
+}
+
+// kindPrefixes maps peer kinds to gotd's storage key prefixes, in Kind's
+// lookup order.
+//
+//nolint:gochecknoglobals // immutable lookup table
+var kindPrefixes = []struct{ kind, prefix string }{
+	{KindUser, "users_"},
+	{KindChannel, "channel_"},
+	{KindChat, "chats_"},
+}
+
+// prefixKind returns the peer kind of a storage key prefix.
+func prefixKind(prefix string) (string, bool) {
+	for _, kp := range kindPrefixes {
+		if kp.prefix == prefix {
+			return kp.kind, true
+		}
+	}
+	return "", false
+}
+
 // SavePhone implements peers.Storage.
 func (s *Storage) SavePhone(_ context.Context, phone string, key peers.Key) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.data.Phones[phone] = keyString(key)
-	return s.flush()
+	s.dirty.phones[phone] = true
+	return nil
 }
 
 // FindPhone implements peers.Storage.
@@ -177,5 +357,198 @@ func (s *Storage) SaveContactsHash(_ context.Context, hash int64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.data.ContactsHash = hash
-	return s.flush()
+	s.dirty.contacts = true
+	return nil
+}
+
+// SaveInfo records peer metadata, written by the next Flush. Entries are
+// merged with what is cached: empty names and usernames do not erase known
+// ones, and LastSeen only moves forward.
+func (s *Storage) SaveInfo(infos ...Info) error {
+	if len(infos) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, info := range infos {
+		if info.ID == 0 || info.Kind == "" {
+			continue
+		}
+		k := info.key()
+		if old, ok := s.data.Info[k]; ok {
+			info = mergeInfo(old, info)
+		}
+		s.data.Info[k] = info
+		s.dirty.info[k] = true
+	}
+	return nil
+}
+
+// Infos returns the cached peer metadata, most recently active first, then by
+// name.
+func (s *Storage) Infos() []Info {
+	s.mu.Lock()
+	out := make([]Info, 0, len(s.data.Info))
+	for _, info := range s.data.Info {
+		out = append(out, info)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.LastSeen != b.LastSeen {
+			return a.LastSeen > b.LastSeen
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.key() < b.key()
+	})
+	return out
+}
+
+// Entry is one cached peer: its metadata (if known) and whether its access
+// hash is stored, which is what "id:<n>" resolution needs.
+type Entry struct {
+	Info
+	HasAccessHash bool
+}
+
+// Entries returns every cached peer, whether it has metadata, an access hash
+// or both, in Infos order; peers without metadata come last, by id.
+func (s *Storage) Entries() []Entry {
+	infos := s.Infos()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Entry, 0, len(infos))
+	seen := map[string]bool{}
+	for _, info := range infos {
+		_, ok := s.data.Peers[s.peerKey(info.Kind, info.ID)]
+		out = append(out, Entry{Info: info, HasAccessHash: ok})
+		seen[info.key()] = true
+	}
+	var bare []Entry
+	for raw := range s.data.Peers {
+		key, err := parseKey(raw)
+		if err != nil {
+			continue
+		}
+		kind, ok := prefixKind(key.Prefix)
+		if !ok {
+			continue
+		}
+		info := Info{ID: key.ID, Kind: kind}
+		if seen[info.key()] {
+			continue
+		}
+		bare = append(bare, Entry{Info: info, HasAccessHash: true})
+	}
+	sort.Slice(bare, func(i, j int) bool { return bare[i].key() < bare[j].key() })
+	return append(out, bare...)
+}
+
+// peerKey returns the access-hash key of a peer; caller must hold mu.
+func (s *Storage) peerKey(kind string, id int64) string {
+	for _, kp := range kindPrefixes {
+		if kp.kind == kind {
+			return kp.prefix + ":" + strconv.FormatInt(id, 10)
+		}
+	}
+	return ""
+}
+
+// Remove drops the peers matching match: access hash, metadata and phone
+// numbers, and writes the result at once. It matches against the file's
+// current contents, so peers cached by other processes since Open can be
+// removed too. It returns the removed entries.
+func (s *Storage) Remove(match func(Entry) bool) ([]Entry, error) {
+	s.mu.Lock()
+	err := s.flush()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []Entry
+	for _, e := range s.Entries() {
+		if match(e) {
+			removed = append(removed, e)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range removed {
+		key := s.peerKey(e.Kind, e.ID)
+		delete(s.data.Peers, key)
+		delete(s.data.Info, e.key())
+		s.dirty.peers[key] = true
+		s.dirty.info[e.key()] = true
+		for phone, k := range s.data.Phones {
+			if k == key {
+				delete(s.data.Phones, phone)
+				s.dirty.phones[phone] = true
+			}
+		}
+	}
+	return removed, s.flush()
+}
+
+// Export writes the whole cache as JSON, in the on-disk format, so it can be
+// imported into the same account's cache on another machine.
+func (s *Storage) Export(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s.data); err != nil {
+		return errors.Wrap(err, "export peer cache")
+	}
+	return nil
+}
+
+// Import merges an exported cache into s and writes the result: imported
+// access hashes and phones win, metadata is merged as by SaveInfo. The
+// contacts hash is not imported: it tracks this cache's own contacts sync. It
+// returns the number of distinct peers read.
+func (s *Storage) Import(r io.Reader) (int, error) {
+	var in data
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return 0, errors.Wrap(err, "parse peer cache export")
+	}
+	infos := make([]Info, 0, len(in.Info))
+	for _, info := range in.Info {
+		infos = append(infos, info)
+	}
+	if err := s.SaveInfo(infos...); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range in.Peers {
+		s.data.Peers[k] = v
+		s.dirty.peers[k] = true
+	}
+	for k, v := range in.Phones {
+		s.data.Phones[k] = v
+		s.dirty.phones[k] = true
+	}
+	n := len(in.Info)
+	for raw := range in.Peers {
+		key, err := parseKey(raw)
+		if err != nil {
+			continue
+		}
+		if kind, ok := prefixKind(key.Prefix); ok {
+			if _, dup := in.Info[Info{ID: key.ID, Kind: kind}.key()]; !dup {
+				n++
+			}
+		}
+	}
+	return n, s.flush()
 }
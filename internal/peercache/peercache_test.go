@@ -1,7 +1,9 @@
 package peercache
 
 import (
+	"bytes"
 	"context"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -28,6 +30,14 @@ func TestStorageRoundTrip(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// Saves are batched until Flush/Close.
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("cache written before Close: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
 	// Reopen from disk to confirm persistence.
 	s2, err := Open(path)
 	if err != nil {
@@ -98,3 +108,255 @@ func TestFindMissing(t *testing.T) {
 		t.Error("expected not found")
 	}
 }
+
+func TestInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveInfo(
+		Info{ID: 1, Kind: KindUser, Name: "Old", Username: "durov", LastSeen: 100},
+		Info{ID: 2, Kind: KindChannel, Name: "News", LastSeen: 300},
+		Info{ID: 3, Kind: KindChat, Name: "Group"},
+	); err != nil {
+		t.Fatal(err)
+	}
+	// A later sighting without a username or activity keeps both.
+	if err := s.SaveInfo(Info{ID: 1, Kind: KindUser, Name: "Pavel"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s2.Infos()
+	want := []Info{
+		{ID: 2, Kind: KindChannel, Name: "News", LastSeen: 300},
+		{ID: 1, Kind: KindUser, Name: "Pavel", Username: "durov", LastSeen: 100},
+		{ID: 3, Kind: KindChat, Name: "Group"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Infos = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Infos[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestEntriesRemoveExportImport(t *testing.T) {
+	ctx := context.Background()
+	s, err := Open(filepath.Join(t.TempDir(), "peers.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := peers.Key{Prefix: "users_", ID: 1}
+	if err := s.Save(ctx, user, peers.Value{AccessHash: 11}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SavePhone(ctx, "+100", user); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(ctx, peers.Key{Prefix: "channel_", ID: 2}, peers.Value{AccessHash: 22}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveInfo(
+		Info{ID: 1, Kind: KindUser, Name: "Pavel", Username: "durov", LastSeen: 50},
+		Info{ID: 3, Kind: KindChat, Name: "Family"},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Entry{
+		{Info: Info{ID: 1, Kind: KindUser, Name: "Pavel", Username: "durov", LastSeen: 50}, HasAccessHash: true},
+		{Info: Info{ID: 3, Kind: KindChat, Name: "Family"}},
+		{Info: Info{ID: 2, Kind: KindChannel}, HasAccessHash: true},
+	}
+	got := s.Entries()
+	if len(got) != len(want) {
+		t.Fatalf("Entries = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Entries[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := s.Remove(func(e Entry) bool { return e.ID == 1 })
+	if err != nil || len(removed) != 1 {
+		t.Fatalf("Remove = %+v, %v", removed, err)
+	}
+	if _, ok := s.Kind(1); ok {
+		t.Error("access hash survived Remove")
+	}
+	if _, _, found, _ := s.FindPhone(ctx, "+100"); found {
+		t.Error("phone survived Remove")
+	}
+
+	dst, err := Open(filepath.Join(t.TempDir(), "other.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := dst.Import(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("Import read %d peers, want 3", n)
+	}
+	if v, found, _ := dst.Find(ctx, user); !found || v.AccessHash != 11 {
+		t.Errorf("imported Find = %+v, %v", v, found)
+	}
+	if len(dst.Entries()) != 3 {
+		t.Errorf("imported entries = %+v", dst.Entries())
+	}
+}
+
+func TestConcurrentWritersMerge(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "peers.json")
+
+	// Two processes open the same (empty) cache and learn different peers.
+	a, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Save(ctx, peers.Key{Prefix: "users_", ID: 1}, peers.Value{AccessHash: 11}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Save(ctx, peers.Key{Prefix: "users_", ID: 2}, peers.Value{AccessHash: 22}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SaveInfo(Info{ID: 2, Kind: KindUser, Name: "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// b's flush adopted a's entry as well.
+	if v, ok, _ := b.Find(ctx, peers.Key{Prefix: "users_", ID: 1}); !ok || v.AccessHash != 11 {
+		t.Fatalf("b lost a's peer: %v %v", v, ok)
+	}
+
+	// A removal by a is not undone by b's later unrelated write.
+	if _, err := a.Remove(func(e Entry) bool { return e.ID == 2 }); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Save(ctx, peers.Key{Prefix: "channel_", ID: 3}, peers.Value{AccessHash: 33}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		key  peers.Key
+		want bool
+	}{
+		{peers.Key{Prefix: "users_", ID: 1}, true},
+		{peers.Key{Prefix: "users_", ID: 2}, false},
+		{peers.Key{Prefix: "channel_", ID: 3}, true},
+	} {
+		if _, ok, _ := s.Find(ctx, tc.key); ok != tc.want {
+			t.Errorf("Find(%v) ok = %v, want %v", tc.key, ok, tc.want)
+		}
+	}
+	if infos := s.Infos(); len(infos) != 0 {
+		t.Errorf("Infos() = %v, want removed", infos)
+	}
+}
+
+// xorCodec is a reversible test codec marking encoded files with a prefix.
+type xorCodec struct{}
+
+func (xorCodec) Encode(plain []byte) ([]byte, error) {
+	out := []byte("x:")
+	for _, b := range plain {
+		out = append(out, b^0x5a)
+	}
+	return out, nil
+}
+
+func (xorCodec) Decode(raw []byte) ([]byte, error) {
+	enc, ok := bytes.CutPrefix(raw, []byte("x:"))
+	if !ok {
+		return raw, nil // written without the codec
+	}
+	out := make([]byte, len(enc))
+	for i, b := range enc {
+		out[i] = b ^ 0x5a
+	}
+	return out, nil
+}
+
+func TestCodec(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "peers.json")
+	key := peers.Key{Prefix: "user", ID: 42}
+
+	// A plain cache is read through the codec and rewritten encoded.
+	plain, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plain.Save(ctx, key, peers.Value{AccessHash: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := plain.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := OpenCodec(path, xorCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found, err := s.Find(ctx, key); err != nil || !found {
+		t.Fatalf("Find = %v, %v", found, err)
+	}
+	if err := s.Save(ctx, peers.Key{Prefix: "user", ID: 43}, peers.Value{AccessHash: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(raw, []byte("x:")) || bytes.Contains(raw, []byte("peers")) {
+		t.Fatalf("cache not encoded: %q", raw)
+	}
+	s2, err := OpenCodec(path, xorCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int64{42, 43} {
+		if _, found, err := s2.Find(ctx, peers.Key{Prefix: "user", ID: id}); err != nil || !found {
+			t.Errorf("Find(%d) = %v, %v", id, found, err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Package sealed encrypts small files at rest under a secret.
+//
+// A sealed file is a JSON document carrying the key derivation parameters,
+// the nonce and the XChaCha20-Poly1305 ciphertext. The encryption key is
+// derived from the secret (a passphrase or the contents of a key file) with
+// Argon2id, so a sealed file can be opened with the secret alone:
+//
+//	{"sealed":1,"kdf":"argon2id","time":1,"memory":65536,"threads":4,
+//	 "salt":"...","nonce":"...","data":"..."}
+package sealed
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"sync"
+
+	"github.com/go-faster/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Version is the sealed file format version.
+const Version = 1
+
+const (
+	kdfArgon2id = "argon2id"
+	saltSize    = 16
+
+	// Argon2id parameters for new files: the RFC 9106 second recommended
+	// option (64 MiB) with a lighter time cost, one pass instead of three,
+	// about 50ms on a laptop.
+	argonTime    = 1
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+
+	// maxMemory bounds the memory cost accepted from a file, so a tampered
+	// file cannot make Open allocate without limit.
+	maxMemory = 1024 * 1024 // KiB
+)
+
+// ErrKey is returned by Open when the secret does not match the file, or the
+// file was modified.
+var ErrKey = errors.New("wrong key or corrupted data")
+
+// file is the on-disk form of a sealed file.
+type file struct {
+	Sealed  int    `json:"sealed"`
+	KDF     string `json:"kdf"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// params are key derivation parameters.
+type params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	salt    string
+}
+
+// Key seals and opens files under one secret. Derived keys are cached, so a
+// process pays for the key derivation once per salt. It is safe for
+// concurrent use.
+type Key struct {
+	secret []byte
+
+	mu      sync.Mutex
+	current *params // parameters of the files this Key writes
+	derived map[params][]byte
+}
+
+// NewKey returns a Key for secret.
+func NewKey(secret []byte) *Key {
+	return &Key{secret: secret, derived: map[params][]byte{}}
+}
+
+// IsSealed reports whether raw is a sealed file.
+func IsSealed(raw []byte) bool {
+	var f struct {
+		Sealed int `json:"sealed"`
+	}
+	return json.Unmarshal(raw, &f) == nil && f.Sealed > 0
+}
+
+// derive returns the key for p, deriving it on first use; caller must hold mu.
+func (k *Key) derive(p params) []byte {
+	if key, ok := k.derived[p]; ok {
+		return key
+	}
+	key := argon2.IDKey(k.secret, []byte(p.salt), p.time, p.memory, p.threads, chacha20poly1305.KeySize)
+	k.derived[p] = key
+	return key
+}
+
+// Seal encrypts plain into a sealed file. Files sealed by one Key share a salt
+// (the one of the first file it opened, or a fresh one) and differ by nonce.
+func (k *Key) Seal(plain []byte) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.current == nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, errors.Wrap(err, "generate salt")
+		}
+		k.current = &params{time: argonTime, memory: argonMemory, threads: argonThreads, salt: string(salt)}
+	}
+	p := *k.current
+	aead, err := chacha20poly1305.NewX(k.derive(p))
+	if err != nil {
+		return nil, errors.Wrap(err, "init cipher")
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "generate nonce")
+	}
+	f := file{
+		Sealed:  Version,
+		KDF:     kdfArgon2id,
+		Time:    p.time,
+		Memory:  p.memory,
+		Threads: p.threads,
+		Salt:    []byte(p.salt),
+		Nonce:   nonce,
+		Data:    aead.Seal(nil, nonce, plain, nil),
+	}
+	out, err := json.Marshal(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal sealed file")
+	}
+	return out, nil
+}
+
+// Open decrypts a sealed file.
+func (k *Key) Open(raw []byte) ([]byte, error) {
+	var f file
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, errors.Wrap(err, "parse sealed file")
+	}
+	switch {
+	case f.Sealed != Version:
+		return nil, errors.Errorf("unsupported sealed file version %d", f.Sealed)
+	case f.KDF != kdfArgon2id:
+		return nil, errors.Errorf("unsupported key derivation %q", f.KDF)
+	case f.Time == 0 || f.Threads == 0 || f.Memory == 0 || f.Memory > maxMemory || len(f.Salt) == 0:
+		return nil, errors.New("invalid key derivation parameters")
+	case len(f.Nonce) != chacha20poly1305.NonceSizeX:
+		return nil, errors.New("invalid nonce")
+	}
+	p := params{time: f.Time, memory: f.Memory, threads: f.Threads, salt: string(f.Salt)}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	aead, err := chacha20poly1305.NewX(k.derive(p))
+	if err != nil {
+		return nil, errors.Wrap(err, "init cipher")
+	}
+	plain, err := aead.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return nil, ErrKey
+	}
+	if k.current == nil {
+		// Keep writing with this file's parameters: no second derivation.
+		k.current = &p
+	}
+	return plain, nil
+}
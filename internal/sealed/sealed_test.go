@@ -0,0 +1,99 @@
+package sealed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	k := NewKey([]byte("correct horse"))
+	raw, err := k.Seal([]byte("session"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealed(raw) {
+		t.Fatal("sealed file not recognized")
+	}
+	if bytes.Contains(raw, []byte("session")) {
+		t.Fatal("plaintext leaked into the sealed file")
+	}
+
+	// A fresh Key with the same secret opens it.
+	plain, err := NewKey([]byte("correct horse")).Open(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plain) != "session" {
+		t.Errorf("plain = %q", plain)
+	}
+
+	if _, err := NewKey([]byte("wrong")).Open(raw); !errors.Is(err, ErrKey) {
+		t.Errorf("wrong secret: err = %v, want ErrKey", err)
+	}
+}
+
+func TestSealReusesSalt(t *testing.T) {
+	k := NewKey([]byte("s"))
+	a, err := k.Seal([]byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := k.Seal([]byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fa, fb file
+	if err := json.Unmarshal(a, &fa); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &fb); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fa.Salt, fb.Salt) {
+		t.Error("salt changed between seals")
+	}
+	if bytes.Equal(fa.Nonce, fb.Nonce) || bytes.Equal(fa.Data, fb.Data) {
+		t.Error("nonce reused")
+	}
+	if len(k.derived) != 1 {
+		t.Errorf("derived %d keys, want 1", len(k.derived))
+	}
+}
+
+func TestOpenTampered(t *testing.T) {
+	k := NewKey([]byte("s"))
+	raw, err := k.Seal([]byte("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f file
+	if err := json.Unmarshal(raw, &f); err != nil {
+		t.Fatal(err)
+	}
+	f.Data[0] ^= 1
+	tampered, _ := json.Marshal(f)
+	if _, err := k.Open(tampered); !errors.Is(err, ErrKey) {
+		t.Errorf("tampered data: err = %v, want ErrKey", err)
+	}
+
+	f.Memory = maxMemory + 1
+	huge, _ := json.Marshal(f)
+	if _, err := k.Open(huge); err == nil {
+		t.Error("expected error for an oversized memory cost")
+	}
+}
+
+func TestIsSealed(t *testing.T) {
+	for raw, want := range map[string]bool{
+		`{"Version":1,"Data":{}}`: false,
+		`{"peers":{}}`:            false,
+		``:                        false,
+		`{"sealed":1}`:            true,
+	} {
+		if got := IsSealed([]byte(raw)); got != want {
+			t.Errorf("IsSealed(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// Package sessionfmt converts gotd sessions to and from portable strings and
+// the string sessions of other MTProto clients (Telethon, Pyrogram).
+//
+// A gotd session is the JSON document gotd's session.Loader stores. Other
+// clients keep less: Telethon a DC address and the auth key, Pyrogram a DC id,
+// the auth key and the account's user id. Converting to them drops gotd's
+// cached config and server salt, which the client fetches again on connect.
+package sessionfmt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/crypto"
+	"github.com/gotd/td/session"
+	"github.com/gotd/td/telegram/dcs"
+	"github.com/gotd/td/tg"
+)
+
+// authKeySize is the size of an MTProto auth key.
+const authKeySize = 256
+
+// Decode parses a gotd session document.
+func Decode(raw []byte) (*session.Data, error) {
+	m := memory(raw)
+	l := session.Loader{Storage: &m}
+	d, err := l.Load(context.Background())
+	if err != nil {
+		return nil, errors.Wrap(err, "decode gotd session")
+	}
+	return d, nil
+}
+
+// Encode renders d as a gotd session document.
+func Encode(d *session.Data) ([]byte, error) {
+	var m memory
+	l := session.Loader{Storage: &m}
+	if err := l.Save(context.Background(), d); err != nil {
+		return nil, errors.Wrap(err, "encode gotd session")
+	}
+	return m, nil
+}
+
+// memory is a minimal session.Storage over a byte slice.
+type memory []byte
+
+func (m *memory) LoadSession(context.Context) ([]byte, error) {
+	if len(*m) == 0 {
+		return nil, session.ErrNotFound
+	}
+	return *m, nil
+}
+
+func (m *memory) StoreSession(_ context.Context, data []byte) error {
+	*m = append((*m)[:0], data...)
+	return nil
+}
+
+// FromKey builds session data for an auth key on a DC, addressed by the DC's
+// default IPv4 address (test servers when test is set).
+func FromKey(dc int, test bool, key []byte) (*session.Data, error) {
+	if len(key) != authKeySize {
+		return nil, errors.Errorf("auth key is %d bytes, want %d", len(key), authKeySize)
+	}
+	addr, err := dcAddr(dc, test, nil)
+	if err != nil {
+		return nil, err
+	}
+	var k crypto.Key
+	copy(k[:], key)
+	id := k.WithID().ID
+	return &session.Data{DC: dc, Addr: addr, AuthKey: k[:], AuthKeyID: id[:]}, nil
+}
+
+// dcAddr returns the "ip:port" of a DC from opts, else from gotd's built-in
+// lists.
+func dcAddr(dc int, test bool, opts []tg.DCOption) (string, error) {
+	list := dcs.Prod()
+	if test {
+		list = dcs.Test()
+	}
+	for _, o := range append(opts, list.Options...) {
+		if o.ID == dc && !o.Ipv6 && !o.MediaOnly && !o.CDN && !o.TCPObfuscatedOnly {
+			return net.JoinHostPort(o.IPAddress, strconv.Itoa(o.Port)), nil
+		}
+	}
+	return "", errors.Errorf("no known address for DC %d", dc)
+}
+
+// Telethon renders d as a Telethon StringSession.
+func Telethon(d *session.Data) (string, error) {
+	if len(d.AuthKey) != authKeySize {
+		return "", errors.New("session has no auth key")
+	}
+	addr := d.Addr
+	if addr == "" {
+		var err error
+		if addr, err = dcAddr(d.DC, d.Config.TestMode, d.Config.DCOptions); err != nil {
+			return "", err
+		}
+	}
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse DC address %q", addr)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "", errors.Errorf("DC address %q is not an IP", addr)
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse DC port %q", portStr)
+	}
+
+	// '>B{4|16}sH256s': DC id, IP, port, auth key.
+	var b bytes.Buffer
+	b.WriteByte(byte(d.DC))
+	b.Write(ip)
+	_ = binary.Write(&b, binary.BigEndian, uint16(port))
+	b.Write(d.AuthKey)
+	return "1" + base64.URLEncoding.EncodeToString(b.Bytes()), nil
+}
+
+// ParseTelethon parses a Telethon StringSession.
+func ParseTelethon(s string) (*session.Data, error) {
+	d, err := session.TelethonSession(strings.TrimSpace(s))
+	if err != nil {
+		return nil, errors.Wrap(err, "parse telethon session")
+	}
+	return d, nil
+}
+
+// Pyrogram is the content of a Pyrogram string session.
+type Pyrogram struct {
+	DC      int
+	APIID   int
+	Test    bool
+	AuthKey []byte
+	UserID  int64
+	Bot     bool
+}
+
+// Pyrogram string session layouts, by decoded length.
+const (
+	pyrogramSize      = 271 // '>BI?256sQ?': dc, api id, test, key, user id, bot
+	pyrogramOldSize   = 263 // '>B?256sI?': dc, test, key, 32-bit user id, bot
+	pyrogramOld64Size = 267 // '>B?256sQ?': dc, test, key, user id, bot
+)
+
+// String renders the session in Pyrogram's current layout.
+func (p Pyrogram) String() string {
+	var b bytes.Buffer
+	b.WriteByte(byte(p.DC))
+	_ = binary.Write(&b, binary.BigEndian, uint32(p.APIID)) // #nosec G115 // api ids are small positive ints
+	b.WriteByte(boolByte(p.Test))
+	b.Write(p.AuthKey)
+	_ = binary.Write(&b, binary.BigEndian, uint64(p.UserID)) // #nosec G115 // user ids are positive
+	b.WriteByte(boolByte(p.Bot))
+	return base64.RawURLEncoding.EncodeToString(b.Bytes())
+}
+
+func boolByte(v bool) byte {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// ParsePyrogram parses a Pyrogram string session in any of its layouts.
+func ParsePyrogram(s string) (Pyrogram, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(strings.TrimSpace(s), "="))
+	if err != nil {
+		return Pyrogram{}, errors.Wrap(err, "parse pyrogram session")
+	}
+	var (
+		p    Pyrogram
+		rest []byte
+	)
+	switch len(raw) {
+	case pyrogramSize:
+		p.APIID = int(binary.BigEndian.Uint32(raw[1:5]))
+		p.Test = raw[5] != 0
+		rest = raw[6:]
+	case pyrogramOldSize, pyrogramOld64Size:
+		p.Test = raw[1] != 0
+		rest = raw[2:]
+	default:
+		return Pyrogram{}, errors.Errorf("pyrogram session has invalid length %d", len(raw))
+	}
+	p.DC = int(raw[0])
+	p.AuthKey = rest[:authKeySize]
+	rest = rest[authKeySize:]
+	if len(rest) == 5 {
+		p.UserID = int64(binary.BigEndian.Uint32(rest[:4]))
+	} else {
+		p.UserID = int64(binary.BigEndian.Uint64(rest[:8])) // #nosec G115 // user ids are positive
+	}
+	p.Bot = rest[len(rest)-1] != 0
+	return p, nil
+}
+
+// Data returns the gotd session data of p.
+func (p Pyrogram) Data() (*session.Data, error) {
+	return FromKey(p.DC, p.Test, p.AuthKey)
+}
+
+// Portable string prefixes: a plain and a sealed (encrypted) portable session.
+// The prefix is followed by the base64url (unpadded) JSON of a Portable, or of
+// a file sealed with internal/sealed holding it.
+const (
+	PortablePrefix       = "gotd1:"
+	SealedPortablePrefix = "gotd1s:"
+)
+
+// Portable is a session with what is needed to use it on another machine.
+type Portable struct {
+	// Kind is the auth kind of the session: "user" or "bot".
+	Kind string `json:"kind"`
+	// Test reports a session on the test servers.
+	Test bool `json:"test,omitempty"`
+	// AppID is the app the session was created with, for reference.
+	AppID int `json:"app_id,omitempty"`
+	// Session is the gotd session document.
+	Session json.RawMessage `json:"session"`
+}
+
+// Marshal renders p as JSON.
+func (p Portable) Marshal() ([]byte, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal portable session")
+	}
+	return raw, nil
+}
+
+// Armor renders raw as prefix + base64url(raw).
+func Armor(prefix string, raw []byte) string {
+	return prefix + base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Dearmor decodes the base64url body following prefix in s.
+func Dearmor(prefix, s string) ([]byte, error) {
+	body := strings.TrimRight(strings.TrimPrefix(strings.TrimSpace(s), prefix), "=")
+	raw, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode portable session")
+	}
+	return raw, nil
+}
+
+// UnmarshalPortable parses the JSON form of a portable session.
+func UnmarshalPortable(raw []byte) (Portable, error) {
+	var p Portable
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return Portable{}, errors.Wrap(err, "parse portable session")
+	}
+	if len(p.Session) == 0 {
+		return Portable{}, errors.New("portable session has no session")
+	}
+	return p, nil
+}
@@ -0,0 +1,111 @@
+package sessionfmt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func testKey() []byte {
+	key := make([]byte, authKeySize)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	return key
+}
+
+func TestEncodeDecode(t *testing.T) {
+	d, err := FromKey(2, false, testKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := Encode(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DC != 2 || got.Addr != d.Addr || !bytes.Equal(got.AuthKey, d.AuthKey) || !bytes.Equal(got.AuthKeyID, d.AuthKeyID) {
+		t.Errorf("round trip = %+v, want %+v", got, d)
+	}
+	if _, err := FromKey(2, false, []byte("short")); err == nil {
+		t.Error("expected error for a short auth key")
+	}
+}
+
+func TestTelethon(t *testing.T) {
+	d, err := FromKey(4, false, testKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Telethon(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s[0] != '1' {
+		t.Errorf("string %q lacks the version prefix", s)
+	}
+	got, err := ParseTelethon(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DC != 4 || got.Addr != d.Addr || !bytes.Equal(got.AuthKey, d.AuthKey) {
+		t.Errorf("round trip = DC %d addr %q, want DC 4 addr %q", got.DC, got.Addr, d.Addr)
+	}
+}
+
+func TestPyrogram(t *testing.T) {
+	p := Pyrogram{DC: 2, APIID: 12345, AuthKey: testKey(), UserID: 7_000_000_000, Bot: true}
+	got, err := ParsePyrogram(p.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DC != p.DC || got.APIID != p.APIID || got.Test || got.UserID != p.UserID || !got.Bot ||
+		!bytes.Equal(got.AuthKey, p.AuthKey) {
+		t.Errorf("round trip = %+v", got)
+	}
+
+	// The old layout: dc, test, key, 32-bit user id, bot.
+	var old bytes.Buffer
+	old.WriteByte(1)
+	old.WriteByte(1)
+	old.Write(testKey())
+	old.Write([]byte{0, 0, 0, 42})
+	old.WriteByte(0)
+	got, err = ParsePyrogram(base64.URLEncoding.EncodeToString(old.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DC != 1 || !got.Test || got.UserID != 42 || got.Bot {
+		t.Errorf("old layout = %+v", got)
+	}
+
+	if _, err := ParsePyrogram("AAAA"); err == nil {
+		t.Error("expected error for a short string")
+	}
+}
+
+func TestPortable(t *testing.T) {
+	p := Portable{Kind: "user", Test: true, AppID: 1, Session: []byte(`{"Version":1}`)}
+	raw, err := p.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Armor(PortablePrefix, raw)
+	body, err := Dearmor(PortablePrefix, s+"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalPortable(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Kind != p.Kind || !got.Test || got.AppID != 1 || string(got.Session) != string(p.Session) {
+		t.Errorf("round trip = %+v", got)
+	}
+	if _, err := UnmarshalPortable([]byte(`{"kind":"user"}`)); err == nil {
+		t.Error("expected error for a portable session without a session")
+	}
+}